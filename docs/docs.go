@@ -16,6 +16,44 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "well-known"
+                ],
+                "summary": "Public keys for token signature verification",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_lib_jwt.JWKSet"
+                        }
+                    }
+                }
+            }
+        },
+        "/.well-known/openid-configuration": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "well-known"
+                ],
+                "summary": "OpenID Connect discovery document",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.DiscoveryResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/client": {
             "get": {
                 "security": [
@@ -171,7 +209,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/client/users": {
+        "/admin/client/user/{id}/sessions": {
             "get": {
                 "security": [
                     {
@@ -184,38 +222,55 @@ const docTemplate = `{
                 "tags": [
                     "admin-client"
                 ],
-                "summary": "Get users",
+                "summary": "List user sessions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-client_ClientUsersResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_auth_SessionResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/client/{id}": {
+        "/admin/client/user/{id}/sessions/{sessionId}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Ends the session and revokes its refresh and access tokens",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin-client"
                 ],
-                "summary": "Delete client",
+                "summary": "Revoke user session",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Client ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -228,46 +283,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/order": {
+        "/admin/client/user/{id}/unlock": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Clears failed login attempts and lifts the temporary lockout of the user",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-client"
                 ],
-                "summary": "Create or update order",
+                "summary": "Unlock user login",
                 "parameters": [
                     {
-                        "description": "Order request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.OrderRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
                         }
                     }
+                }
+            }
+        },
+        "/admin/client/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-client"
                 ],
+                "summary": "Get users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-client_ClientUsersResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/order/clients": {
-            "get": {
+        "/admin/client/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -277,20 +352,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-client"
+                ],
+                "summary": "Delete client",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get order clients",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderClientsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
                         }
                     }
                 }
             }
         },
-        "/admin/order/products": {
+        "/admin/key": {
             "get": {
                 "security": [
                     {
@@ -301,44 +385,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-key"
                 ],
-                "summary": "Get order products",
+                "summary": "Get token signing keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderProductsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_keys_SigningKeyResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/order/statuses": {
-            "get": {
+        "/admin/key/rotate": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Creates a new signing key and retires the current one. Retired keys keep verifying tokens until they expire.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-key"
                 ],
-                "summary": "Get order statuses",
+                "summary": "Rotate token signing key",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderStatusesResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_keys_SigningKeyResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/order/{id}": {
+        "/admin/oauth-client": {
             "get": {
                 "security": [
                     {
@@ -349,27 +434,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-oauth-client"
                 ],
-                "summary": "Get order by ID",
+                "summary": "Get OAuth clients",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_oauth_ClientResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The client secret is returned only once, when a confidential client is created",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-oauth-client"
+                ],
+                "summary": "Create or update OAuth client",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Client request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ClientRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_oauth_ClientResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/oauth-client/{id}": {
             "delete": {
                 "security": [
                     {
@@ -380,13 +495,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-oauth-client"
                 ],
-                "summary": "Delete order",
+                "summary": "Delete OAuth client",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
+                        "type": "string",
+                        "description": "Client ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -402,8 +517,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/orders/status/{statusId}": {
-            "get": {
+        "/admin/oauth-client/{id}/secret": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -413,14 +528,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "admin-order"
+                    "admin-oauth-client"
                 ],
-                "summary": "Get orders",
+                "summary": "Reset OAuth client secret",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Status ID",
-                        "name": "statusId",
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -429,57 +544,264 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_oauth_ClientResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/product": {
-            "get": {
+        "/admin/order": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin-product"
+                    "admin-order"
+                ],
+                "summary": "Create or update order",
+                "parameters": [
+                    {
+                        "description": "Order request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.OrderRequest"
+                        }
+                    }
                 ],
-                "summary": "Get all products",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/order/clients": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "multipart/form-data"
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-order"
+                ],
+                "summary": "Get order clients",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderClientsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/order/products": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin-product"
+                    "admin-order"
                 ],
-                "summary": "Create or update product",
-                "parameters": [
+                "summary": "Get order products",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderProductsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/order/statuses": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Product article",
-                        "name": "article",
-                        "in": "formData"
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-order"
+                ],
+                "summary": "Get order statuses",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_OrderStatusesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/order/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-order"
+                ],
+                "summary": "Get order by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-order"
+                ],
+                "summary": "Delete order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/status/{statusId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-order"
+                ],
+                "summary": "Get orders",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Status ID",
+                        "name": "statusId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/product": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-product"
+                ],
+                "summary": "Get all products",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-product"
+                ],
+                "summary": "Create or update product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product article",
+                        "name": "article",
+                        "in": "formData"
                     },
                     {
                         "type": "string",
@@ -859,8 +1181,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/forgotPassword": {
+        "/auth/2fa/confirm": {
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Enables two-factor authentication and returns one-time recovery codes, shown only once",
                 "consumes": [
                     "application/json"
                 ],
@@ -870,15 +1198,15 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Send password reset email",
+                "summary": "Confirm TOTP enrollment",
                 "parameters": [
                     {
-                        "description": "User login",
+                        "description": "Code from the authenticator app",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_application_auth.ForgotPasswordRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.TOTPCodeRequest"
                         }
                     }
                 ],
@@ -886,16 +1214,18 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_RecoveryCodesResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/logIn": {
-            "post": {
-                "consumes": [
-                    "application/json"
+        "/auth/2fa/recoveryCodes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
@@ -903,48 +1233,66 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Login user",
-                "parameters": [
-                    {
-                        "description": "Credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "Two-factor status and remaining recovery codes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_RecoveryCodesStatusResponse"
                         }
                     }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issues a new set of recovery codes, previous codes stop working",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
                 ],
+                "summary": "Regenerate recovery codes",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_RecoveryCodesResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/refresh": {
+        "/auth/2fa/setup": {
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generates a new TOTP secret. Two-factor authentication is enabled after /auth/2fa/confirm",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Refresh access token",
+                "summary": "Start TOTP enrollment",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_TOTPSetupResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/resetPassword": {
+        "/auth/2fa/verify": {
             "post": {
                 "consumes": [
                     "application/json"
@@ -955,15 +1303,15 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Reset user password",
+                "summary": "Complete login with the second factor",
                 "parameters": [
                     {
-                        "description": "Login and new password",
+                        "description": "MFA token from /auth/logIn and TOTP or recovery code",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MFAVerifyRequest"
                         }
                     }
                 ],
@@ -971,14 +1319,15 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/signUp": {
+        "/auth/changeExpiredPassword": {
             "post": {
+                "description": "Staff passwords expire periodically; /auth/logIn then returns password_expired and password_change_token instead of tokens",
                 "consumes": [
                     "application/json"
                 ],
@@ -988,15 +1337,15 @@ const docTemplate = `{
                 "tags": [
                     "auth"
                 ],
-                "summary": "Register user",
+                "summary": "Change an expired password and finish login",
                 "parameters": [
                     {
-                        "description": "Credentials",
+                        "description": "Password change token and new password",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ChangeExpiredPasswordRequest"
                         }
                     }
                 ],
@@ -1004,41 +1353,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/client/order": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "client-order"
-                ],
-                "summary": "Get client orders",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
-                        }
-                    }
-                }
-            },
+        "/auth/changePassword": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Verifies the old password and sets a new one. With revoke_other_sessions all sessions except the current one are ended. A notice is sent to the user's email",
                 "consumes": [
                     "application/json"
                 ],
@@ -1046,17 +1374,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "client-order"
+                    "auth"
                 ],
-                "summary": "Create order",
+                "summary": "Change password of the current user",
                 "parameters": [
                     {
-                        "description": "Create order request",
+                        "description": "Old and new password",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ChangePasswordRequest"
                         }
                     }
                 ],
@@ -1064,19 +1392,15 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/client/order/add-product": {
+        "/auth/external/{provider}/callback": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
+                "description": "Exchanges the code returned by the provider for the same tokens as /auth/logIn. The external account is linked to the user with the same verified email; a new customer is registered if there is none. Requires the cookie set by the start endpoint, so call it with credentials included from the browser that started the login",
                 "consumes": [
                     "application/json"
                 ],
@@ -1084,17 +1408,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "client-order"
+                    "auth"
                 ],
-                "summary": "Add product to order",
+                "summary": "Finish login via an external provider",
                 "parameters": [
                     {
-                        "description": "Product into order request",
+                        "type": "string",
+                        "description": "Provider name from config, e.g. google",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Code and state from the provider redirect",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ExternalLoginCallbackRequest"
                         }
                     }
                 ],
@@ -1102,31 +1433,27 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/client/order/{id}": {
+        "/auth/external/{provider}/start": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
+                "description": "Returns the provider login page URL and state and sets an HttpOnly cookie that binds the state to this browser. Call it with credentials (cookies) included and navigate to the URL; the callback endpoint accepts the state only together with this cookie",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-order"
+                    "auth"
                 ],
-                "summary": "Get order by id",
+                "summary": "Start login via an external provider",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Provider name from config, e.g. google",
+                        "name": "provider",
                         "in": "path",
                         "required": true
                     }
@@ -1135,144 +1462,119 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_ExternalLoginStartResponse"
                         }
                     }
                 }
             }
         },
-        "/client/order/{id}/cancel": {
+        "/auth/forgotPassword": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-order"
+                    "auth"
                 ],
-                "summary": "Cancel order",
+                "summary": "Send password reset email",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "User login",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_application_auth.ForgotPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/client/order/{id}/complete": {
+        "/auth/logIn": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+                "description": "When two-factor authentication is enabled, returns mfa_required and mfa_token instead of tokens; finish with /auth/2fa/verify",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-order"
+                    "auth"
                 ],
-                "summary": "Complete order",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/client/product": {
-            "get": {
+        "/auth/logout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-product"
+                    "auth"
                 ],
-                "summary": "Get products",
+                "summary": "Logout current session",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Offset",
-                        "name": "offset",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Minimum price",
-                        "name": "min_price",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Maximum price",
-                        "name": "max_price",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Brand ID",
-                        "name": "brand_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Search term (article, name, brand)",
-                        "name": "term",
-                        "in": "query"
+                        "description": "Refresh token of the session",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_application_auth.LogoutRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/client/product/base-models": {
-            "get": {
+        "/auth/logoutAll": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -1282,133 +1584,124 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "client-product"
-                ],
-                "summary": "Get all base models",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Base model name",
-                        "name": "baseModelName",
-                        "in": "query"
-                    }
+                    "auth"
                 ],
+                "summary": "Logout from all devices",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/client/product/favorites": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+        "/auth/magicLink": {
+            "post": {
+                "description": "Emails a one-time, short-lived login link. Limited per login",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-product"
+                    "auth"
                 ],
-                "summary": "Get favorite products",
+                "summary": "Send passwordless login link",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Client ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "User login",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_application_auth.ForgotPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/client/product/{article}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+        "/auth/magicLink/consume": {
+            "post": {
+                "description": "Exchanges the token from the email for the same response as /auth/logIn",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-product"
+                    "auth"
                 ],
-                "summary": "Get product",
+                "summary": "Log in with a magic link",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Product article",
-                        "name": "article",
-                        "in": "path",
-                        "required": true
+                        "description": "Token from the link",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.MagicLinkConsumeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedProductResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/client/product/{article}/favorites": {
+        "/auth/passkey/login": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+                "description": "Verifies the authenticator assertion and returns the same tokens as /auth/logIn",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client-product"
+                    "auth"
                 ],
-                "summary": "Action product to favorites",
+                "summary": "Log in with a passkey",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Product article",
-                        "name": "article",
-                        "in": "path",
-                        "required": true
+                        "description": "PublicKeyCredential.toJSON() result",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyLoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse"
                         }
                     }
                 }
             }
         },
-        "/client/profile": {
+        "/auth/passkey/login/options": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
+                "description": "Returns options for navigator.credentials.get(). Login is optional: without it the browser offers any passkey for this site",
                 "consumes": [
                     "application/json"
                 ],
@@ -1416,17 +1709,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "client"
+                    "auth"
                 ],
-                "summary": "Fill client profile",
+                "summary": "Start passkey login",
                 "parameters": [
                     {
-                        "description": "Client request",
+                        "description": "Optional login",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyLoginOptionsRequest"
                         }
                     }
                 ],
@@ -1434,80 +1726,102 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyRequestOptions"
                         }
                     }
                 }
             }
         },
-        "/client/profile/{id}": {
-            "get": {
+        "/auth/passkey/register": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Verifies the authenticator response and stores the new passkey",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client"
+                    "auth"
                 ],
-                "summary": "Get client profile",
+                "summary": "Finish passkey registration",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Client ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "PublicKeyCredential.toJSON() result and optional name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyRegistrationRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyResponse"
                         }
                     }
                 }
             }
         },
-        "/client/{id}": {
-            "delete": {
+        "/auth/passkey/register/options": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Returns options for navigator.credentials.create(); finish with /auth/passkey/register",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "client"
+                    "auth"
                 ],
-                "summary": "Delete client",
-                "parameters": [
+                "summary": "Start passkey registration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyCreationOptions"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/passkeys": {
+            "get": {
+                "security": [
                     {
-                        "type": "integer",
-                        "description": "Client ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List passkeys of the current user",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_auth_PasskeyResponse"
                         }
                     }
                 }
             }
         },
-        "/manager/order": {
-            "get": {
+        "/auth/passkeys/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -1517,26 +1831,84 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "manager"
+                    "auth"
+                ],
+                "summary": "Delete a passkey of the current user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Credential id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all orders",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/manager/order/give": {
+        "/auth/refresh": {
             "post": {
-                "security": [
+                "description": "When a staff password has expired, returns password_expired and password_change_token instead of tokens; finish with /auth/changeExpiredPassword",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/resendVerificationEmail": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Resend email verification letter",
+                "parameters": [
                     {
-                        "BearerAuth": []
+                        "description": "User login",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_application_auth.ForgotPasswordRequest"
+                        }
                     }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/resetPassword": {
+            "post": {
+                "description": "Sets a new password using the single-use token from the reset email and ends all user sessions",
                 "consumes": [
                     "application/json"
                 ],
@@ -1544,17 +1916,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "manager"
+                    "auth"
                 ],
-                "summary": "Give order",
+                "summary": "Reset user password",
                 "parameters": [
                     {
-                        "description": "Paid order request",
+                        "description": "Reset token and new password",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/manager.PaidOrderRequest"
+                            "$ref": "#/definitions/internal_application_auth.ResetPasswordRequest"
                         }
                     }
                 ],
@@ -1562,63 +1934,56 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
         },
-        "/manager/order/{id}": {
+        "/auth/sessions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Each login on a device is a session; the session of this request is marked current",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "manager"
-                ],
-                "summary": "Get order by id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "List active sessions of the current user",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_auth_SessionResponse"
                         }
                     }
                 }
             }
         },
-        "/manager/order/{id}/cancel": {
-            "post": {
+        "/auth/sessions/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
+                "description": "Ends the session on its device: its refresh token stops working and its access tokens are revoked",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "manager"
+                    "auth"
                 ],
-                "summary": "Cancel order",
+                "summary": "Revoke a session of the current user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
+                        "type": "string",
+                        "description": "Session id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1628,374 +1993,2476 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.BaseModelResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "is_archived": {
-                    "type": "boolean"
-                },
-                "name": {
-                    "type": "string"
-                }
-            }
         },
-        "api.BaseModelsResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.BaseModelResponse"
+        "/auth/signUp": {
+            "post": {
+                "description": "When email.require_verification is enabled, returns email_verification_required instead of tokens; log in after following the link from the email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
                     }
                 }
             }
         },
-        "api.ClientRequest": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "full_name": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "phone": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
-                }
-            }
-        },
-        "api.ClientResponse": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "full_name": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "login": {
-                    "type": "string"
-                },
-                "phone": {
-                    "type": "string"
+        "/auth/verifyEmail": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm user email",
+                "parameters": [
+                    {
+                        "description": "Verification token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_application_auth.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.ApiResponse-any"
+                        }
+                    }
                 }
             }
         },
-        "api.ExtendedOrderResponse": {
-            "type": "object",
-            "properties": {
-                "change_amount": {
-                    "type": "number"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "orderInfo": {
-                    "$ref": "#/definitions/api.OrderResponse"
-                },
-                "products": {
-                    "type": "array",
-                    "items": {
+        "/client/order": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Get client orders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Create order",
+                "parameters": [
+                    {
+                        "description": "Create order request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/order/add-product": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Add product to order",
+                "parameters": [
+                    {
+                        "description": "Product into order request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/order/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Get order by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-order_ClientOrderResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/order/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/order/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-order"
+                ],
+                "summary": "Complete order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/product": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-product"
+                ],
+                "summary": "Get products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Brand ID",
+                        "name": "brand_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search term (article, name, brand)",
+                        "name": "term",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/product/base-models": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-product"
+                ],
+                "summary": "Get all base models",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Base model name",
+                        "name": "baseModelName",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/product/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-product"
+                ],
+                "summary": "Get favorite products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/product/{article}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-product"
+                ],
+                "summary": "Get product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product article",
+                        "name": "article",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedProductResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/product/{article}/favorites": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client-product"
+                ],
+                "summary": "Action product to favorites",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product article",
+                        "name": "article",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/profile": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Fill client profile",
+                "parameters": [
+                    {
+                        "description": "Client request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/profile/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Get client profile",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "client"
+                ],
+                "summary": "Delete client",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/manager/order": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "manager"
+                ],
+                "summary": "Get all orders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/manager/order/give": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "manager"
+                ],
+                "summary": "Give order",
+                "parameters": [
+                    {
+                        "description": "Paid order request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/manager.PaidOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/manager/order/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "manager"
+                ],
+                "summary": "Get order by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/manager/order/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "manager"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/authorize": {
+            "get": {
+                "description": "Validates the request and redirects the browser to the SSO login page, which confirms it via /oauth2/authorize/approve",
+                "tags": [
+                    "oauth2"
+                ],
+                "summary": "OAuth2 authorization endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента",
+                        "name": "client_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE: BASE64URL(SHA256(code_verifier))",
+                        "name": "code_challenge",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "S256",
+                        "description": "PKCE: поддерживается только S256",
+                        "name": "code_challenge_method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение для защиты ID токена от повторного использования",
+                        "name": "nonce",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Адрес возврата, должен быть зарегистрирован для клиента",
+                        "name": "redirect_uri",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "code",
+                        "description": "Должен быть code",
+                        "name": "response_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "openid email",
+                        "description": "Запрашиваемые scope через пробел",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Непрозрачное значение, возвращается клиенту без изменений",
+                        "name": "state",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/authorize/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth2"
+                ],
+                "summary": "Issue authorization code for the logged in user",
+                "parameters": [
+                    {
+                        "description": "Authorization request received by the login page",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.AuthorizeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_oauth_AuthorizeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/introspect": {
+            "post": {
+                "description": "Reports whether a token issued by this server is active, including its revocation state",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth2"
+                ],
+                "summary": "OAuth2 token introspection (RFC 7662)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access, refresh or service token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token or refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (client_secret_post), alternatively use HTTP Basic auth",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.IntrospectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/revoke": {
+            "post": {
+                "description": "Revokes an access or refresh token issued to the client. Revoking a refresh token ends the whole session",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "oauth2"
+                ],
+                "summary": "OAuth2 token revocation (RFC 7009)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access or refresh token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token or refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (client_secret_post), alternatively use HTTP Basic auth",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/token": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth2"
+                ],
+                "summary": "OAuth2 token endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization_code, refresh_token or client_credentials",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Redirect URI used in the authorization request",
+                        "name": "redirect_uri",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (client_secret_post), alternatively use HTTP Basic auth",
+                        "name": "client_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE code verifier",
+                        "name": "code_verifier",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refresh token",
+                        "name": "refresh_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Requested scopes (client_credentials)",
+                        "name": "scope",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.BaseModelResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "is_archived": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.BaseModelsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.BaseModelResponse"
+                    }
+                }
+            }
+        },
+        "api.ClientRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ClientResponse": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ExtendedOrderResponse": {
+            "type": "object",
+            "properties": {
+                "change_amount": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "orderInfo": {
+                    "$ref": "#/definitions/api.OrderResponse"
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
                         "$ref": "#/definitions/api.OrderProduct"
                     }
                 },
-                "received_amount": {
-                    "type": "number"
+                "received_amount": {
+                    "type": "number"
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OrderStatus"
+                    }
+                }
+            }
+        },
+        "api.ExtendedProductResponse": {
+            "type": "object",
+            "properties": {
+                "brand": {
+                    "type": "string"
+                },
+                "brand_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "productInfo": {
+                    "$ref": "#/definitions/api.ProductResponse"
+                },
+                "product_type": {
+                    "type": "string"
+                },
+                "product_type_id": {
+                    "type": "integer"
+                },
+                "texture": {
+                    "type": "string"
+                },
+                "texture_id": {
+                    "type": "integer"
+                },
+                "volume": {
+                    "type": "integer"
+                },
+                "volume_type": {
+                    "type": "string"
+                },
+                "volume_type_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OrderClient": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OrderProduct": {
+            "type": "object",
+            "properties": {
+                "article": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OrderResponse": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "$ref": "#/definitions/api.OrderClient"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total_amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "api.OrderStatus": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.OrdersResponse": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.OrderResponse"
+                    }
+                }
+            }
+        },
+        "api.ProductResponse": {
+            "type": "object",
+            "properties": {
+                "article": {
+                    "type": "string"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "is_archived": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ProductsResponse": {
+            "type": "object",
+            "properties": {
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ProductResponse"
+                    }
+                }
+            }
+        },
+        "client.ClientUsersResponse": {
+            "type": "object",
+            "properties": {
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/client.UserResponse"
+                    }
+                }
+            }
+        },
+        "client.ClientsResponse": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ClientResponse"
+                    }
+                }
+            }
+        },
+        "client.RoleResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "role_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "client.RolesResponse": {
+            "type": "object",
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/client.RoleResponse"
+                    }
+                }
+            }
+        },
+        "client.UserRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "is_archived": {
+                    "type": "boolean"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "client.UserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/client.RoleResponse"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.AuthRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "description": "Логин пользователя",
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "password": {
+                    "description": "Пароль пользователя",
+                    "type": "string",
+                    "example": "P@ssw0rd!"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "description": "Access Token для доступа к защищенным ресурсам",
+                    "type": "string"
+                },
+                "email_verification_required": {
+                    "description": "Регистрация прошла, но токены выдаются только после перехода по ссылке из письма",
+                    "type": "boolean"
+                },
+                "mfa_required": {
+                    "description": "Вход не завершен: нужен код второго фактора. Токенов в ответе нет,\nMFAToken обменивается на них через /auth/2fa/verify",
+                    "type": "boolean"
+                },
+                "mfa_token": {
+                    "type": "string"
+                },
+                "password_change_token": {
+                    "type": "string"
+                },
+                "password_expired": {
+                    "description": "Вход не завершен: срок действия пароля истек. Токенов в ответе нет,\nPasswordChangeToken обменивается на них через /auth/changeExpiredPassword",
+                    "type": "boolean"
+                },
+                "recovery_codes_left": {
+                    "description": "Сколько кодов восстановления осталось - заполняется при входе по коду восстановления",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "description": "Refresh Token для обновления пары токенов",
+                    "type": "string"
+                },
+                "role_id": {
+                    "description": "Role ID пользователя (1=client, 2=manager, 3=admin)",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ChangeExpiredPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "newPassword123"
+                },
+                "password_change_token": {
+                    "description": "Токен из ответа /auth/logIn",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "newPassword123"
+                },
+                "old_password": {
+                    "type": "string",
+                    "example": "password123"
+                },
+                "revoke_other_sessions": {
+                    "description": "Завершить все сессии, кроме текущей",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ExternalLoginCallbackRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.ExternalLoginStartResponse": {
+            "type": "object",
+            "properties": {
+                "authorization_url": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.MFAVerifyRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код TOTP или код восстановления",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "description": "Токен из ответа /auth/logIn",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.MagicLinkConsumeRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Токен из ссылки в письме",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyAuthenticatorSelection": {
+            "type": "object",
+            "properties": {
+                "residentKey": {
+                    "type": "string"
+                },
+                "userVerification": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyCreationOptions": {
+            "type": "object",
+            "properties": {
+                "attestation": {
+                    "type": "string"
+                },
+                "authenticatorSelection": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyAuthenticatorSelection"
+                },
+                "challenge": {
+                    "type": "string"
+                },
+                "excludeCredentials": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyCredentialDescriptor"
+                    }
+                },
+                "pubKeyCredParams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyCredentialParameter"
+                    }
+                },
+                "rp": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyRelyingParty"
+                },
+                "timeout": {
+                    "type": "integer"
+                },
+                "user": {
+                    "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyUser"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyCredentialDescriptor": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "transports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyCredentialParameter": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyLoginOptionsRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyLoginRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "rawId": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "object",
+                    "properties": {
+                        "authenticatorData": {
+                            "type": "string"
+                        },
+                        "clientDataJSON": {
+                            "type": "string"
+                        },
+                        "signature": {
+                            "type": "string"
+                        },
+                        "userHandle": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyRegistrationRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Название, под которым passkey будет показан в списке",
+                    "type": "string",
+                    "example": "iPhone"
+                },
+                "rawId": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "object",
+                    "properties": {
+                        "attestationObject": {
+                            "type": "string"
+                        },
+                        "clientDataJSON": {
+                            "type": "string"
+                        },
+                        "transports": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyRelyingParty": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyRequestOptions": {
+            "type": "object",
+            "properties": {
+                "allowCredentials": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyCredentialDescriptor"
+                    }
+                },
+                "challenge": {
+                    "type": "string"
+                },
+                "rpId": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "type": "integer"
+                },
+                "userVerification": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "transports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.PasskeyUser": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k3m9p-x7q2r"
+                    ]
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.RecoveryCodesStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "remaining": {
+                    "description": "Число неиспользованных кодов восстановления",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "description": "ClientID - OAuth клиент, через который выполнен вход, пусто для собственного фронтенда",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current - сессия, из которой выполнен запрос",
+                    "type": "boolean"
+                },
+                "device": {
+                    "description": "Device - браузер и система, определенные по User-Agent",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.TOTPCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_auth.TOTPSetupResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "Секрет в base32 для ручного ввода",
+                    "type": "string"
+                },
+                "uri": {
+                    "description": "otpauth:// ссылка для QR кода",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_keys.SigningKeyResponse": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "description": "Алгоритм подписи",
+                    "type": "string",
+                    "example": "RS256"
+                },
+                "created_at": {
+                    "description": "Время создания",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Идентификатор ключа (kid)",
+                    "type": "string"
+                },
+                "retired_at": {
+                    "description": "Время вывода из оборота, пусто для текущего ключа",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.AuthorizeRequest": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "description": "Идентификатор клиента",
+                    "type": "string"
+                },
+                "code_challenge": {
+                    "description": "PKCE: BASE64URL(SHA256(code_verifier))",
+                    "type": "string"
+                },
+                "code_challenge_method": {
+                    "description": "PKCE: поддерживается только S256",
+                    "type": "string",
+                    "example": "S256"
+                },
+                "nonce": {
+                    "description": "Значение для защиты ID токена от повторного использования",
+                    "type": "string"
+                },
+                "redirect_uri": {
+                    "description": "Адрес возврата, должен быть зарегистрирован для клиента",
+                    "type": "string"
+                },
+                "response_type": {
+                    "description": "Должен быть code",
+                    "type": "string",
+                    "example": "code"
+                },
+                "scope": {
+                    "description": "Запрашиваемые scope через пробел",
+                    "type": "string",
+                    "example": "openid email"
+                },
+                "state": {
+                    "description": "Непрозрачное значение, возвращается клиенту без изменений",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.AuthorizeResponse": {
+            "type": "object",
+            "properties": {
+                "redirect_uri": {
+                    "description": "redirect_uri клиента с кодом авторизации и state",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.ClientRequest": {
+            "type": "object",
+            "properties": {
+                "grant_types": {
+                    "description": "Разрешенные типы грантов",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "authorization_code",
+                        "refresh_token",
+                        "client_credentials"
+                    ]
+                },
+                "id": {
+                    "description": "Идентификатор клиента (client_id)",
+                    "type": "string",
+                    "example": "admin-panel"
+                },
+                "is_public": {
+                    "description": "Публичный клиент (без секрета, только с PKCE)",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Название приложения",
+                    "type": "string",
+                    "example": "Admin panel"
+                },
+                "redirect_uris": {
+                    "description": "Разрешенные адреса возврата",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "description": "Разрешенные scope",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "openid",
+                        "email"
+                    ]
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.ClientResponse": {
+            "type": "object",
+            "properties": {
+                "client_secret": {
+                    "type": "string"
+                },
+                "grant_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_public": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "redirect_uris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.DiscoveryResponse": {
+            "type": "object",
+            "properties": {
+                "authorization_endpoint": {
+                    "type": "string"
+                },
+                "claims_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "code_challenge_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "grant_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id_token_signing_alg_values_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "introspection_endpoint": {
+                    "type": "string"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "jwks_uri": {
+                    "type": "string"
+                },
+                "response_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "revocation_endpoint": {
+                    "type": "string"
+                },
+                "scopes_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "statuses": {
+                "token_endpoint": {
+                    "type": "string"
+                },
+                "token_endpoint_auth_methods_supported": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.OrderStatus"
+                        "type": "string"
                     }
                 }
             }
         },
-        "api.ExtendedProductResponse": {
+        "github_com_phenirain_sso_internal_dto_oauth.ErrorResponse": {
             "type": "object",
             "properties": {
-                "brand": {
-                    "type": "string"
-                },
-                "brand_id": {
-                    "type": "integer"
+                "error": {
+                    "type": "string",
+                    "example": "invalid_grant"
                 },
-                "description": {
+                "error_description": {
                     "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_oauth.IntrospectResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
                 },
-                "productInfo": {
-                    "$ref": "#/definitions/api.ProductResponse"
+                "aud": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "product_type": {
+                "client_id": {
                     "type": "string"
                 },
-                "product_type_id": {
+                "exp": {
                     "type": "integer"
                 },
-                "texture": {
+                "iat": {
+                    "type": "integer"
+                },
+                "jti": {
                     "type": "string"
                 },
-                "texture_id": {
+                "role": {
+                    "description": "Роль пользователя, отсутствует у сервисных токенов",
                     "type": "integer"
                 },
-                "volume": {
-                    "type": "integer"
+                "scope": {
+                    "type": "string"
                 },
-                "volume_type": {
+                "sub": {
+                    "description": "id пользователя или client_id сервиса",
                     "type": "string"
                 },
-                "volume_type_id": {
-                    "type": "integer"
+                "token_type": {
+                    "description": "Тип токена: access, refresh или service",
+                    "type": "string",
+                    "example": "access"
                 }
             }
         },
-        "api.OrderClient": {
+        "github_com_phenirain_sso_internal_dto_oauth.TokenResponse": {
             "type": "object",
             "properties": {
-                "email": {
+                "access_token": {
                     "type": "string"
                 },
-                "full_name": {
+                "expires_in": {
+                    "type": "integer"
+                },
+                "id_token": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "refresh_token": {
+                    "type": "string"
                 },
-                "phone": {
+                "scope": {
                     "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
                 }
             }
         },
-        "api.OrderProduct": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-any": {
             "type": "object",
             "properties": {
-                "article": {
-                    "type": "string"
+                "data": {
+                    "description": "Данные ответа"
                 },
-                "name": {
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "price": {
-                    "type": "number"
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
                 },
-                "quantity": {
-                    "type": "integer"
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "api.OrderResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_auth_PasskeyResponse": {
             "type": "object",
             "properties": {
-                "client": {
-                    "$ref": "#/definitions/api.OrderClient"
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyResponse"
+                    }
                 },
-                "created_at": {
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "status": {
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
                 },
-                "total_amount": {
-                    "type": "number"
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "api.OrderStatus": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-array_github_com_phenirain_sso_internal_dto_auth_SessionResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.SessionResponse"
+                    }
                 },
-                "id": {
-                    "type": "integer"
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
                 },
-                "name": {
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "api.OrdersResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_AuthResponse": {
             "type": "object",
             "properties": {
-                "orders": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.OrderResponse"
-                    }
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.AuthResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "api.ProductResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_ExternalLoginStartResponse": {
             "type": "object",
             "properties": {
-                "article": {
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.ExternalLoginStartResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "image_url": {
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
                 },
-                "is_archived": {
+                "success": {
+                    "description": "Статус ответа",
                     "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyCreationOptions": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyCreationOptions"
+                        }
+                    ]
                 },
-                "name": {
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "price": {
-                    "type": "number"
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
                 },
-                "quantity": {
-                    "type": "integer"
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "api.ProductsResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyRequestOptions": {
             "type": "object",
             "properties": {
-                "products": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.ProductResponse"
-                    }
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyRequestOptions"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.ClientUsersResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_PasskeyResponse": {
             "type": "object",
             "properties": {
-                "users": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/client.UserResponse"
-                    }
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.PasskeyResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.ClientsResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_RecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "clients": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.ClientResponse"
-                    }
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.RecoveryCodesResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.RoleResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_RecoveryCodesStatusResponse": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.RecoveryCodesStatusResponse"
+                        }
+                    ]
                 },
-                "role_name": {
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.RolesResponse": {
+        "github_com_phenirain_sso_internal_dto_response.ApiResponse-github_com_phenirain_sso_internal_dto_auth_TOTPSetupResponse": {
             "type": "object",
             "properties": {
-                "roles": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/client.RoleResponse"
-                    }
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.TOTPSetupResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.UserRequest": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelResponse": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.BaseModelResponse"
+                        }
+                    ]
                 },
-                "is_archived": {
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
                     "type": "boolean"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.BaseModelsResponse"
+                        }
+                    ]
                 },
-                "login": {
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "password": {
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
                 },
-                "role_id": {
-                    "type": "integer"
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "client.UserResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.ClientResponse"
+                        }
+                    ]
                 },
-                "login": {
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "role": {
-                    "$ref": "#/definitions/client.RoleResponse"
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_auth.AuthRequest": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse": {
             "type": "object",
             "properties": {
-                "login": {
-                    "description": "Логин пользователя",
-                    "type": "string",
-                    "example": "user@example.com"
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.ExtendedOrderResponse"
+                        }
+                    ]
                 },
-                "password": {
-                    "description": "Пароль пользователя",
-                    "type": "string",
-                    "example": "P@ssw0rd!"
+                "details": {
+                    "description": "Детали ошибки",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_auth.AuthResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedProductResponse": {
             "type": "object",
             "properties": {
-                "access_token": {
-                    "description": "Access Token для доступа к защищенным ресурсам",
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.ExtendedProductResponse"
+                        }
+                    ]
+                },
+                "details": {
+                    "description": "Детали ошибки",
                     "type": "string"
                 },
-                "refresh_token": {
-                    "description": "Refresh Token для обновления пары токенов",
+                "message": {
+                    "description": "Сообщение (комментарий) об ошибке",
                     "type": "string"
                 },
-                "role_id": {
-                    "description": "Role ID пользователя (1=client, 2=manager, 3=admin)",
-                    "type": "integer"
+                "success": {
+                    "description": "Статус ответа",
+                    "type": "boolean"
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.ApiResponse-any": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "description": "Данные ответа"
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.OrdersResponse"
+                        }
+                    ]
                 },
                 "details": {
                     "description": "Детали ошибки",
@@ -2011,14 +4478,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.BaseModelResponse"
+                            "$ref": "#/definitions/api.ProductsResponse"
                         }
                     ]
                 },
@@ -2036,14 +4503,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_BaseModelsResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_auth_SessionResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.BaseModelsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_auth.SessionResponse"
                         }
                     ]
                 },
@@ -2061,14 +4528,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_ClientResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_keys_SigningKeyResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.ClientResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_keys.SigningKeyResponse"
                         }
                     ]
                 },
@@ -2086,14 +4553,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedOrderResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-array_github_com_phenirain_sso_internal_dto_oauth_ClientResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.ExtendedOrderResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ClientResponse"
                         }
                     ]
                 },
@@ -2111,14 +4578,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_ExtendedProductResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-client_ClientUsersResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.ExtendedProductResponse"
+                            "$ref": "#/definitions/client.ClientUsersResponse"
                         }
                     ]
                 },
@@ -2136,14 +4603,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_OrdersResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-client_ClientsResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.OrdersResponse"
+                            "$ref": "#/definitions/client.ClientsResponse"
                         }
                     ]
                 },
@@ -2161,14 +4628,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-api_ProductsResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-client_RolesResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/api.ProductsResponse"
+                            "$ref": "#/definitions/client.RolesResponse"
                         }
                     ]
                 },
@@ -2186,14 +4653,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-client_ClientUsersResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-client_UserResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/client.ClientUsersResponse"
+                            "$ref": "#/definitions/client.UserResponse"
                         }
                     ]
                 },
@@ -2211,14 +4678,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-client_ClientsResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_keys_SigningKeyResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/client.ClientsResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_keys.SigningKeyResponse"
                         }
                     ]
                 },
@@ -2236,14 +4703,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-client_RolesResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_oauth_AuthorizeResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/client.RolesResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.AuthorizeResponse"
                         }
                     ]
                 },
@@ -2261,14 +4728,14 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_phenirain_sso_internal_dto_response.Response-client_UserResponse": {
+        "github_com_phenirain_sso_internal_dto_response.Response-github_com_phenirain_sso_internal_dto_oauth_ClientResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "description": "Данные ответа",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/client.UserResponse"
+                            "$ref": "#/definitions/github_com_phenirain_sso_internal_dto_oauth.ClientResponse"
                         }
                     ]
                 },
@@ -2482,6 +4949,48 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_phenirain_sso_internal_lib_jwt.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "OKP (Ed25519)",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_phenirain_sso_internal_lib_jwt.JWKSet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_phenirain_sso_internal_lib_jwt.JWK"
+                    }
+                }
+            }
+        },
         "internal_application_auth.ForgotPasswordRequest": {
             "type": "object",
             "properties": {
@@ -2491,6 +5000,36 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_application_auth.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_application_auth.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "newPassword123"
+                },
+                "token": {
+                    "description": "Токен из ссылки в письме",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_application_auth.VerifyEmailRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Токен из ссылки в письме",
+                    "type": "string"
+                }
+            }
+        },
         "manager.PaidOrderRequest": {
             "type": "object",
             "properties": {
@@ -49,6 +49,78 @@ app.post('/send-reset-email', async (req, res) => {
   }
 });
 
+// Endpoint для отправки письма подтверждения email после регистрации
+app.post('/send-verification-email', async (req, res) => {
+  const { to, verifyLink, login } = req.body;
+
+  if (!to || !verifyLink || !login) {
+    return res.status(400).json({
+      error: 'Missing required fields: to, verifyLink, login'
+    });
+  }
+
+  console.log(`Sending verification email to: ${to}`);
+  await deliverEmail(res, to, 'Подтверждение email - Cosmetics Shop', generateVerificationEmailHTML(login, verifyLink));
+});
+
+// Endpoint для отправки письма со ссылкой для входа без пароля
+app.post('/send-magic-link-email', async (req, res) => {
+  const { to, magicLink, login } = req.body;
+
+  if (!to || !magicLink || !login) {
+    return res.status(400).json({
+      error: 'Missing required fields: to, magicLink, login'
+    });
+  }
+
+  console.log(`Sending magic link email to: ${to}`);
+  await deliverEmail(res, to, 'Вход в аккаунт - Cosmetics Shop', generateMagicLinkEmailHTML(login, magicLink));
+});
+
+// Endpoint для уведомления о смене пароля
+app.post('/send-password-changed-email', async (req, res) => {
+  const { to, login } = req.body;
+
+  if (!to || !login) {
+    return res.status(400).json({
+      error: 'Missing required fields: to, login'
+    });
+  }
+
+  console.log(`Sending password changed email to: ${to}`);
+  await deliverEmail(res, to, 'Пароль изменен - Cosmetics Shop', generatePasswordChangedEmailHTML(login));
+});
+
+// Отправка письма через Resend и ответ вызывающему сервису
+async function deliverEmail(res, to, subject, html) {
+  try {
+    const { data, error } = await resend.emails.send({
+      from: process.env.FROM_EMAIL,
+      to: to,
+      subject: subject,
+      html: html
+    });
+
+    if (error) {
+      console.error('Resend error:', error);
+      return res.status(400).json({ error });
+    }
+
+    console.log('Email sent successfully:', data.id);
+    res.status(200).json({
+      success: true,
+      messageId: data.id
+    });
+
+  } catch (error) {
+    console.error('Server error:', error);
+    res.status(500).json({
+      error: 'Internal server error',
+      message: error.message
+    });
+  }
+}
+
 // Генерация HTML письма (минималистичный черно-белый стиль)
 function generateResetEmailHTML(login, resetLink) {
   return `
@@ -121,6 +193,150 @@ function generateResetEmailHTML(login, resetLink) {
   `;
 }
 
+// Письмо со ссылкой-кнопкой в том же стиле, что и письмо сброса пароля
+function generateLinkEmailHTML({ title, login, intro, action, buttonText, link, notice }) {
+  return `
+    <!DOCTYPE html>
+    <html>
+    <head>
+      <meta charset="UTF-8">
+      <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    </head>
+    <body style="margin: 0; padding: 20px; font-family: monospace; background: #fff; color: #000;">
+      <div style="max-width: 600px; margin: 0 auto; border: 2px solid #000;">
+
+        <!-- Header -->
+        <div style="background: #000; color: #fff; padding: 20px; text-align: center;">
+          <div style="font-size: 24px; font-weight: bold;">
+            ${title}
+          </div>
+          <div style="margin-top: 10px; font-size: 14px; letter-spacing: 2px;">
+            COSMETICS SHOP
+          </div>
+        </div>
+
+        <!-- Content -->
+        <div style="padding: 30px;">
+          <div style="margin-bottom: 20px;">
+            <strong>Здравствуйте,</strong>
+          </div>
+
+          <div style="margin-bottom: 20px;">
+            ${intro}: <strong>${login}</strong>
+          </div>
+
+          <div style="margin-bottom: 30px;">
+            ${action}
+          </div>
+
+          <!-- Button -->
+          <div style="text-align: center; margin: 30px 0;">
+            <a href="${link}"
+               style="display: inline-block;
+                      background: #000;
+                      color: #fff;
+                      padding: 15px 40px;
+                      text-decoration: none;
+                      border: 2px solid #000;
+                      font-weight: bold;
+                      letter-spacing: 1px;">
+              ${buttonText}
+            </a>
+          </div>
+
+          <div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #000; font-size: 12px; color: #333;">
+            ${notice}
+          </div>
+
+          <div style="margin-top: 10px; font-size: 12px; color: #333;">
+            Или скопируйте ссылку в браузер:<br>
+            <span style="word-break: break-all;">${link}</span>
+          </div>
+        </div>
+
+        <!-- Footer -->
+        <div style="background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; border-top: 1px solid #000;">
+          Cosmetics Shop - Your Beauty Destination
+        </div>
+
+      </div>
+    </body>
+    </html>
+  `;
+}
+
+function generateVerificationEmailHTML(login, verifyLink) {
+  return generateLinkEmailHTML({
+    title: 'EMAIL VERIFICATION',
+    login,
+    intro: 'Спасибо за регистрацию аккаунта',
+    action: 'Чтобы подтвердить email, нажмите на кнопку ниже:',
+    buttonText: 'ПОДТВЕРДИТЬ EMAIL',
+    link: verifyLink,
+    notice: 'Если вы не регистрировались в Cosmetics Shop, просто проигнорируйте это письмо.'
+  });
+}
+
+function generateMagicLinkEmailHTML(login, magicLink) {
+  return generateLinkEmailHTML({
+    title: 'SIGN IN',
+    login,
+    intro: 'Получен запрос на вход без пароля в аккаунт',
+    action: 'Чтобы войти, нажмите на кнопку ниже. Ссылка одноразовая и скоро перестанет действовать:',
+    buttonText: 'ВОЙТИ',
+    link: magicLink,
+    notice: 'Если вы не запрашивали вход, просто проигнорируйте это письмо - без ссылки в аккаунт никто не войдет.'
+  });
+}
+
+// Уведомление без ссылки: действовать нужно, только если пароль менял не владелец аккаунта
+function generatePasswordChangedEmailHTML(login) {
+  return `
+    <!DOCTYPE html>
+    <html>
+    <head>
+      <meta charset="UTF-8">
+      <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    </head>
+    <body style="margin: 0; padding: 20px; font-family: monospace; background: #fff; color: #000;">
+      <div style="max-width: 600px; margin: 0 auto; border: 2px solid #000;">
+
+        <!-- Header -->
+        <div style="background: #000; color: #fff; padding: 20px; text-align: center;">
+          <div style="font-size: 24px; font-weight: bold;">
+            PASSWORD CHANGED
+          </div>
+          <div style="margin-top: 10px; font-size: 14px; letter-spacing: 2px;">
+            COSMETICS SHOP
+          </div>
+        </div>
+
+        <!-- Content -->
+        <div style="padding: 30px;">
+          <div style="margin-bottom: 20px;">
+            <strong>Здравствуйте,</strong>
+          </div>
+
+          <div style="margin-bottom: 20px;">
+            Пароль от аккаунта <strong>${login}</strong> был изменен.
+          </div>
+
+          <div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #000; font-size: 12px; color: #333;">
+            Если вы не меняли пароль, немедленно восстановите доступ через сброс пароля и обратитесь в поддержку.
+          </div>
+        </div>
+
+        <!-- Footer -->
+        <div style="background: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; border-top: 1px solid #000;">
+          Cosmetics Shop - Your Beauty Destination
+        </div>
+
+      </div>
+    </body>
+    </html>
+  `;
+}
+
 // Health check
 app.get('/health', (req, res) => {
   res.json({ status: 'ok', service: 'email-service' });
@@ -131,4 +347,7 @@ const PORT = process.env.PORT || 3001;
 app.listen(PORT, () => {
   console.log(`Email service running on port ${PORT}`);
   console.log(`Endpoint: http://localhost:${PORT}/send-reset-email`);
+  console.log(`Endpoint: http://localhost:${PORT}/send-verification-email`);
+  console.log(`Endpoint: http://localhost:${PORT}/send-magic-link-email`);
+  console.log(`Endpoint: http://localhost:${PORT}/send-password-changed-email`);
 });
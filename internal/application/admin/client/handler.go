@@ -1,26 +1,42 @@
 package client
 
 import (
+	"errors"
 	"net/http"
 
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
 	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
 	"gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api"
 	pb "gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api/admin"
 	"gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api/admin/messages/client"
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// PasswordPolicy проверяет пароль, назначаемый администратором
+type PasswordPolicy interface {
+	Check(password, login string) error
+}
+
+// PasswordHasher хеширует пароль, назначаемый администратором, тем же алгоритмом, что и при входе
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+}
+
 type ClientHandler struct {
-	s pb.ClientServiceClient
+	s      pb.ClientServiceClient
+	policy PasswordPolicy
+	hasher PasswordHasher
 }
 
-func NewClientHandler(clientService pb.ClientServiceClient) *ClientHandler {
+func NewClientHandler(clientService pb.ClientServiceClient, policy PasswordPolicy, hasher PasswordHasher) *ClientHandler {
 	return &ClientHandler{
-		s: clientService,
+		s:      clientService,
+		policy: policy,
+		hasher: hasher,
 	}
 }
 
@@ -141,7 +157,15 @@ func (h *ClientHandler) CreateOrUpdateUser(c echo.Context) (err error) {
 
 	// Хешируем пароль перед отправкой по gRPC, если он указан
 	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err := h.policy.Check(req.Password, req.Login); err != nil {
+			var policyErr *passwordpolicy.Error
+			if errors.As(err, &policyErr) {
+				return c.JSON(http.StatusOK, response.NewBadResponseWithData("Пароль не соответствует требованиям", err.Error(),
+					&authModels.PasswordViolationsResponse{Violations: policyErr.Violations}))
+			}
+			return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка проверки пароля", err.Error()))
+		}
+		hashedPassword, err := h.hasher.Hash(req.Password)
 		if err != nil {
 			return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка хеширования пароля", err.Error()))
 		}
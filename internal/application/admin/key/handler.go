@@ -0,0 +1,73 @@
+package key
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/domain"
+	keysModels "github.com/phenirain/sso/internal/dto/keys"
+	"github.com/phenirain/sso/internal/dto/response"
+)
+
+type KeysService interface {
+	Rotate(ctx context.Context) (*domain.SigningKey, error)
+	GetSigningKeys(ctx context.Context) ([]domain.SigningKey, error)
+}
+
+type KeyHandler struct {
+	s KeysService
+}
+
+func NewKeyHandler(keysService KeysService) *KeyHandler {
+	return &KeyHandler{
+		s: keysService,
+	}
+}
+
+// GetKeys - получение ключей подписи токенов
+// @Summary Get token signing keys
+// @Tags admin-key
+// @Produce json
+// @Success 200 {object} response.Response[[]keysModels.SigningKeyResponse]
+// @Security BearerAuth
+// @Router /admin/key [get]
+func (h *KeyHandler) GetKeys(c echo.Context) error {
+	keys, err := h.s.GetSigningKeys(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения ключей", err.Error()))
+	}
+
+	result := make([]keysModels.SigningKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, toResponse(key))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// RotateKey - ротация ключа подписи токенов
+// @Summary Rotate token signing key
+// @Description Creates a new signing key and retires the current one. Retired keys keep verifying tokens until they expire.
+// @Tags admin-key
+// @Produce json
+// @Success 200 {object} response.Response[keysModels.SigningKeyResponse]
+// @Security BearerAuth
+// @Router /admin/key/rotate [post]
+func (h *KeyHandler) RotateKey(c echo.Context) error {
+	key, err := h.s.Rotate(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка ротации ключа", err.Error()))
+	}
+
+	result := toResponse(*key)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+func toResponse(key domain.SigningKey) keysModels.SigningKeyResponse {
+	return keysModels.SigningKeyResponse{
+		Id:        key.Id,
+		Algorithm: key.Algorithm,
+		CreatedAt: key.CreatedAt,
+		RetiredAt: key.RetiredAt,
+	}
+}
@@ -0,0 +1,100 @@
+package oauthclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	oauthModels "github.com/phenirain/sso/internal/dto/oauth"
+	"github.com/phenirain/sso/internal/dto/response"
+)
+
+type ClientsService interface {
+	GetClients(ctx context.Context) ([]oauthModels.ClientResponse, error)
+	SaveClient(ctx context.Context, req oauthModels.ClientRequest) (*oauthModels.ClientResponse, error)
+	ResetClientSecret(ctx context.Context, clientId string) (*oauthModels.ClientResponse, error)
+	DeleteClient(ctx context.Context, clientId string) error
+}
+
+type ClientHandler struct {
+	s ClientsService
+}
+
+func NewClientHandler(clientsService ClientsService) *ClientHandler {
+	return &ClientHandler{
+		s: clientsService,
+	}
+}
+
+// GetClients - получение зарегистрированных OAuth клиентов
+// @Summary Get OAuth clients
+// @Tags admin-oauth-client
+// @Produce json
+// @Success 200 {object} response.Response[[]oauthModels.ClientResponse]
+// @Security BearerAuth
+// @Router /admin/oauth-client [get]
+func (h *ClientHandler) GetClients(c echo.Context) error {
+	result, err := h.s.GetClients(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения клиентов", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// CreateOrUpdateClient - создание или обновление OAuth клиента
+// @Summary Create or update OAuth client
+// @Description The client secret is returned only once, when a confidential client is created
+// @Tags admin-oauth-client
+// @Accept json
+// @Produce json
+// @Param request body oauthModels.ClientRequest true "Client request"
+// @Success 200 {object} response.Response[oauthModels.ClientResponse]
+// @Security BearerAuth
+// @Router /admin/oauth-client [post]
+func (h *ClientHandler) CreateOrUpdateClient(c echo.Context) error {
+	var req oauthModels.ClientRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	result, err := h.s.SaveClient(c.Request().Context(), req)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка сохранения клиента", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// ResetClientSecret - перевыпуск секрета OAuth клиента
+// @Summary Reset OAuth client secret
+// @Tags admin-oauth-client
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} response.Response[oauthModels.ClientResponse]
+// @Security BearerAuth
+// @Router /admin/oauth-client/{id}/secret [post]
+func (h *ClientHandler) ResetClientSecret(c echo.Context) error {
+	result, err := h.s.ResetClientSecret(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка перевыпуска секрета", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// DeleteClient - удаление OAuth клиента
+// @Summary Delete OAuth client
+// @Tags admin-oauth-client
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} response.Response[string]
+// @Security BearerAuth
+// @Router /admin/oauth-client/{id} [delete]
+func (h *ClientHandler) DeleteClient(c echo.Context) error {
+	if err := h.s.DeleteClient(c.Request().Context(), c.Param("id")); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка удаления клиента", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Клиент успешно удален"))
+}
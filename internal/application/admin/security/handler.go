@@ -0,0 +1,95 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+)
+
+type SecurityService interface {
+	UnlockUser(ctx context.Context, userId int64) error
+	GetSessions(ctx context.Context, userId int64, accessTokenId string) ([]authModels.SessionResponse, error)
+	RevokeSession(ctx context.Context, userId int64, sessionId string) error
+}
+
+// SecurityHandler - управление безопасностью учетных записей пользователей
+type SecurityHandler struct {
+	s SecurityService
+}
+
+func NewSecurityHandler(securityService SecurityService) *SecurityHandler {
+	return &SecurityHandler{
+		s: securityService,
+	}
+}
+
+// UnlockUser - снятие блокировки входа после неудачных попыток
+// @Summary Unlock user login
+// @Description Clears failed login attempts and lifts the temporary lockout of the user
+// @Tags admin-client
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response[string]
+// @Security BearerAuth
+// @Router /admin/client/user/{id}/unlock [post]
+func (h *SecurityHandler) UnlockUser(c echo.Context) error {
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор", err.Error()))
+	}
+
+	if err := h.s.UnlockUser(c.Request().Context(), userId); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка разблокировки пользователя", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Пользователь разблокирован"))
+}
+
+// GetUserSessions - активные сессии пользователя
+// @Summary List user sessions
+// @Tags admin-client
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response[[]authModels.SessionResponse]
+// @Security BearerAuth
+// @Router /admin/client/user/{id}/sessions [get]
+func (h *SecurityHandler) GetUserSessions(c echo.Context) error {
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор", err.Error()))
+	}
+
+	result, err := h.s.GetSessions(c.Request().Context(), userId, "")
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения сессий", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// RevokeUserSession - завершение сессии пользователя
+// @Summary Revoke user session
+// @Description Ends the session and revokes its refresh and access tokens
+// @Tags admin-client
+// @Produce json
+// @Param id path int true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response[string]
+// @Security BearerAuth
+// @Router /admin/client/user/{id}/sessions/{sessionId} [delete]
+func (h *SecurityHandler) RevokeUserSession(c echo.Context) error {
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор", err.Error()))
+	}
+
+	if err := h.s.RevokeSession(c.Request().Context(), userId, c.Param("sessionId")); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка завершения сессии", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Сессия завершена"))
+}
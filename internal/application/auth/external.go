@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+)
+
+// externalStateCookie - HttpOnly cookie с хешем state: callback принимает state только из браузера, начавшего вход
+const externalStateCookie = "external_login_state"
+
+// StartExternalLogin godoc
+// @Summary Start login via an external provider
+// @Description Returns the provider login page URL and state and sets an HttpOnly cookie that binds the state to this browser. Call it with credentials (cookies) included and navigate to the URL; the callback endpoint accepts the state only together with this cookie
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name from config, e.g. google"
+// @Success 200 {object} response.ApiResponse[authModels.ExternalLoginStartResponse]
+// @Router /auth/external/{provider}/start [get]
+func (h *Handler) StartExternalLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	result, err := h.s.StartExternalLogin(ctx, c.Param("provider"))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка входа через внешний сервис", err.Error()))
+	}
+
+	c.SetCookie(externalStateCookieFor(c, result.StateBinding))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// ExternalLoginCallback godoc
+// @Summary Finish login via an external provider
+// @Description Exchanges the code returned by the provider for the same tokens as /auth/logIn. The external account is linked to the user with the same verified email; a new customer is registered if there is none. Requires the cookie set by the start endpoint, so call it with credentials included from the browser that started the login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name from config, e.g. google"
+// @Param request body authModels.ExternalLoginCallbackRequest true "Code and state from the provider redirect"
+// @Success 200 {object} response.ApiResponse[authModels.AuthResponse]
+// @Router /auth/external/{provider}/callback [post]
+func (h *Handler) ExternalLoginCallback(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.ExternalLoginCallbackRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("external_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Code == "" || req.State == "" {
+		h.m.RecordAuthOperation("external_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "code и state обязательны"))
+	}
+
+	// cookie одноразовая, как и сам state
+	var stateBinding string
+	if cookie, err := c.Cookie(externalStateCookie); err == nil {
+		stateBinding = cookie.Value
+	}
+	expired := externalStateCookieFor(c, "")
+	expired.MaxAge = -1
+	c.SetCookie(expired)
+
+	result, err := h.s.FinishExternalLogin(ctx, c.Param("provider"), req.Code, req.State, stateBinding)
+	if err != nil {
+		h.m.RecordAuthOperation("external_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка входа через внешний сервис", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("external_login", "success", roleIDToName(result.RoleId))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// externalStateCookieFor - cookie привязки state, видна только маршрутам входа через провайдера
+func externalStateCookieFor(c echo.Context, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     externalStateCookie,
+		Value:    value,
+		Path:     "/auth/external/" + c.Param("provider"),
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -2,21 +2,46 @@ package auth
 
 import (
 	"context"
-	"encoding/base64"
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	authModels "github.com/phenirain/sso/internal/dto/auth"
 	"github.com/phenirain/sso/internal/dto/response"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
+	"github.com/phenirain/sso/pkg/contextkeys"
 	"github.com/phenirain/sso/pkg/metrics"
 )
 
 type AuthService interface {
 	Auth(ctx context.Context, request authModels.AuthRequest, isNew bool) (*authModels.AuthResponse, error)
 	Refresh(ctx context.Context, refreshToken string) (*authModels.AuthResponse, error)
-	ResetPassword(ctx context.Context, login, newPassword string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangeExpiredPassword(ctx context.Context, passwordChangeToken, newPassword string) (*authModels.AuthResponse, error)
+	ChangePassword(ctx context.Context, userId int64, accessTokenId string, req authModels.ChangePasswordRequest) error
 	SendPasswordResetEmail(ctx context.Context, login string) error
+	Logout(ctx context.Context, userId int64, refreshToken string) error
+	LogoutAll(ctx context.Context, userId int64) error
+	GetSessions(ctx context.Context, userId int64, accessTokenId string) ([]authModels.SessionResponse, error)
+	RevokeSession(ctx context.Context, userId int64, sessionId string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, login string) error
+	SetupTOTP(ctx context.Context, userId int64) (*authModels.TOTPSetupResponse, error)
+	ConfirmTOTP(ctx context.Context, userId int64, code string) (*authModels.RecoveryCodesResponse, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (*authModels.AuthResponse, error)
+	RegenerateRecoveryCodes(ctx context.Context, userId int64) (*authModels.RecoveryCodesResponse, error)
+	GetRecoveryCodesStatus(ctx context.Context, userId int64) (*authModels.RecoveryCodesStatusResponse, error)
+	SendMagicLink(ctx context.Context, login string) error
+	LoginWithMagicLink(ctx context.Context, token string) (*authModels.AuthResponse, error)
+	StartExternalLogin(ctx context.Context, provider string) (*authModels.ExternalLoginStartResponse, error)
+	FinishExternalLogin(ctx context.Context, provider, code, state, stateBinding string) (*authModels.AuthResponse, error)
+	BeginPasskeyRegistration(ctx context.Context, userId int64) (*authModels.PasskeyCreationOptions, error)
+	FinishPasskeyRegistration(ctx context.Context, userId int64, req authModels.PasskeyRegistrationRequest) (*authModels.PasskeyResponse, error)
+	BeginPasskeyLogin(ctx context.Context, login string) (*authModels.PasskeyRequestOptions, error)
+	FinishPasskeyLogin(ctx context.Context, req authModels.PasskeyLoginRequest) (*authModels.AuthResponse, error)
+	GetPasskeys(ctx context.Context, userId int64) ([]authModels.PasskeyResponse, error)
+	DeletePasskey(ctx context.Context, userId int64, passkeyId string) error
 }
 
 type Handler struct {
@@ -31,6 +56,15 @@ func NewHandler(auth AuthService, m *metrics.Metrics) *Handler {
 	}
 }
 
+// badResponse отдает нарушения требований к паролю списком в data, остальные ошибки - как обычно
+func badResponse(message string, err error) any {
+	var policyErr *passwordpolicy.Error
+	if errors.As(err, &policyErr) {
+		return response.NewBadResponseWithData(message, err.Error(), &authModels.PasswordViolationsResponse{Violations: policyErr.Violations})
+	}
+	return response.NewBadResponse[any](message, err.Error())
+}
+
 // roleIDToName converts role ID to role name for metrics
 // 1 = client (buyer), 2 = manager, 3 = admin
 func roleIDToName(roleID int64) string {
@@ -48,6 +82,7 @@ func roleIDToName(roleID int64) string {
 
 // LogIn godoc
 // @Summary Login user
+// @Description When two-factor authentication is enabled, returns mfa_required and mfa_token instead of tokens; finish with /auth/2fa/verify
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -60,6 +95,7 @@ func (h *Handler) LogIn(c echo.Context) error {
 
 // SignUp godoc
 // @Summary Register user
+// @Description When email.require_verification is enabled, returns email_verification_required instead of tokens; log in after following the link from the email
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -72,6 +108,7 @@ func (h *Handler) SignUp(c echo.Context) error {
 
 // Refresh godoc
 // @Summary Refresh access token
+// @Description When a staff password has expired, returns password_expired and password_change_token instead of tokens; finish with /auth/changeExpiredPassword
 // @Tags auth
 // @Produce json
 // @Success 200 {object} authModels.AuthResponse
@@ -100,12 +137,92 @@ func (h *Handler) Refresh(c echo.Context) error {
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка обновления токена", err.Error()))
 	}
 
+	// Password expired, the session continues only after /auth/changeExpiredPassword
+	if result.PasswordExpired {
+		h.m.RecordAuthOperation("refresh", "password_expired", "unknown")
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
 	// Use actual role from response
 	roleName := roleIDToName(result.RoleId)
 	h.m.RecordAuthOperation("refresh", "success", roleName)
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 }
 
+// LogoutRequest represents the request body for logout
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout godoc
+// @Summary Logout current session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LogoutRequest true "Refresh token of the session"
+// @Success 200 {object} response.ApiResponse[any]
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *Handler) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("logout", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	var req LogoutRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("logout", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if req.RefreshToken == "" {
+		h.m.RecordAuthOperation("logout", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Refresh токен обязателен"))
+	}
+
+	if err := h.s.Logout(ctx, userId, req.RefreshToken); err != nil {
+		h.m.RecordAuthOperation("logout", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка выхода", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("logout", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Сессия завершена"))
+}
+
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[any]
+// @Security BearerAuth
+// @Router /auth/logoutAll [post]
+func (h *Handler) LogoutAll(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("logout_all", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	if err := h.s.LogoutAll(ctx, userId); err != nil {
+		h.m.RecordAuthOperation("logout_all", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка выхода", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("logout_all", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Все сессии завершены"))
+}
+
+// roleFromContext returns role name of the authenticated user for metrics
+func roleFromContext(c echo.Context) string {
+	roleId, _ := c.Request().Context().Value(contextkeys.RoleIDCtxKey).(int64)
+	return roleIDToName(roleId)
+}
+
 // ForgotPasswordRequest represents the request body for forgot password
 type ForgotPasswordRequest struct {
 	Login string `json:"login" example:"user@example.com"`
@@ -144,46 +261,189 @@ func (h *Handler) ForgotPassword(c echo.Context) error {
 	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Письмо для сброса пароля отправлено на почту"))
 }
 
+type ResetPasswordRequest struct {
+	// Токен из ссылки в письме
+	Token    string `json:"token"`
+	Password string `json:"password" example:"newPassword123"`
+}
+
 // ResetPassword godoc
 // @Summary Reset user password
+// @Description Sets a new password using the single-use token from the reset email and ends all user sessions
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param request body authModels.AuthRequest true "Login and new password"
+// @Param request body ResetPasswordRequest true "Reset token and new password"
 // @Success 200 {object} response.ApiResponse[any]
 // @Router /auth/resetPassword [post]
 func (h *Handler) ResetPassword(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req authModels.AuthRequest
+	var req ResetPasswordRequest
 	if err := c.Bind(&req); err != nil {
 		h.m.RecordAuthOperation("password_reset", "failure", "unknown")
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
 	}
 
-	if req.Login == "" {
+	if req.Token == "" {
 		h.m.RecordAuthOperation("password_reset", "failure", "unknown")
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Логин обязателен"))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Токен сброса пароля обязателен"))
 	}
 	if req.Password == "" {
 		h.m.RecordAuthOperation("password_reset", "failure", "unknown")
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Новый пароль обязателен"))
 	}
 
-	// Декодируем login из base64 если он закодирован
-	decodedLogin := req.Login
-	if decoded, err := base64.StdEncoding.DecodeString(req.Login); err == nil {
-		decodedLogin = string(decoded)
-	}
-
-	err := h.s.ResetPassword(ctx, decodedLogin, req.Password)
+	err := h.s.ResetPassword(ctx, req.Token, req.Password)
 	if err != nil {
 		h.m.RecordAuthOperation("password_reset", "failure", "client")
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка сброса пароля", err.Error()))
+		return c.JSON(http.StatusOK, badResponse("Ошибка сброса пароля", err))
 	}
 
 	h.m.RecordAuthOperation("password_reset", "success", "client")
-	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty(fmt.Sprintf("Пароль для пользователя %s успешно изменен", decodedLogin)))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Пароль успешно изменен"))
+}
+
+// ChangeExpiredPassword godoc
+// @Summary Change an expired password and finish login
+// @Description Staff passwords expire periodically; /auth/logIn then returns password_expired and password_change_token instead of tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ChangeExpiredPasswordRequest true "Password change token and new password"
+// @Success 200 {object} response.ApiResponse[authModels.AuthResponse]
+// @Router /auth/changeExpiredPassword [post]
+func (h *Handler) ChangeExpiredPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.ChangeExpiredPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("password_rotation", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.PasswordChangeToken == "" || req.NewPassword == "" {
+		h.m.RecordAuthOperation("password_rotation", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Токен и новый пароль обязательны"))
+	}
+
+	result, err := h.s.ChangeExpiredPassword(ctx, req.PasswordChangeToken, req.NewPassword)
+	if err != nil {
+		h.m.RecordAuthOperation("password_rotation", "failure", "unknown")
+		return c.JSON(http.StatusOK, badResponse("Ошибка смены пароля", err))
+	}
+
+	h.m.RecordAuthOperation("password_rotation", "success", roleIDToName(result.RoleId))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// ChangePassword godoc
+// @Summary Change password of the current user
+// @Description Verifies the old password and sets a new one. With revoke_other_sessions all sessions except the current one are ended. A notice is sent to the user's email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.ChangePasswordRequest true "Old and new password"
+// @Success 200 {object} response.ApiResponse[any]
+// @Security BearerAuth
+// @Router /auth/changePassword [post]
+func (h *Handler) ChangePassword(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("password_change", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+	tokenId, _ := ctx.Value(contextkeys.TokenIDCtxKey).(string)
+
+	var req authModels.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("password_change", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.OldPassword == "" || req.NewPassword == "" {
+		h.m.RecordAuthOperation("password_change", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Старый и новый пароль обязательны"))
+	}
+
+	if err := h.s.ChangePassword(ctx, userId, tokenId, req); err != nil {
+		status := "failure"
+		if errors.Is(err, authErrors.ErrAccountLocked) {
+			status = "locked"
+		}
+		h.m.RecordAuthOperation("password_change", status, roleFromContext(c))
+		return c.JSON(http.StatusOK, badResponse("Ошибка смены пароля", err))
+	}
+
+	h.m.RecordAuthOperation("password_change", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Пароль успешно изменен"))
+}
+
+type VerifyEmailRequest struct {
+	// Токен из ссылки в письме
+	Token string `json:"token"`
+}
+
+// VerifyEmail godoc
+// @Summary Confirm user email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} response.ApiResponse[any]
+// @Router /auth/verifyEmail [post]
+func (h *Handler) VerifyEmail(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("verify_email", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if req.Token == "" {
+		h.m.RecordAuthOperation("verify_email", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Токен подтверждения обязателен"))
+	}
+
+	if err := h.s.VerifyEmail(ctx, req.Token); err != nil {
+		h.m.RecordAuthOperation("verify_email", "failure", "client")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка подтверждения email", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("verify_email", "success", "client")
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Email подтвержден"))
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend email verification letter
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "User login"
+// @Success 200 {object} response.ApiResponse[any]
+// @Router /auth/resendVerificationEmail [post]
+func (h *Handler) ResendVerificationEmail(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("resend_verification", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if req.Login == "" {
+		h.m.RecordAuthOperation("resend_verification", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Логин обязателен"))
+	}
+
+	if err := h.s.ResendVerificationEmail(ctx, req.Login); err != nil {
+		h.m.RecordAuthOperation("resend_verification", "failure", "client")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка отправки письма", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("resend_verification", "success", "client")
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Письмо для подтверждения email отправлено на почту"))
 }
 
 func (h *Handler) auth(c echo.Context, isNew bool) error {
@@ -212,8 +472,30 @@ func (h *Handler) auth(c echo.Context, isNew bool) error {
 	result, err := h.s.Auth(ctx, req, isNew)
 	if err != nil {
 		// On error, we don't know the role yet, so use "unknown"
-		h.m.RecordAuthOperation(operation, "failure", "unknown")
-		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+		status := "failure"
+		if errors.Is(err, authErrors.ErrAccountLocked) {
+			status = "locked"
+		}
+		h.m.RecordAuthOperation(operation, status, "unknown")
+		return c.JSON(http.StatusOK, badResponse("Ошибка авторизации", err))
+	}
+
+	// Password accepted, login continues with the second factor
+	if result.MFARequired {
+		h.m.RecordAuthOperation(operation, "mfa_required", "unknown")
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	// User created, tokens are issued after the email is verified
+	if result.EmailVerificationRequired {
+		h.m.RecordAuthOperation(operation, "email_verification_required", "client")
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	// Password accepted but expired, tokens are issued after /auth/changeExpiredPassword
+	if result.PasswordExpired {
+		h.m.RecordAuthOperation(operation, "password_expired", "unknown")
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 	}
 
 	// Successfully authenticated - use actual role from response
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+)
+
+// SendMagicLink godoc
+// @Summary Send passwordless login link
+// @Description Emails a one-time, short-lived login link. Limited per login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "User login"
+// @Success 200 {object} response.ApiResponse[any]
+// @Router /auth/magicLink [post]
+func (h *Handler) SendMagicLink(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("magic_link", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if req.Login == "" {
+		h.m.RecordAuthOperation("magic_link", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Логин обязателен"))
+	}
+
+	if err := h.s.SendMagicLink(ctx, req.Login); err != nil {
+		h.m.RecordAuthOperation("magic_link", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка отправки письма", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("magic_link", "success", "unknown")
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Ссылка для входа отправлена на почту"))
+}
+
+// ConsumeMagicLink godoc
+// @Summary Log in with a magic link
+// @Description Exchanges the token from the email for the same response as /auth/logIn
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MagicLinkConsumeRequest true "Token from the link"
+// @Success 200 {object} response.ApiResponse[authModels.AuthResponse]
+// @Router /auth/magicLink/consume [post]
+func (h *Handler) ConsumeMagicLink(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.MagicLinkConsumeRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("magic_link_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if req.Token == "" {
+		h.m.RecordAuthOperation("magic_link_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Токен обязателен"))
+	}
+
+	result, err := h.s.LoginWithMagicLink(ctx, req.Token)
+	if err != nil {
+		h.m.RecordAuthOperation("magic_link_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	if result.MFARequired {
+		h.m.RecordAuthOperation("magic_link_login", "mfa_required", "unknown")
+	} else {
+		h.m.RecordAuthOperation("magic_link_login", "success", roleIDToName(result.RoleId))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
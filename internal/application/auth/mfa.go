@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// SetupTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generates a new TOTP secret. Two-factor authentication is enabled after /auth/2fa/confirm
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[authModels.TOTPSetupResponse]
+// @Security BearerAuth
+// @Router /auth/2fa/setup [post]
+func (h *Handler) SetupTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("totp_setup", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	result, err := h.s.SetupTOTP(ctx, userId)
+	if err != nil {
+		h.m.RecordAuthOperation("totp_setup", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка настройки двухфакторной аутентификации", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("totp_setup", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enables two-factor authentication and returns one-time recovery codes, shown only once
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.TOTPCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} response.ApiResponse[authModels.RecoveryCodesResponse]
+// @Security BearerAuth
+// @Router /auth/2fa/confirm [post]
+func (h *Handler) ConfirmTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("totp_confirm", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	var req authModels.TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("totp_confirm", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Code == "" {
+		h.m.RecordAuthOperation("totp_confirm", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Код обязателен"))
+	}
+
+	result, err := h.s.ConfirmTOTP(ctx, userId, req.Code)
+	if err != nil {
+		h.m.RecordAuthOperation("totp_confirm", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка подтверждения кода", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("totp_confirm", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// VerifyMFA godoc
+// @Summary Complete login with the second factor
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MFAVerifyRequest true "MFA token from /auth/logIn and TOTP or recovery code"
+// @Success 200 {object} response.ApiResponse[authModels.AuthResponse]
+// @Router /auth/2fa/verify [post]
+func (h *Handler) VerifyMFA(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("mfa_verify", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.MFAToken == "" || req.Code == "" {
+		h.m.RecordAuthOperation("mfa_verify", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Токен и код обязательны"))
+	}
+
+	result, err := h.s.VerifyMFA(ctx, req.MFAToken, req.Code)
+	if err != nil {
+		h.m.RecordAuthOperation("mfa_verify", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("mfa_verify", "success", roleIDToName(result.RoleId))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Issues a new set of recovery codes, previous codes stop working
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[authModels.RecoveryCodesResponse]
+// @Security BearerAuth
+// @Router /auth/2fa/recoveryCodes [post]
+func (h *Handler) RegenerateRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("recovery_codes", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	result, err := h.s.RegenerateRecoveryCodes(ctx, userId)
+	if err != nil {
+		h.m.RecordAuthOperation("recovery_codes", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка выпуска кодов восстановления", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("recovery_codes", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// GetRecoveryCodesStatus godoc
+// @Summary Two-factor status and remaining recovery codes
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[authModels.RecoveryCodesStatusResponse]
+// @Security BearerAuth
+// @Router /auth/2fa/recoveryCodes [get]
+func (h *Handler) GetRecoveryCodesStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	result, err := h.s.GetRecoveryCodesStatus(ctx, userId)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения кодов восстановления", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// BeginPasskeyRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns options for navigator.credentials.create(); finish with /auth/passkey/register
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[authModels.PasskeyCreationOptions]
+// @Security BearerAuth
+// @Router /auth/passkey/register/options [post]
+func (h *Handler) BeginPasskeyRegistration(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("passkey_register", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	result, err := h.s.BeginPasskeyRegistration(ctx, userId)
+	if err != nil {
+		h.m.RecordAuthOperation("passkey_register", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка регистрации passkey", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// FinishPasskeyRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verifies the authenticator response and stores the new passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.PasskeyRegistrationRequest true "PublicKeyCredential.toJSON() result and optional name"
+// @Success 200 {object} response.ApiResponse[authModels.PasskeyResponse]
+// @Security BearerAuth
+// @Router /auth/passkey/register [post]
+func (h *Handler) FinishPasskeyRegistration(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("passkey_register", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	var req authModels.PasskeyRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("passkey_register", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Response.ClientDataJSON == "" || req.Response.AttestationObject == "" {
+		h.m.RecordAuthOperation("passkey_register", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "clientDataJSON и attestationObject обязательны"))
+	}
+
+	result, err := h.s.FinishPasskeyRegistration(ctx, userId, req)
+	if err != nil {
+		h.m.RecordAuthOperation("passkey_register", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка регистрации passkey", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("passkey_register", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// BeginPasskeyLogin godoc
+// @Summary Start passkey login
+// @Description Returns options for navigator.credentials.get(). Login is optional: without it the browser offers any passkey for this site
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.PasskeyLoginOptionsRequest false "Optional login"
+// @Success 200 {object} response.ApiResponse[authModels.PasskeyRequestOptions]
+// @Router /auth/passkey/login/options [post]
+func (h *Handler) BeginPasskeyLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.PasskeyLoginOptionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	result, err := h.s.BeginPasskeyLogin(ctx, req.Login)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// FinishPasskeyLogin godoc
+// @Summary Log in with a passkey
+// @Description Verifies the authenticator assertion and returns the same tokens as /auth/logIn
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.PasskeyLoginRequest true "PublicKeyCredential.toJSON() result"
+// @Success 200 {object} response.ApiResponse[authModels.AuthResponse]
+// @Router /auth/passkey/login [post]
+func (h *Handler) FinishPasskeyLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.PasskeyLoginRequest
+	if err := c.Bind(&req); err != nil {
+		h.m.RecordAuthOperation("passkey_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.RawID == "" {
+		req.RawID = req.ID
+	}
+	if req.RawID == "" || req.Response.ClientDataJSON == "" || req.Response.AuthenticatorData == "" || req.Response.Signature == "" {
+		h.m.RecordAuthOperation("passkey_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "rawId, clientDataJSON, authenticatorData и signature обязательны"))
+	}
+
+	result, err := h.s.FinishPasskeyLogin(ctx, req)
+	if err != nil {
+		h.m.RecordAuthOperation("passkey_login", "failure", "unknown")
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("passkey_login", "success", roleIDToName(result.RoleId))
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// GetPasskeys godoc
+// @Summary List passkeys of the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[[]authModels.PasskeyResponse]
+// @Security BearerAuth
+// @Router /auth/passkeys [get]
+func (h *Handler) GetPasskeys(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	result, err := h.s.GetPasskeys(ctx, userId)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения passkey", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&result))
+}
+
+// DeletePasskey godoc
+// @Summary Delete a passkey of the current user
+// @Tags auth
+// @Produce json
+// @Param id path string true "Credential id"
+// @Success 200 {object} response.ApiResponse[any]
+// @Security BearerAuth
+// @Router /auth/passkeys/{id} [delete]
+func (h *Handler) DeletePasskey(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("passkey_delete", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	if err := h.s.DeletePasskey(ctx, userId, c.Param("id")); err != nil {
+		h.m.RecordAuthOperation("passkey_delete", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка удаления passkey", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("passkey_delete", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Passkey удален"))
+}
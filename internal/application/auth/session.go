@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authModels "github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// GetSessions godoc
+// @Summary List active sessions of the current user
+// @Description Each login on a device is a session; the session of this request is marked current
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.ApiResponse[[]authModels.SessionResponse]
+// @Security BearerAuth
+// @Router /auth/sessions [get]
+func (h *Handler) GetSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+	tokenId, _ := ctx.Value(contextkeys.TokenIDCtxKey).(string)
+
+	result, err := h.s.GetSessions(ctx, userId, tokenId)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка получения сессий", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse[[]authModels.SessionResponse](&result))
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session of the current user
+// @Description Ends the session on its device: its refresh token stops working and its access tokens are revoked
+// @Tags auth
+// @Produce json
+// @Param id path string true "Session id"
+// @Success 200 {object} response.ApiResponse[any]
+// @Security BearerAuth
+// @Router /auth/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		h.m.RecordAuthOperation("session_revoke", "failure", "unknown")
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	if err := h.s.RevokeSession(ctx, userId, c.Param("id")); err != nil {
+		h.m.RecordAuthOperation("session_revoke", "failure", roleFromContext(c))
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка завершения сессии", err.Error()))
+	}
+
+	h.m.RecordAuthOperation("session_revoke", "success", roleFromContext(c))
+	return c.JSON(http.StatusOK, response.NewSuccessResponseEmpty("Сессия завершена"))
+}
@@ -0,0 +1,60 @@
+package application
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/ldap"
+	authService "github.com/phenirain/sso/internal/services/auth"
+	"github.com/phenirain/sso/pkg/echomiddleware"
+)
+
+// newDirectoryAuthenticators создает подключения к включенным в конфиге каталогам сотрудников по доменам логина
+func newDirectoryAuthenticators(cfg config.LDAPConfig, log *slog.Logger) map[string]authService.Authenticator {
+	authenticators := make(map[string]authService.Authenticator)
+	for _, directory := range cfg.Directories {
+		if !directory.Enabled {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSpace(directory.Domain))
+		if domain == "" || directory.URL == "" || directory.BaseDN == "" || !strings.Contains(directory.UserFilter, "%s") {
+			log.Warn("Directory is misconfigured, skipping", slog.String("domain", domain))
+			continue
+		}
+		if _, ok := authenticators[domain]; ok {
+			log.Warn("Directory for domain is already configured, skipping", slog.String("domain", domain))
+			continue
+		}
+
+		// каталог может выдавать только роли сотрудников
+		groupRoles := make(map[string]int64, len(directory.GroupRoles))
+		for _, groupRole := range directory.GroupRoles {
+			if groupRole.RoleId != echomiddleware.RoleManager && groupRole.RoleId != echomiddleware.RoleAdmin {
+				log.Warn("Directory group role is not a staff role, skipping",
+					slog.String("domain", domain), slog.String("group", groupRole.Group), slog.Int64("role", groupRole.RoleId))
+				continue
+			}
+			groupRoles[groupRole.Group] = groupRole.RoleId
+		}
+
+		authenticators[domain] = ldap.New(ldap.Config{
+			URL:                directory.URL,
+			StartTLS:           directory.StartTLS,
+			AllowInsecure:      directory.AllowInsecure,
+			InsecureSkipVerify: directory.InsecureSkipVerify,
+			Timeout:            directory.Timeout,
+			BindDN:             directory.BindDN,
+			BindPassword:       directory.BindPassword,
+			BaseDN:             directory.BaseDN,
+			UserFilter:         directory.UserFilter,
+			GroupAttribute:     directory.GroupAttribute,
+			GroupRoles:         groupRoles,
+		})
+		if directory.AllowInsecure && !directory.StartTLS && !strings.HasPrefix(strings.ToLower(directory.URL), "ldaps://") {
+			log.Warn("Directory passwords are sent in clear text", slog.String("domain", domain))
+		}
+		log.Info("Directory login enabled", slog.String("domain", domain))
+	}
+	return authenticators
+}
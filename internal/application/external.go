@@ -0,0 +1,39 @@
+package application
+
+import (
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/oidcclient"
+	authService "github.com/phenirain/sso/internal/services/auth"
+)
+
+// newExternalProviders создает клиентов включенных в конфиге внешних провайдеров входа
+func newExternalProviders(cfg config.ExternalAuthConfig, log *slog.Logger) map[string]authService.ExternalProvider {
+	providers := make(map[string]authService.ExternalProvider)
+	for name, provider := range cfg.Providers {
+		if !provider.Enabled {
+			continue
+		}
+		if provider.ClientID == "" || provider.RedirectURL == "" || (provider.Issuer == "" && provider.AuthURL == "") {
+			log.Warn("External login provider is misconfigured, skipping", slog.String("provider", name))
+			continue
+		}
+		providers[name] = oidcclient.New(oidcclient.Config{
+			Issuer:             provider.Issuer,
+			ClientID:           provider.ClientID,
+			ClientSecret:       provider.ClientSecret,
+			Scopes:             provider.Scopes,
+			RedirectURL:        provider.RedirectURL,
+			AuthURL:            provider.AuthURL,
+			TokenURL:           provider.TokenURL,
+			UserInfoURL:        provider.UserInfoURL,
+			SubjectClaim:       provider.SubjectClaim,
+			EmailClaim:         provider.EmailClaim,
+			EmailVerifiedClaim: provider.EmailVerifiedClaim,
+			TrustEmail:         provider.TrustEmail,
+		})
+		log.Info("External login provider enabled", slog.String("provider", name))
+	}
+	return providers
+}
@@ -0,0 +1,228 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	oauthModels "github.com/phenirain/sso/internal/dto/oauth"
+	"github.com/phenirain/sso/internal/dto/response"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	oauthService "github.com/phenirain/sso/internal/services/oauth"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+type OAuthService interface {
+	ValidateAuthorizeRequest(ctx context.Context, req oauthModels.AuthorizeRequest) error
+	Authorize(ctx context.Context, userId int64, req oauthModels.AuthorizeRequest) (string, error)
+	Token(ctx context.Context, req oauthModels.TokenRequest) (*oauthModels.TokenResponse, error)
+	Introspect(ctx context.Context, req oauthModels.IntrospectRequest) (*oauthModels.IntrospectResponse, error)
+	Revoke(ctx context.Context, req oauthModels.RevokeRequest) error
+}
+
+type Handler struct {
+	s        OAuthService
+	loginURL string
+}
+
+func NewHandler(oauth OAuthService, loginURL string) *Handler {
+	return &Handler{
+		s:        oauth,
+		loginURL: loginURL,
+	}
+}
+
+// Authorize godoc
+// @Summary OAuth2 authorization endpoint
+// @Description Validates the request and redirects the browser to the SSO login page, which confirms it via /oauth2/authorize/approve
+// @Tags oauth2
+// @Param request query oauthModels.AuthorizeRequest true "Authorization request"
+// @Success 302
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Router /oauth2/authorize [get]
+func (h *Handler) Authorize(c echo.Context) error {
+	var req oauthModels.AuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, oauthErrors.ErrInvalidRequest)
+	}
+
+	if err := h.s.ValidateAuthorizeRequest(c.Request().Context(), req); err != nil {
+		// при неизвестном клиенте или redirect_uri перенаправлять нельзя
+		if errors.Is(err, oauthErrors.ErrInvalidClient) || errors.Is(err, oauthErrors.ErrInvalidRedirectURI) {
+			return oauthError(c, err)
+		}
+		return redirectError(c, req, err)
+	}
+
+	loginURL, err := oauthService.RedirectWithParams(h.loginURL, c.QueryParams())
+	if err != nil {
+		return oauthError(c, err)
+	}
+	return c.Redirect(http.StatusFound, loginURL)
+}
+
+// Approve godoc
+// @Summary Issue authorization code for the logged in user
+// @Tags oauth2
+// @Accept json
+// @Produce json
+// @Param request body oauthModels.AuthorizeRequest true "Authorization request received by the login page"
+// @Success 200 {object} response.Response[oauthModels.AuthorizeResponse]
+// @Security BearerAuth
+// @Router /oauth2/authorize/approve [post]
+func (h *Handler) Approve(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Пользователь не авторизован", "Идентификатор пользователя не найден"))
+	}
+
+	var req oauthModels.AuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	redirectURI, err := h.s.Authorize(ctx, userId, req)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации клиента", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&oauthModels.AuthorizeResponse{RedirectURI: redirectURI}))
+}
+
+// Token godoc
+// @Summary OAuth2 token endpoint
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in the authorization request"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret (client_secret_post), alternatively use HTTP Basic auth"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Requested scopes (client_credentials)"
+// @Success 200 {object} oauthModels.TokenResponse
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Router /oauth2/token [post]
+func (h *Handler) Token(c echo.Context) error {
+	var req oauthModels.TokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, oauthErrors.ErrInvalidRequest)
+	}
+	// client_secret_basic имеет приоритет над параметрами формы
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		req.ClientId, req.ClientSecret = clientId, clientSecret
+	}
+
+	result, err := h.s.Token(c.Request().Context(), req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, result)
+}
+
+// Introspect godoc
+// @Summary OAuth2 token introspection (RFC 7662)
+// @Description Reports whether a token issued by this server is active, including its revocation state
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access, refresh or service token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret (client_secret_post), alternatively use HTTP Basic auth"
+// @Success 200 {object} oauthModels.IntrospectResponse
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Failure 401 {object} oauthModels.ErrorResponse
+// @Router /oauth2/introspect [post]
+func (h *Handler) Introspect(c echo.Context) error {
+	var req oauthModels.IntrospectRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, oauthErrors.ErrInvalidRequest)
+	}
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		req.ClientId, req.ClientSecret = clientId, clientSecret
+	}
+
+	result, err := h.s.Introspect(c.Request().Context(), req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, result)
+}
+
+// Revoke godoc
+// @Summary OAuth2 token revocation (RFC 7009)
+// @Description Revokes an access or refresh token issued to the client. Revoking a refresh token ends the whole session
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret (client_secret_post), alternatively use HTTP Basic auth"
+// @Success 200
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Failure 401 {object} oauthModels.ErrorResponse
+// @Router /oauth2/revoke [post]
+func (h *Handler) Revoke(c echo.Context) error {
+	var req oauthModels.RevokeRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, oauthErrors.ErrInvalidRequest)
+	}
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		req.ClientId, req.ClientSecret = clientId, clientSecret
+	}
+
+	if err := h.s.Revoke(c.Request().Context(), req); err != nil {
+		return oauthError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// oauthError отвечает ошибкой в формате RFC 6749. Внутренние ошибки не раскрываются
+func oauthError(c echo.Context, err error) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	var oerr *oauthErrors.Error
+	if !errors.As(err, &oerr) {
+		slog.Error("oauth request failed", "err", err)
+		return c.JSON(http.StatusInternalServerError, oauthModels.ErrorResponse{Error: "server_error"})
+	}
+
+	status := http.StatusBadRequest
+	if oerr.Code == oauthErrors.ErrInvalidClient.Code {
+		status = http.StatusUnauthorized
+	}
+	return c.JSON(status, oauthModels.ErrorResponse{
+		Error:            oerr.Code,
+		ErrorDescription: oerr.Description,
+	})
+}
+
+// redirectError возвращает ошибку клиенту через его redirect_uri (RFC 6749, раздел 4.1.2.1)
+func redirectError(c echo.Context, req oauthModels.AuthorizeRequest, err error) error {
+	var oerr *oauthErrors.Error
+	if !errors.As(err, &oerr) {
+		return oauthError(c, err)
+	}
+
+	redirectURI, rerr := oauthService.RedirectWithParams(req.RedirectURI, url.Values{
+		"error":             {oerr.Code},
+		"error_description": {oerr.Description},
+		"state":             {req.State},
+	})
+	if rerr != nil {
+		return oauthError(c, rerr)
+	}
+	return c.Redirect(http.StatusFound, redirectURI)
+}
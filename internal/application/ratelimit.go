@@ -0,0 +1,51 @@
+package application
+
+import (
+	"log/slog"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/pkg/echomiddleware"
+	"github.com/phenirain/sso/pkg/metrics"
+)
+
+// rateLimits - ограничители частоты по группам маршрутов из конфига.
+// Маршруты одной группы делят общий лимит
+type rateLimits map[string]echo.MiddlewareFunc
+
+func newRateLimits(cfg config.RateLimitConfig, m *metrics.Metrics, log *slog.Logger) rateLimits {
+	limits := make(rateLimits)
+	if !cfg.Enabled {
+		log.Info("Rate limiting is disabled")
+		return limits
+	}
+
+	for name, group := range cfg.Groups {
+		if group.Requests <= 0 || group.Period <= 0 {
+			log.Warn("Rate limit group is misconfigured, skipping", slog.String("group", name))
+			continue
+		}
+		keyBy := group.Key
+		if keyBy == "" {
+			keyBy = echomiddleware.RateLimitByIP
+		}
+		limits[name] = echomiddleware.RateLimitMiddleware(echomiddleware.RateLimitConfig{
+			Name:     name,
+			Requests: group.Requests,
+			Period:   group.Period,
+			Burst:    group.Burst,
+			KeyBy:    keyBy,
+		}, m)
+	}
+	return limits
+}
+
+// group возвращает ограничитель группы. Группа без настроек не ограничивается
+func (l rateLimits) group(name string) echo.MiddlewareFunc {
+	if limit, ok := l[name]; ok {
+		return limit
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return next
+	}
+}
@@ -9,18 +9,31 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/phenirain/sso/docs"
 	adminClient "github.com/phenirain/sso/internal/application/admin/client"
+	adminKey "github.com/phenirain/sso/internal/application/admin/key"
+	adminOAuthClient "github.com/phenirain/sso/internal/application/admin/oauthclient"
 	adminOrder "github.com/phenirain/sso/internal/application/admin/order"
 	adminProduct "github.com/phenirain/sso/internal/application/admin/product"
 	adminReport "github.com/phenirain/sso/internal/application/admin/report"
+	adminSecurity "github.com/phenirain/sso/internal/application/admin/security"
 	"github.com/phenirain/sso/internal/application/auth"
 	clientClient "github.com/phenirain/sso/internal/application/client/client"
 	clientOrder "github.com/phenirain/sso/internal/application/client/order"
 	clientProduct "github.com/phenirain/sso/internal/application/client/product"
 	manager "github.com/phenirain/sso/internal/application/manager"
+	"github.com/phenirain/sso/internal/application/oauth"
+	"github.com/phenirain/sso/internal/application/wellknown"
 	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/denylist"
 	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/loginguard"
+	"github.com/phenirain/sso/internal/lib/passwordhash"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
+	"github.com/phenirain/sso/internal/lib/secretbox"
+	oauthRepository "github.com/phenirain/sso/internal/repository/oauth"
 	"github.com/phenirain/sso/internal/repository/user"
 	authService "github.com/phenirain/sso/internal/services/auth"
+	keysService "github.com/phenirain/sso/internal/services/keys"
+	oauthService "github.com/phenirain/sso/internal/services/oauth"
 	"github.com/phenirain/sso/pkg/echomiddleware"
 	grpcpkg "github.com/phenirain/sso/pkg/grpc"
 	"github.com/phenirain/sso/pkg/metrics"
@@ -33,9 +46,16 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func SetupHTTPServer(cfg *config.Config, db *sqlx.DB, jwt *jwt.JwtLib, log *slog.Logger) (*echo.Echo, *metrics.Metrics, error) {
+func SetupHTTPServer(cfg *config.Config, db *sqlx.DB, jwt *jwt.JwtLib, denylist *denylist.Denylist, keys *keysService.Keys, mfaBox *secretbox.Box, guard *loginguard.Guard, log *slog.Logger) (*echo.Echo, *metrics.Metrics, error) {
 	e := echo.New()
 
+	// от IP клиента зависят ограничение частоты и блокировки входа - подделанным заголовкам не верим
+	ipExtractor, err := echomiddleware.NewIPExtractor(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.IPExtractor = ipExtractor
+
 	// Initialize Prometheus metrics
 	m := metrics.New()
 
@@ -65,12 +85,15 @@ func SetupHTTPServer(cfg *config.Config, db *sqlx.DB, jwt *jwt.JwtLib, log *slog
 
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Use(middleware.Recover())
-	e.Use(echomiddleware.JwtValidation(jwt))
+	e.Use(echomiddleware.PutClientContext)
+	e.Use(echomiddleware.JwtValidation(jwt, denylist))
 	e.Use(echomiddleware.SlogLoggerMiddleware(log))
 	e.Use(echomiddleware.MetricsMiddleware(m)) // Add metrics middleware
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: cfg.AllowedOrigins,
 		AllowMethods: []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
+		// фронтенд передает cookie привязки state при входе через внешнего провайдера
+		AllowCredentials: true,
 	}))
 
 	// Prometheus metrics endpoint
@@ -140,24 +163,110 @@ func SetupHTTPServer(cfg *config.Config, db *sqlx.DB, jwt *jwt.JwtLib, log *slog
 
 	log.Info("gRPC clients initialized successfully")
 
+	registerWellKnownRoutes(e, jwt, cfg.JWT.Issuer)
+
+	passwordPolicy := &passwordpolicy.Policy{
+		MinLength:      cfg.PasswordPolicy.MinLength,
+		MaxLength:      cfg.PasswordPolicy.MaxLength,
+		RequireUpper:   cfg.PasswordPolicy.RequireUpper,
+		RequireLower:   cfg.PasswordPolicy.RequireLower,
+		RequireDigit:   cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol:  cfg.PasswordPolicy.RequireSymbol,
+		DisallowLogin:  cfg.PasswordPolicy.DisallowLogin,
+		DisallowCommon: cfg.PasswordPolicy.DisallowCommon,
+	}
+
+	passwordHasher, err := passwordhash.New(passwordhash.Config{
+		Algorithm:  cfg.PasswordHash.Algorithm,
+		BcryptCost: cfg.PasswordHash.BcryptCost,
+		Argon2id: passwordhash.Argon2idParams{
+			Memory:      cfg.PasswordHash.Argon2id.Memory,
+			Iterations:  cfg.PasswordHash.Argon2id.Iterations,
+			Parallelism: cfg.PasswordHash.Argon2id.Parallelism,
+			SaltLength:  cfg.PasswordHash.Argon2id.SaltLength,
+			KeyLength:   cfg.PasswordHash.Argon2id.KeyLength,
+		},
+	})
+	if err != nil {
+		log.Error("Invalid password hash config", slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+
 	usersRepository := user.New(db)
-	authService := authService.New(usersRepository, jwt, clientClientService, cfg)
-	registerAuthRoutes(e, authService, m)
-	registerAdminRoutes(e, adminClientService, adminProductService, adminOrderService, adminReportService)
-	registerClientRoutes(e, clientClientService, clientProductService, clientOrderService)
+	authService := authService.New(authService.Deps{
+		Repo:           usersRepository,
+		Jwt:            jwt,
+		Denylist:       denylist,
+		Cipher:         mfaBox,
+		Guard:          guard,
+		Policy:         passwordPolicy,
+		Hasher:         passwordHasher,
+		External:       newExternalProviders(cfg.ExternalAuth, log),
+		Authenticators: newDirectoryAuthenticators(cfg.LDAP, log),
+		ClientService:  clientClientService,
+	}, cfg)
+	limits := newRateLimits(cfg.RateLimit, m, log)
+	registerAuthRoutes(e, authService, m, limits)
+
+	oauthService := oauthService.New(oauthRepository.New(db), usersRepository, jwt, authService, denylist, cfg.OIDC.CodeTTL)
+	registerOAuthRoutes(e, oauthService, cfg.OIDC.LoginURL)
+	registerAdminRoutes(e, adminClientService, adminProductService, adminOrderService, adminReportService, keys, oauthService, authService, passwordPolicy, passwordHasher)
+	registerClientRoutes(e, clientClientService, clientProductService, clientOrderService, limits)
 	registerManagerRoutes(e, managerManagerService)
 
 	return e, m, nil
 }
 
-func registerAuthRoutes(e *echo.Echo, authService auth.AuthService, m *metrics.Metrics) {
+func registerWellKnownRoutes(e *echo.Echo, keys wellknown.KeySet, issuer string) {
+	wellKnownHandler := wellknown.NewHandler(keys, issuer)
+	wellKnown := e.Group("/.well-known")
+	wellKnown.GET("/jwks.json", wellKnownHandler.JWKS)
+	wellKnown.GET("/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+}
+
+func registerOAuthRoutes(e *echo.Echo, oauthService oauth.OAuthService, loginURL string) {
+	oauthHandler := oauth.NewHandler(oauthService, loginURL)
+	oauth2 := e.Group("/oauth2")
+	oauth2.GET("/authorize", oauthHandler.Authorize)
+	oauth2.POST("/authorize/approve", oauthHandler.Approve)
+	oauth2.POST("/token", oauthHandler.Token)
+	oauth2.POST("/introspect", oauthHandler.Introspect)
+	oauth2.POST("/revoke", oauthHandler.Revoke)
+}
+
+func registerAuthRoutes(e *echo.Echo, authService auth.AuthService, m *metrics.Metrics, limits rateLimits) {
 	authHandler := auth.NewHandler(authService, m)
+	loginLimit := limits.group("login")
+	emailLimit := limits.group("email")
 	auth := e.Group("/auth")
-	auth.POST("/logIn", authHandler.LogIn)
-	auth.POST("/signUp", authHandler.SignUp)
+	auth.POST("/logIn", authHandler.LogIn, loginLimit)
+	auth.POST("/signUp", authHandler.SignUp, limits.group("signup"))
 	auth.POST("/refresh", authHandler.Refresh)
-	auth.POST("/forgotPassword", authHandler.ForgotPassword)
+	auth.POST("/forgotPassword", authHandler.ForgotPassword, emailLimit)
 	auth.POST("/resetPassword", authHandler.ResetPassword)
+	auth.POST("/changeExpiredPassword", authHandler.ChangeExpiredPassword, loginLimit)
+	auth.POST("/changePassword", authHandler.ChangePassword, loginLimit)
+	auth.POST("/verifyEmail", authHandler.VerifyEmail)
+	auth.POST("/resendVerificationEmail", authHandler.ResendVerificationEmail, emailLimit)
+	auth.POST("/magicLink", authHandler.SendMagicLink, emailLimit)
+	auth.POST("/magicLink/consume", authHandler.ConsumeMagicLink, loginLimit)
+	auth.GET("/external/:provider/start", authHandler.StartExternalLogin, loginLimit)
+	auth.POST("/external/:provider/callback", authHandler.ExternalLoginCallback, loginLimit)
+	auth.POST("/2fa/setup", authHandler.SetupTOTP)
+	auth.POST("/2fa/confirm", authHandler.ConfirmTOTP)
+	auth.POST("/2fa/verify", authHandler.VerifyMFA, loginLimit)
+	auth.GET("/2fa/recoveryCodes", authHandler.GetRecoveryCodesStatus)
+	auth.POST("/2fa/recoveryCodes", authHandler.RegenerateRecoveryCodes)
+	auth.POST("/passkey/register/options", authHandler.BeginPasskeyRegistration)
+	auth.POST("/passkey/register", authHandler.FinishPasskeyRegistration)
+	auth.POST("/passkey/login/options", authHandler.BeginPasskeyLogin, loginLimit)
+	auth.POST("/passkey/login", authHandler.FinishPasskeyLogin, loginLimit)
+	auth.GET("/passkeys", authHandler.GetPasskeys)
+	auth.DELETE("/passkeys/:id", authHandler.DeletePasskey)
+	auth.POST("/logout", authHandler.Logout)
+	auth.POST("/logoutAll", authHandler.LogoutAll)
+	auth.GET("/sessions", authHandler.GetSessions)
+	auth.DELETE("/sessions/:id", authHandler.RevokeSession)
 }
 
 func registerAdminRoutes(
@@ -166,6 +275,11 @@ func registerAdminRoutes(
 	productService pbAdmin.ProductServiceClient,
 	orderService pbAdmin.OrderServiceClient,
 	reportService pbAdmin.ReportServiceClient,
+	keysService adminKey.KeysService,
+	oauthClientsService adminOAuthClient.ClientsService,
+	securityService adminSecurity.SecurityService,
+	passwordPolicy adminClient.PasswordPolicy,
+	passwordHasher adminClient.PasswordHasher,
 ) {
 	adminGroup := e.Group("/admin", echomiddleware.RoleMiddleware(echomiddleware.RoleAdmin))
 
@@ -192,16 +306,23 @@ func registerAdminRoutes(
 	orderGroup.DELETE("/:id", orderHandler.DeleteOrder)
 
 	// Client routes
-	clientHandler := adminClient.NewClientHandler(clientService)
+	clientHandler := adminClient.NewClientHandler(clientService, passwordPolicy, passwordHasher)
 	clientGroup := adminGroup.Group("/client")
 	clientGroup.GET("/users", clientHandler.GetUsers)
 	clientGroup.GET("/roles", clientHandler.GetRoles)
-	clientGroup.POST("/user", clientHandler.CreateOrUpdateUser)
+	clientGroup.POST("/user", clientHandler.CreateOrUpdateUser, echomiddleware.UserOnlyMiddleware())
 	clientGroup.DELETE("/user/:id", clientHandler.DeleteUser)
 	clientGroup.POST("", clientHandler.CreateClient)
 	clientGroup.GET("", clientHandler.GetClients)
 	clientGroup.DELETE("/:id", clientHandler.DeleteClient)
 
+	// User security routes
+	securityHandler := adminSecurity.NewSecurityHandler(securityService)
+	securityGroup := clientGroup.Group("/user/:id", echomiddleware.UserOnlyMiddleware())
+	securityGroup.POST("/unlock", securityHandler.UnlockUser)
+	securityGroup.GET("/sessions", securityHandler.GetUserSessions)
+	securityGroup.DELETE("/sessions/:sessionId", securityHandler.RevokeUserSession)
+
 	// Report routes
 	reportHandler := adminReport.NewReportHandler(reportService)
 	reportGroup := adminGroup.Group("/report")
@@ -209,6 +330,20 @@ func registerAdminRoutes(
 	reportGroup.GET("/purchases-by-brands/:period", reportHandler.GetPurchasesByBrands)
 	reportGroup.GET("/average-processing-time/:period", reportHandler.GetAverageOrderProcessingTime)
 
+	// Signing key routes
+	keyHandler := adminKey.NewKeyHandler(keysService)
+	keyGroup := adminGroup.Group("/key", echomiddleware.UserOnlyMiddleware())
+	keyGroup.GET("", keyHandler.GetKeys)
+	keyGroup.POST("/rotate", keyHandler.RotateKey)
+
+	// OAuth client routes
+	oauthClientHandler := adminOAuthClient.NewClientHandler(oauthClientsService)
+	oauthClientGroup := adminGroup.Group("/oauth-client", echomiddleware.UserOnlyMiddleware())
+	oauthClientGroup.GET("", oauthClientHandler.GetClients)
+	oauthClientGroup.POST("", oauthClientHandler.CreateOrUpdateClient)
+	oauthClientGroup.POST("/:id/secret", oauthClientHandler.ResetClientSecret)
+	oauthClientGroup.DELETE("/:id", oauthClientHandler.DeleteClient)
+
 	// Orders list route
 	adminGroup.GET("/orders/status/:statusId", orderHandler.GetOrders)
 }
@@ -218,6 +353,7 @@ func registerClientRoutes(
 	clientServiceClient pbClient.ClientServiceClient,
 	productServiceClient pbClient.ProductServiceClient,
 	orderServiceClient pbClient.OrderServiceClient,
+	limits rateLimits,
 ) {
 	clientGroup := e.Group("/client", echomiddleware.RoleMiddleware(echomiddleware.RoleClient))
 
@@ -231,7 +367,7 @@ func registerClientRoutes(
 	pHandler := clientProduct.NewProductHandler(productServiceClient)
 	productGroup := clientGroup.Group("/product")
 	productGroup.GET("/base-models", pHandler.GetAllBaseModels)
-	productGroup.GET("", pHandler.GetProducts)
+	productGroup.GET("", pHandler.GetProducts, limits.group("product_search"))
 	productGroup.GET("/:article", pHandler.GetProduct)
 	productGroup.POST("/:article/favorites", pHandler.ActionProductToFavorites)
 	productGroup.GET("/favorites", pHandler.GetFavoriteProducts)
@@ -0,0 +1,63 @@
+package wellknown
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	oauthModels "github.com/phenirain/sso/internal/dto/oauth"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+type KeySet interface {
+	JWKS() jwt.JWKSet
+	SigningAlgorithm() string
+}
+
+type Handler struct {
+	keys   KeySet
+	issuer string
+}
+
+func NewHandler(keys KeySet, issuer string) *Handler {
+	return &Handler{
+		keys:   keys,
+		issuer: issuer,
+	}
+}
+
+// OpenIDConfiguration godoc
+// @Summary OpenID Connect discovery document
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} oauthModels.DiscoveryResponse
+// @Router /.well-known/openid-configuration [get]
+func (h *Handler) OpenIDConfiguration(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, oauthModels.DiscoveryResponse{
+		Issuer:                            h.issuer,
+		AuthorizationEndpoint:             h.issuer + "/oauth2/authorize",
+		TokenEndpoint:                     h.issuer + "/oauth2/token",
+		IntrospectionEndpoint:             h.issuer + "/oauth2/introspect",
+		RevocationEndpoint:                h.issuer + "/oauth2/revoke",
+		JwksURI:                           h.issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.keys.SigningAlgorithm()},
+		ScopesSupported:                   []string{"openid", "email"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "email", "role", "scope"},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	})
+}
+
+// JWKS godoc
+// @Summary Public keys for token signature verification
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} jwt.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -17,11 +18,24 @@ type Config struct {
 	GRPC             GRPCConfig      `mapstructure:"grpc"`
 	Email            EmailConfig     `mapstructure:"email"`
 	InfluxDB         InfluxDBConfig  `mapstructure:"influxdb"`
+	JWT              JWTConfig       `mapstructure:"jwt"`
+	OIDC             OIDCConfig      `mapstructure:"oidc"`
+	MFA              MFAConfig       `mapstructure:"mfa"`
+	WebAuthn         WebAuthnConfig  `mapstructure:"webauthn"`
+	Lockout          LockoutConfig   `mapstructure:"lockout"`
+	RateLimit        RateLimitConfig `mapstructure:"rate_limit"`
+	PasswordPolicy   PasswordPolicyConfig `mapstructure:"password_policy"`
+	PasswordHash     PasswordHashConfig   `mapstructure:"password_hash"`
+	ExternalAuth     ExternalAuthConfig   `mapstructure:"external_auth"`
+	LDAP             LDAPConfig           `mapstructure:"ldap"`
 }
 
+// HTTPConfig - параметры HTTP сервера. TrustedProxies - IP или подсети балансировщиков перед
+// сервисом: только им доверяется X-Forwarded-For. Пусто - IP клиента берется из соединения
 type HTTPConfig struct {
-	Port    int           `mapstructure:"port"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	Port           int           `mapstructure:"port"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	TrustedProxies []string      `mapstructure:"trusted_proxies"`
 }
 
 type GRPCConfig struct {
@@ -31,8 +45,193 @@ type GRPCConfig struct {
 }
 
 type EmailConfig struct {
-	ServiceURL         string `mapstructure:"service_url"`
-	FrontendResetURL   string `mapstructure:"frontend_reset_url"`
+	ServiceURL       string `mapstructure:"service_url"`
+	FrontendResetURL string `mapstructure:"frontend_reset_url"`
+	// ResetTokenTTL - время жизни ссылки сброса пароля
+	ResetTokenTTL time.Duration `mapstructure:"reset_token_ttl"`
+	// FrontendVerifyURL - страница фронтенда, принимающая токен подтверждения email
+	FrontendVerifyURL string        `mapstructure:"frontend_verify_url"`
+	VerificationTTL   time.Duration `mapstructure:"verification_ttl"`
+	// RequireVerification запрещает вход пользователям с неподтвержденным email
+	RequireVerification bool `mapstructure:"require_verification"`
+	// FrontendMagicLinkURL - страница фронтенда, обменивающая ссылку для входа на токены
+	FrontendMagicLinkURL string        `mapstructure:"frontend_magic_link_url"`
+	MagicLinkTTL         time.Duration `mapstructure:"magic_link_ttl"`
+	// Не больше MagicLinkLimit писем со ссылкой для входа на один логин за MagicLinkWindow
+	MagicLinkLimit  int           `mapstructure:"magic_link_limit"`
+	MagicLinkWindow time.Duration `mapstructure:"magic_link_window"`
+}
+
+// LockoutConfig - защита /auth/logIn от перебора паролей
+type LockoutConfig struct {
+	// Window - окно подсчета неудачных попыток, Duration - время блокировки после превышения лимита
+	Window           time.Duration `mapstructure:"window"`
+	Duration         time.Duration `mapstructure:"duration"`
+	MaxLoginFailures int           `mapstructure:"max_login_failures"`
+	MaxIPFailures    int           `mapstructure:"max_ip_failures"`
+	// Задержка ответа удваивается с каждой неудачей подряд, начиная с BaseDelay
+	BaseDelay time.Duration `mapstructure:"base_delay"`
+	MaxDelay  time.Duration `mapstructure:"max_delay"`
+}
+
+// PasswordPolicyConfig - требования к паролям при регистрации, сбросе и назначении администратором
+type PasswordPolicyConfig struct {
+	MinLength     int  `mapstructure:"min_length"`
+	MaxLength     int  `mapstructure:"max_length"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+	// DisallowLogin запрещает пароль, содержащий логин, DisallowCommon - пароли из списка самых распространенных
+	DisallowLogin  bool `mapstructure:"disallow_login"`
+	DisallowCommon bool `mapstructure:"disallow_common"`
+	// HistorySize - сколько последних паролей нельзя использовать повторно, 0 - без ограничения
+	HistorySize int `mapstructure:"history_size"`
+	// StaffMaxAge - как часто менеджеры и администраторы обязаны менять пароль, 0 - без ограничения
+	StaffMaxAge time.Duration `mapstructure:"staff_max_age"`
+}
+
+// PasswordHashConfig - алгоритм хеширования новых паролей: bcrypt или argon2id.
+// Хеши с параметрами слабее текущих пересчитываются при входе
+type PasswordHashConfig struct {
+	Algorithm  string         `mapstructure:"algorithm"`
+	BcryptCost int            `mapstructure:"bcrypt_cost"`
+	Argon2id   Argon2idConfig `mapstructure:"argon2id"`
+}
+
+// Argon2idConfig - параметры argon2id, Memory - в КиБ
+type Argon2idConfig struct {
+	Memory      uint32 `mapstructure:"memory"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	SaltLength  uint32 `mapstructure:"salt_length"`
+	KeyLength   uint32 `mapstructure:"key_length"`
+}
+
+// ExternalAuthConfig - вход через внешних провайдеров OAuth2/OIDC (Google, Yandex, ...).
+// StateTTL - сколько ждать возврата пользователя от провайдера
+type ExternalAuthConfig struct {
+	StateTTL  time.Duration                     `mapstructure:"state_ttl"`
+	Providers map[string]ExternalProviderConfig `mapstructure:"providers"`
+}
+
+// ExternalProviderConfig - провайдер с discovery задается через Issuer, без него - через
+// AuthURL, TokenURL и UserInfoURL. RedirectURL - страница фронтенда, принимающая code и state
+type ExternalProviderConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	AuthURL      string   `mapstructure:"auth_url"`
+	TokenURL     string   `mapstructure:"token_url"`
+	UserInfoURL  string   `mapstructure:"userinfo_url"`
+	// Имена полей ответа провайдера, если они отличаются от sub, email и email_verified
+	SubjectClaim       string `mapstructure:"subject_claim"`
+	EmailClaim         string `mapstructure:"email_claim"`
+	EmailVerifiedClaim string `mapstructure:"email_verified_claim"`
+	// TrustEmail - провайдер отдает только подтвержденные email и не присылает email_verified
+	TrustEmail bool `mapstructure:"trust_email"`
+}
+
+// LDAPConfig - вход сотрудников с паролем корпоративного каталога (LDAP, Active Directory).
+// Каталог выбирается по домену логина. Списки вместо словарей: viper разбивает ключи по точкам
+type LDAPConfig struct {
+	Directories []LDAPDirectoryConfig `mapstructure:"directories"`
+}
+
+// LDAPDirectoryConfig - каталог одного домена. Пользователь ищется фильтром UserFilter
+// (%s - логин) от имени BindDN, затем пароль проверяется входом от имени найденной записи.
+// ldap:// без StartTLS передает пароли открытым текстом и разрешен только с AllowInsecure
+type LDAPDirectoryConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Domain             string        `mapstructure:"domain"`
+	URL                string        `mapstructure:"url"`
+	StartTLS           bool          `mapstructure:"start_tls"`
+	AllowInsecure      bool          `mapstructure:"allow_insecure"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+	BindDN             string        `mapstructure:"bind_dn"`
+	BindPassword       string        `mapstructure:"bind_password"`
+	BaseDN             string        `mapstructure:"base_dn"`
+	UserFilter         string        `mapstructure:"user_filter"`
+	GroupAttribute     string        `mapstructure:"group_attribute"`
+	// GroupRoles - какие группы каталога дают роль менеджера (2) или администратора (3)
+	GroupRoles []LDAPGroupRoleConfig `mapstructure:"group_roles"`
+}
+
+type LDAPGroupRoleConfig struct {
+	Group  string `mapstructure:"group"`
+	RoleId int64  `mapstructure:"role_id"`
+}
+
+// validate не дает запустить сервис с каталогом, которому пароли сотрудников уйдут без шифрования
+func (c LDAPConfig) validate() error {
+	for _, directory := range c.Directories {
+		insecure := strings.HasPrefix(strings.ToLower(strings.TrimSpace(directory.URL)), "ldap://") && !directory.StartTLS
+		if directory.Enabled && insecure && !directory.AllowInsecure {
+			return fmt.Errorf("ldap directory %q: ldap:// without start_tls sends passwords in clear text, enable start_tls or set allow_insecure", directory.Domain)
+		}
+	}
+	return nil
+}
+
+// RateLimitConfig - ограничение частоты запросов по группам маршрутов
+type RateLimitConfig struct {
+	Enabled bool                            `mapstructure:"enabled"`
+	Groups  map[string]RateLimitGroupConfig `mapstructure:"groups"`
+}
+
+// RateLimitGroupConfig - Requests запросов за Period со всплеском до Burst.
+// Key - чем различаются клиенты: ip, user или route
+type RateLimitGroupConfig struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`
+	Key      string        `mapstructure:"key"`
+}
+
+// JWTConfig - параметры выпуска токенов.
+// Для HS256 используется общий secret, для RS256/EdDSA - приватный ключ из PEM файла
+type JWTConfig struct {
+	Algorithm      string        `mapstructure:"algorithm"`
+	PrivateKeyPath string        `mapstructure:"private_key_path"`
+	KeyId          string        `mapstructure:"key_id"`
+	Issuer         string        `mapstructure:"issuer"`
+	Audience       string        `mapstructure:"audience"`
+	AccessTTL      time.Duration `mapstructure:"access_ttl"`
+	RefreshTTL     time.Duration `mapstructure:"refresh_ttl"`
+	ServiceTTL     time.Duration `mapstructure:"service_ttl"`
+	Leeway         time.Duration `mapstructure:"leeway"`
+}
+
+// OIDCConfig - параметры OpenID Connect провайдера.
+// LoginURL - страница фронтенда SSO, куда /oauth2/authorize отправляет неавторизованного пользователя
+type OIDCConfig struct {
+	LoginURL string        `mapstructure:"login_url"`
+	CodeTTL  time.Duration `mapstructure:"code_ttl"`
+}
+
+// MFAConfig - параметры двухфакторной аутентификации (TOTP).
+// EncryptionKey - 32 байта в base64 для шифрования TOTP секретов и ключей подписи в базе,
+// обязателен везде, кроме env local и dev
+type MFAConfig struct {
+	Issuer        string        `mapstructure:"issuer"`
+	EncryptionKey string        `mapstructure:"encryption_key"`
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`
+	// MaxAttempts - сколько попыток дается по одному токену ожидания второго фактора
+	// или смены устаревшего пароля
+	MaxAttempts   int           `mapstructure:"max_attempts"`
+}
+
+// WebAuthnConfig - параметры входа по passkey.
+// RPID - домен, к которому привязываются ключи; Origins - адреса фронтенда, с которых идут церемонии
+type WebAuthnConfig struct {
+	RPID         string        `mapstructure:"rp_id"`
+	RPName       string        `mapstructure:"rp_name"`
+	Origins      []string      `mapstructure:"origins"`
+	ChallengeTTL time.Duration `mapstructure:"challenge_ttl"`
 }
 
 type InfluxDBConfig struct {
@@ -46,6 +245,46 @@ type InfluxDBConfig struct {
 func LoadConfig() (*Config, error) {
 
 	viper.SetConfigFile("./config/config.yaml")
+	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("jwt.issuer", "sso")
+	viper.SetDefault("jwt.audience", "sso")
+	viper.SetDefault("jwt.access_ttl", time.Hour)
+	viper.SetDefault("jwt.refresh_ttl", time.Hour*24*30)
+	viper.SetDefault("jwt.service_ttl", time.Minute*10)
+	viper.SetDefault("jwt.leeway", time.Second*30)
+	viper.SetDefault("oidc.code_ttl", time.Minute)
+	viper.SetDefault("mfa.issuer", "SSO")
+	viper.SetDefault("mfa.challenge_ttl", time.Minute*5)
+	viper.SetDefault("mfa.max_attempts", 5)
+	viper.SetDefault("webauthn.rp_name", "SSO")
+	viper.SetDefault("webauthn.challenge_ttl", time.Minute*5)
+	viper.SetDefault("lockout.window", time.Minute*15)
+	viper.SetDefault("lockout.duration", time.Minute*15)
+	viper.SetDefault("lockout.max_login_failures", 5)
+	viper.SetDefault("lockout.max_ip_failures", 20)
+	viper.SetDefault("lockout.base_delay", time.Millisecond*500)
+	viper.SetDefault("lockout.max_delay", time.Second*5)
+	viper.SetDefault("password_policy.min_length", 8)
+	viper.SetDefault("password_policy.max_length", 64)
+	viper.SetDefault("password_policy.require_lower", true)
+	viper.SetDefault("password_policy.require_digit", true)
+	viper.SetDefault("password_policy.disallow_login", true)
+	viper.SetDefault("password_policy.disallow_common", true)
+	viper.SetDefault("password_policy.history_size", 5)
+	viper.SetDefault("password_policy.staff_max_age", time.Hour*24*90)
+	viper.SetDefault("external_auth.state_ttl", time.Minute*10)
+	viper.SetDefault("password_hash.algorithm", "bcrypt")
+	viper.SetDefault("password_hash.bcrypt_cost", 10)
+	viper.SetDefault("password_hash.argon2id.memory", 64*1024)
+	viper.SetDefault("password_hash.argon2id.iterations", 3)
+	viper.SetDefault("password_hash.argon2id.parallelism", 2)
+	viper.SetDefault("password_hash.argon2id.salt_length", 16)
+	viper.SetDefault("password_hash.argon2id.key_length", 32)
+	viper.SetDefault("email.reset_token_ttl", time.Minute*30)
+	viper.SetDefault("email.verification_ttl", time.Hour*24)
+	viper.SetDefault("email.magic_link_ttl", time.Minute*15)
+	viper.SetDefault("email.magic_link_limit", 3)
+	viper.SetDefault("email.magic_link_window", time.Hour)
 
 	var cfg Config
 	err := viper.ReadInConfig()
@@ -57,5 +296,8 @@ func LoadConfig() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error while unmarshaling config file: %w", err)
 	}
+	if err := cfg.LDAP.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 	return &cfg, nil
 }
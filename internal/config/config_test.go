@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestLDAPConfigRejectsPlaintextDirectory(t *testing.T) {
+	tests := []struct {
+		name      string
+		directory LDAPDirectoryConfig
+		wantErr   bool
+	}{
+		{"ldaps", LDAPDirectoryConfig{Enabled: true, URL: "ldaps://dc.corp:636"}, false},
+		{"ldap with StartTLS", LDAPDirectoryConfig{Enabled: true, URL: "ldap://dc.corp:389", StartTLS: true}, false},
+		{"ldap without StartTLS", LDAPDirectoryConfig{Enabled: true, URL: "ldap://dc.corp:389"}, true},
+		{"ldap in upper case", LDAPDirectoryConfig{Enabled: true, URL: "LDAP://dc.corp:389"}, true},
+		{"ldap explicitly allowed", LDAPDirectoryConfig{Enabled: true, URL: "ldap://dc.corp:389", AllowInsecure: true}, false},
+		{"disabled", LDAPDirectoryConfig{URL: "ldap://dc.corp:389"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LDAPConfig{Directories: []LDAPDirectoryConfig{tt.directory}}.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// AuthorizationCode - одноразовый код авторизации OAuth2 (authorization code flow с PKCE).
+// В базе хранится только хеш кода
+type AuthorizationCode struct {
+	CodeHash            string    `db:"code_hash"`
+	ClientId            string    `db:"client_id"`
+	UserId              int64     `db:"user_id"`
+	RedirectURI         string    `db:"redirect_uri"`
+	Scope               string    `db:"scope"`
+	Nonce               string    `db:"nonce"`
+	CodeChallenge       string    `db:"code_challenge"`
+	CodeChallengeMethod string    `db:"code_challenge_method"`
+	CreatedAt           time.Time `db:"created_at"`
+	ExpiresAt           time.Time `db:"expires_at"`
+}
+
+func (c *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
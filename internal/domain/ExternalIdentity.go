@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// ExternalIdentity - учетная запись пользователя у внешнего провайдера. Subject - идентификатор
+// пользователя у провайдера, Email - адрес, по которому учетная запись была привязана
+type ExternalIdentity struct {
+	Provider    string     `db:"provider"`
+	Subject     string     `db:"subject"`
+	UserId      int64      `db:"user_id"`
+	Email       string     `db:"email"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at"`
+}
+
+// ExternalAuthState - начатый вход через внешнего провайдера, одноразовый.
+// В базе хранится хеш state, nonce и code_verifier нужны для обмена кода
+type ExternalAuthState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (s *ExternalAuthState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+const (
+	LoginChallengeMFA            = "mfa"
+	LoginChallengePasswordChange = "password_change"
+)
+
+// LoginChallenge - серверная запись незавершенного входа. Id совпадает с jti выданного токена:
+// после успешного шага запись удаляется, поэтому токен нельзя предъявить повторно,
+// а Attempts ограничивает число попыток по одному токену
+type LoginChallenge struct {
+	Id        string    `db:"id"`
+	UserId    int64     `db:"user_id"`
+	Purpose   string    `db:"purpose"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// MagicLinkToken - одноразовая ссылка для входа без пароля.
+// В базе хранится только хеш токена. Использованные ссылки остаются до истечения окна
+// ограничения частоты - по ним считается, сколько писем пользователь уже запросил
+type MagicLinkToken struct {
+	TokenHash string     `db:"token_hash"`
+	UserId    int64      `db:"user_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+func (t *MagicLinkToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
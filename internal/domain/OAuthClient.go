@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OAuthClient - приложение (relying party), которому разрешено делегировать вход в SSO.
+// Публичные клиенты (SPA, мобильные приложения) не имеют секрета и обязаны использовать PKCE
+type OAuthClient struct {
+	Id           string         `db:"id"`
+	Name         string         `db:"name"`
+	SecretHash   []byte         `db:"secret_hash"`
+	RedirectURIs pq.StringArray `db:"redirect_uris"`
+	Scopes       pq.StringArray `db:"scopes"`
+	GrantTypes   pq.StringArray `db:"grant_types"`
+	IsPublic     bool           `db:"is_public"`
+	CreationTime time.Time      `db:"creation_datetime"`
+	UpdateTime   *time.Time     `db:"update_datetime"`
+}
+
+// HasRedirectURI проверяет redirect_uri на точное совпадение с зарегистрированными
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+func (c *OAuthClient) AllowsGrantType(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsScopes проверяет, что все запрошенные scope разрешены клиенту
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSecret сохраняет bcrypt хеш секрета клиента
+func (c *OAuthClient) SetSecret(secret string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	c.SecretHash = hash
+	return nil
+}
+
+func (c *OAuthClient) CheckSecret(secret string) bool {
+	if len(c.SecretHash) == 0 {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(c.SecretHash, []byte(secret)) == nil
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Passkey - учетные данные WebAuthn пользователя. Id - credential id в base64url,
+// PublicKey - COSE ключ, SignCount - счетчик подписей для обнаружения клонированных ключей
+type Passkey struct {
+	Id         string         `db:"id"`
+	UserId     int64          `db:"user_id"`
+	PublicKey  []byte         `db:"public_key"`
+	SignCount  int64          `db:"sign_count"`
+	Transports pq.StringArray `db:"transports"`
+	Name       string         `db:"name"`
+	CreatedAt  time.Time      `db:"created_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+}
+
+// WebAuthnChallenge - выданный браузеру challenge, одноразовый. Для регистрации
+// привязан к пользователю, для входа UserId пуст
+type WebAuthnChallenge struct {
+	ChallengeHash string    `db:"challenge_hash"`
+	UserId        *int64    `db:"user_id"`
+	Ceremony      string    `db:"ceremony"`
+	CreatedAt     time.Time `db:"created_at"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+func (c *WebAuthnChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
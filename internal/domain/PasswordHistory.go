@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"time"
+)
+
+// PasswordHistoryEntry - один из прежних паролей пользователя, хранится только хеш
+type PasswordHistoryEntry struct {
+	Id           int64     `db:"id"`
+	UserId       int64     `db:"user_id"`
+	PasswordHash []byte    `db:"password_hash"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (e *PasswordHistoryEntry) Matches(hasher PasswordHasher, password string) bool {
+	return hasher.Verify(e.PasswordHash, password)
+}
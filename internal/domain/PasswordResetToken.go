@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// PasswordResetToken - одноразовый токен сброса пароля из письма.
+// В базе хранится только хеш токена, у пользователя действует не более одного токена
+type PasswordResetToken struct {
+	TokenHash string    `db:"token_hash"`
+	UserId    int64     `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
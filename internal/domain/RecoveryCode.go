@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RecoveryCodesCount - сколько кодов восстановления выдается за раз
+const RecoveryCodesCount = 10
+
+// алфавит без похожих символов (0/o, 1/l)
+const recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+const (
+	recoveryCodeLength = 10
+	// recoveryCodeLookupLength - длина префикса в hex: 16 бит из 50 бит кода
+	recoveryCodeLookupLength = 4
+)
+
+// RecoveryCode - одноразовый код входа на случай потери приложения-аутентификатора.
+// Хранится bcrypt хеш, как и пароль. Lookup - короткий префикс sha256 кода: по нему выбирается
+// кандидат, чтобы bcrypt считался для одного кода, а не для всех. Пустой у кодов, выданных до него
+type RecoveryCode struct {
+	Id        int64      `db:"id"`
+	UserId    int64      `db:"user_id"`
+	CodeHash  []byte     `db:"code_hash"`
+	Lookup    string     `db:"lookup"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// NewRecoveryCodes генерирует набор кодов: открытые значения показываются пользователю один раз
+func NewRecoveryCodes(userId int64) ([]string, []RecoveryCode, error) {
+	codes := make([]string, 0, RecoveryCodesCount)
+	records := make([]RecoveryCode, 0, RecoveryCodesCount)
+	now := time.Now()
+
+	for range RecoveryCodesCount {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(NormalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, RecoveryCode{
+			UserId:    userId,
+			CodeHash:  hash,
+			Lookup:    RecoveryCodeLookup(code),
+			CreatedAt: now,
+		})
+	}
+	return codes, records, nil
+}
+
+// IsCandidate - код может совпасть с введенным: префиксы равны или префикса у записи нет
+func (c *RecoveryCode) IsCandidate(lookup string) bool {
+	return c.Lookup == "" || c.Lookup == lookup
+}
+
+func (c *RecoveryCode) Matches(code string) bool {
+	return bcrypt.CompareHashAndPassword(c.CodeHash, []byte(NormalizeRecoveryCode(code))) == nil
+}
+
+// RecoveryCodeLookup возвращает префикс sha256 нормализованного кода
+func RecoveryCodeLookup(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])[:recoveryCodeLookupLength]
+}
+
+// IsRecoveryCodeFormat - код после нормализации может быть кодом восстановления
+func IsRecoveryCodeFormat(code string) bool {
+	code = NormalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeRecoveryCode убирает разделители и регистр, чтобы код можно было ввести как угодно
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// randomRecoveryCode - 10 символов в формате xxxxx-xxxxx
+func randomRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+	}
+	return string(buf[:5]) + "-" + string(buf[5:]), nil
+}
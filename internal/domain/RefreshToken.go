@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken - серверная запись о выданном refresh токене.
+// Все токены, полученные ротацией от одного логина, принадлежат одному семейству (Family).
+// AccessTokenId - jti access токена, выпущенного в паре с этим refresh токеном.
+// ClientId - OAuth клиент, которому выдан токен, пусто для собственного фронтенда.
+type RefreshToken struct {
+	Id            string    `db:"id"`
+	UserId        int64     `db:"user_id"`
+	Family        string    `db:"family"`
+	IssuedAt      time.Time `db:"issued_at"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	Revoked       bool      `db:"revoked"`
+	AccessTokenId string    `db:"access_token_id"`
+	ClientId      string    `db:"client_id"`
+}
+
+// NewRefreshToken создает запись о токене. Если family пуст - начинается новое семейство.
+func NewRefreshToken(userId int64, family, clientId string, ttl time.Duration) *RefreshToken {
+	if family == "" {
+		family = uuid.NewString()
+	}
+	now := time.Now()
+	return &RefreshToken{
+		Id:            uuid.NewString(),
+		UserId:        userId,
+		Family:        family,
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(ttl),
+		AccessTokenId: uuid.NewString(),
+		ClientId:      clientId,
+	}
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
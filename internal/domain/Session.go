@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Session - вход пользователя на одном устройстве. Id совпадает с семейством refresh токенов,
+// LastSeenAt и ExpiresAt обновляются при каждой ротации. ClientId - OAuth клиент, пусто для собственного фронтенда
+type Session struct {
+	Id         string    `db:"id"`
+	UserId     int64     `db:"user_id"`
+	ClientId   string    `db:"client_id"`
+	UserAgent  string    `db:"user_agent"`
+	IP         string    `db:"ip"`
+	Device     string    `db:"device"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+	Revoked    bool      `db:"revoked"`
+}
+
+// NewSession создает запись о сессии, к которой относится refresh токен token
+func NewSession(token *RefreshToken, userAgent, ip, device string) *Session {
+	return &Session{
+		Id:         token.Family,
+		UserId:     token.UserId,
+		ClientId:   token.ClientId,
+		UserAgent:  userAgent,
+		IP:         ip,
+		Device:     device,
+		CreatedAt:  token.IssuedAt,
+		LastSeenAt: token.IssuedAt,
+		ExpiresAt:  token.ExpiresAt,
+	}
+}
+
+func (s *Session) IsActive() bool {
+	return !s.Revoked && time.Now().Before(s.ExpiresAt)
+}
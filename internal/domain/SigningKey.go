@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// SigningKey - ключ подписи токенов. Пока RetiredAt пуст, ключ может быть текущим;
+// после вывода из оборота он хранится только для проверки ранее выпущенных токенов.
+// Sealed - PrivateKey зашифрован, ключи до появления шифрования хранились открыто
+type SigningKey struct {
+	Id         string     `db:"id"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey []byte     `db:"private_key"`
+	Sealed     bool       `db:"sealed"`
+	CreatedAt  time.Time  `db:"created_at"`
+	RetiredAt  *time.Time `db:"retired_at"`
+}
+
+// IsExpired сообщает, что все токены, подписанные ключом, уже истекли и ключ можно удалить
+func (k *SigningKey) IsExpired(maxTokenLifetime time.Duration) bool {
+	return k.RetiredAt != nil && time.Now().After(k.RetiredAt.Add(maxTokenLifetime))
+}
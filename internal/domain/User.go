@@ -3,14 +3,20 @@ package domain
 import (
 	"errors"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidOldPassword error = errors.New("cтарый пароль не совпадает с текущим")
 )
 
+// PasswordHasher хеширует пароли и проверяет хеши, в том числе посчитанные прежними алгоритмами
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(hash []byte, password string) bool
+	// NeedsRehash - хеш посчитан с параметрами слабее текущих и его стоит пересчитать
+	NeedsRehash(hash []byte) bool
+}
+
 type User struct {
 	Id           int64      `db:"id"`
 	RoleId       int64      `db:"role_id"`
@@ -19,13 +25,26 @@ type User struct {
 	CreationTime time.Time  `db:"creation_datetime"`
 	UpdateTime   *time.Time `db:"update_datetime"`
 	IsArchived   bool       `db:"is_archived"`
+	// EmailVerified - пользователь перешел по ссылке из письма подтверждения. Email - это login
+	EmailVerified bool `db:"email_verified"`
+	// TotpSecret - зашифрованный секрет TOTP, TotpEnabled выставляется после подтверждения первым кодом
+	TotpSecret  []byte `db:"totp_secret"`
+	TotpEnabled bool   `db:"totp_enabled"`
+	// TotpLastStep - последний принятый шаг TOTP, коды этого и более ранних шагов повторно не принимаются
+	TotpLastStep int64 `db:"totp_last_step"`
+	// PasswordChangedAt - когда пароль менялся в последний раз, от нее считается срок смены пароля
+	PasswordChangedAt time.Time `db:"password_changed_at"`
 }
 
-func NewUser(login, password string, roleId *int64, isArchived *bool) *User {
+func NewUser(login, password string, hasher PasswordHasher, roleId *int64, isArchived *bool) (*User, error) {
+	passwordHash, err := hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
 	user := &User{
-		Login: login,
+		Login:        login,
+		PasswordHash: passwordHash,
 	}
-	user.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if roleId != nil {
 		user.RoleId = *roleId
 	} else {
@@ -39,12 +58,16 @@ func NewUser(login, password string, roleId *int64, isArchived *bool) *User {
 		user.IsArchived = false
 	}
 
-	return user
+	return user, nil
+}
+
+// IsStaff - менеджер (2) или администратор (3)
+func (u *User) IsStaff() bool {
+	return u.RoleId == 2 || u.RoleId == 3
 }
 
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password))
-	return err == nil
+func (u *User) CheckPassword(hasher PasswordHasher, password string) bool {
+	return hasher.Verify(u.PasswordHash, password)
 }
 
 func (u *User) UpdateLogin(login string) {
@@ -52,10 +75,14 @@ func (u *User) UpdateLogin(login string) {
 	u.updateDateTime()
 }
 
-func (u *User) UpdatePassword(oldPass, newPass string) error {
-	oldCorrect := u.CheckPassword(oldPass)
+func (u *User) UpdatePassword(hasher PasswordHasher, oldPass, newPass string) error {
+	oldCorrect := u.CheckPassword(hasher, oldPass)
 	if oldCorrect {
-		u.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(newPass), bcrypt.DefaultCost)
+		passwordHash, err := hasher.Hash(newPass)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = passwordHash
 		u.updateDateTime()
 		return nil
 	} else {
@@ -1,5 +1,7 @@
 package auth
 
+import "github.com/phenirain/sso/internal/lib/passwordpolicy"
+
 // AuthRequest содержит учетные данные для авторизации
 // swagger:model AuthRequest
 type AuthRequest struct {
@@ -13,9 +15,106 @@ type AuthRequest struct {
 // swagger:model AuthResponse
 type AuthResponse struct {
 	// Refresh Token для обновления пары токенов
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	// Access Token для доступа к защищенным ресурсам
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
 	// Role ID пользователя (1=client, 2=manager, 3=admin)
-	RoleId int64 `json:"role_id"`
+	RoleId int64 `json:"role_id,omitempty"`
+	// Вход не завершен: нужен код второго фактора. Токенов в ответе нет,
+	// MFAToken обменивается на них через /auth/2fa/verify
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// Вход не завершен: срок действия пароля истек. Токенов в ответе нет,
+	// PasswordChangeToken обменивается на них через /auth/changeExpiredPassword
+	PasswordExpired     bool   `json:"password_expired,omitempty"`
+	PasswordChangeToken string `json:"password_change_token,omitempty"`
+	// Регистрация прошла, но токены выдаются только после перехода по ссылке из письма
+	EmailVerificationRequired bool `json:"email_verification_required,omitempty"`
+	// Сколько кодов восстановления осталось - заполняется при входе по коду восстановления
+	RecoveryCodesLeft *int `json:"recovery_codes_left,omitempty"`
+}
+
+// RecoveryCodesResponse - новые коды восстановления, показываются один раз
+// swagger:model RecoveryCodesResponse
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes" example:"k3m9p-x7q2r"`
+}
+
+// RecoveryCodesStatusResponse - состояние двухфакторной аутентификации пользователя
+// swagger:model RecoveryCodesStatusResponse
+type RecoveryCodesStatusResponse struct {
+	Enabled bool `json:"enabled"`
+	// Число неиспользованных кодов восстановления
+	Remaining int `json:"remaining"`
+}
+
+// TOTPSetupResponse - данные для добавления аккаунта в приложение-аутентификатор
+// swagger:model TOTPSetupResponse
+type TOTPSetupResponse struct {
+	// Секрет в base32 для ручного ввода
+	Secret string `json:"secret"`
+	// otpauth:// ссылка для QR кода
+	URI string `json:"uri"`
+}
+
+// TOTPCodeRequest - код из приложения-аутентификатора
+// swagger:model TOTPCodeRequest
+type TOTPCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// MFAVerifyRequest - второй шаг входа
+// swagger:model MFAVerifyRequest
+type MFAVerifyRequest struct {
+	// Токен из ответа /auth/logIn
+	MFAToken string `json:"mfa_token"`
+	// Код TOTP или код восстановления
+	Code string `json:"code" example:"123456"`
+}
+
+// MagicLinkConsumeRequest - вход по ссылке из письма
+// swagger:model MagicLinkConsumeRequest
+type MagicLinkConsumeRequest struct {
+	// Токен из ссылки в письме
+	Token string `json:"token"`
+}
+
+// PasswordViolationsResponse - пароль не соответствует требованиям, все нарушения списком
+// swagger:model PasswordViolationsResponse
+type PasswordViolationsResponse struct {
+	Violations []passwordpolicy.Violation `json:"violations"`
+}
+
+// ChangeExpiredPasswordRequest - смена устаревшего пароля при входе
+// swagger:model ChangeExpiredPasswordRequest
+type ChangeExpiredPasswordRequest struct {
+	// Токен из ответа /auth/logIn
+	PasswordChangeToken string `json:"password_change_token"`
+	NewPassword         string `json:"new_password" example:"newPassword123"`
+}
+
+// ChangePasswordRequest - смена пароля авторизованным пользователем
+// swagger:model ChangePasswordRequest
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" example:"password123"`
+	NewPassword string `json:"new_password" example:"newPassword123"`
+	// Завершить все сессии, кроме текущей
+	RevokeOtherSessions bool `json:"revoke_other_sessions"`
+}
+
+// ExternalLoginStartResponse - адрес страницы входа провайдера. Фронтенд переходит по адресу,
+// а state привязывается к браузеру HttpOnly cookie с его хешем (StateBinding): callback
+// принимает state только из того браузера, который начал вход
+// swagger:model ExternalLoginStartResponse
+type ExternalLoginStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	StateBinding     string `json:"-"`
+}
+
+// ExternalLoginCallbackRequest - параметры, с которыми провайдер вернул пользователя на фронтенд
+// swagger:model ExternalLoginCallbackRequest
+type ExternalLoginCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
 }
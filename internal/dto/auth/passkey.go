@@ -0,0 +1,103 @@
+package auth
+
+import "time"
+
+// Структуры повторяют JSON формат WebAuthn (PublicKeyCredential.toJSON и
+// parseCreationOptionsFromJSON): бинарные поля передаются в base64url
+
+// PasskeyRelyingParty - сервис, к которому привязывается passkey
+type PasskeyRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PasskeyUser - учетная запись в аутентификаторе. ID - user handle в base64url
+type PasskeyUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PasskeyCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions - параметры navigator.credentials.create()
+// swagger:model PasskeyCreationOptions
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RP                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                         `json:"timeout"`
+	Attestation            string                        `json:"attestation"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// PasskeyRequestOptions - параметры navigator.credentials.get()
+// swagger:model PasskeyRequestOptions
+type PasskeyRequestOptions struct {
+	Challenge        string                        `json:"challenge"`
+	RPID             string                        `json:"rpId"`
+	Timeout          int64                         `json:"timeout"`
+	UserVerification string                        `json:"userVerification"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+}
+
+// PasskeyLoginOptionsRequest - начало входа. Без логина браузер предложит любой passkey этого сайта
+// swagger:model PasskeyLoginOptionsRequest
+type PasskeyLoginOptionsRequest struct {
+	Login string `json:"login" example:"user@example.com"`
+}
+
+// PasskeyRegistrationRequest - результат navigator.credentials.create()
+// swagger:model PasskeyRegistrationRequest
+type PasskeyRegistrationRequest struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+	// Название, под которым passkey будет показан в списке
+	Name string `json:"name" example:"iPhone"`
+}
+
+// PasskeyLoginRequest - результат navigator.credentials.get()
+// swagger:model PasskeyLoginRequest
+type PasskeyLoginRequest struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// PasskeyResponse - зарегистрированный passkey пользователя
+// swagger:model PasskeyResponse
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,20 @@
+package auth
+
+import "time"
+
+// SessionResponse - активная сессия пользователя: вход на одном устройстве
+// swagger:model SessionResponse
+type SessionResponse struct {
+	ID string `json:"id"`
+	// Device - браузер и система, определенные по User-Agent
+	Device    string `json:"device"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	// ClientID - OAuth клиент, через который выполнен вход, пусто для собственного фронтенда
+	ClientID   string    `json:"client_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current - сессия, из которой выполнен запрос
+	Current bool `json:"current"`
+}
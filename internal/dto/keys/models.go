@@ -0,0 +1,16 @@
+package keys
+
+import "time"
+
+// SigningKeyResponse описывает ключ подписи без приватной части
+// swagger:model SigningKeyResponse
+type SigningKeyResponse struct {
+	// Идентификатор ключа (kid)
+	Id string `json:"id"`
+	// Алгоритм подписи
+	Algorithm string `json:"algorithm" example:"RS256"`
+	// Время создания
+	CreatedAt time.Time `json:"created_at"`
+	// Время вывода из оборота, пусто для текущего ключа
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
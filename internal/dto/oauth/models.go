@@ -0,0 +1,142 @@
+package oauth
+
+// AuthorizeRequest - параметры запроса авторизации OAuth2/OIDC
+// swagger:model AuthorizeRequest
+type AuthorizeRequest struct {
+	// Должен быть code
+	ResponseType string `json:"response_type" query:"response_type" example:"code"`
+	// Идентификатор клиента
+	ClientId string `json:"client_id" query:"client_id"`
+	// Адрес возврата, должен быть зарегистрирован для клиента
+	RedirectURI string `json:"redirect_uri" query:"redirect_uri"`
+	// Запрашиваемые scope через пробел
+	Scope string `json:"scope" query:"scope" example:"openid email"`
+	// Непрозрачное значение, возвращается клиенту без изменений
+	State string `json:"state" query:"state"`
+	// Значение для защиты ID токена от повторного использования
+	Nonce string `json:"nonce" query:"nonce"`
+	// PKCE: BASE64URL(SHA256(code_verifier))
+	CodeChallenge string `json:"code_challenge" query:"code_challenge"`
+	// PKCE: поддерживается только S256
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" example:"S256"`
+}
+
+// AuthorizeResponse содержит адрес, на который фронтенд SSO перенаправляет браузер
+// swagger:model AuthorizeResponse
+type AuthorizeResponse struct {
+	// redirect_uri клиента с кодом авторизации и state
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// TokenRequest - параметры запроса к /oauth2/token (application/x-www-form-urlencoded)
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	// Запрашиваемые scope через пробел (client_credentials)
+	Scope string `form:"scope"`
+}
+
+// TokenResponse - успешный ответ token endpoint (RFC 6749, раздел 5.1)
+// swagger:model TokenResponse
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// IntrospectRequest - запрос к /oauth2/introspect (RFC 7662)
+type IntrospectRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// IntrospectResponse - состояние токена. Для недействительного токена заполнено только active
+// swagger:model IntrospectResponse
+type IntrospectResponse struct {
+	Active bool `json:"active"`
+	// id пользователя или client_id сервиса
+	Subject string `json:"sub,omitempty"`
+	// Роль пользователя, отсутствует у сервисных токенов
+	Role     int64  `json:"role,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	ClientId string `json:"client_id,omitempty"`
+	// Тип токена: access, refresh или service
+	TokenType string   `json:"token_type,omitempty" example:"access"`
+	TokenId   string   `json:"jti,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// RevokeRequest - запрос к /oauth2/revoke (RFC 7009)
+type RevokeRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// ErrorResponse - ошибка OAuth2 (RFC 6749, раздел 5.2)
+// swagger:model OAuthErrorResponse
+type ErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ClientRequest - создание или обновление OAuth клиента
+// swagger:model OAuthClientRequest
+type ClientRequest struct {
+	// Идентификатор клиента (client_id)
+	Id string `json:"id" example:"admin-panel"`
+	// Название приложения
+	Name string `json:"name" example:"Admin panel"`
+	// Разрешенные адреса возврата
+	RedirectURIs []string `json:"redirect_uris"`
+	// Разрешенные scope
+	Scopes []string `json:"scopes" example:"openid,email"`
+	// Разрешенные типы грантов
+	GrantTypes []string `json:"grant_types" example:"authorization_code,refresh_token,client_credentials"`
+	// Публичный клиент (без секрета, только с PKCE)
+	IsPublic bool `json:"is_public"`
+}
+
+// ClientResponse - OAuth клиент. Секрет возвращается только при создании и перевыпуске
+// swagger:model OAuthClientResponse
+type ClientResponse struct {
+	Id           string   `json:"id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types"`
+	IsPublic     bool     `json:"is_public"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+}
+
+// DiscoveryResponse - OpenID Provider Metadata
+// swagger:model DiscoveryResponse
+type DiscoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
@@ -26,6 +26,16 @@ func NewBadResponse[T any](message, details string) ApiResponse[T] {
 	}
 }
 
+// NewBadResponseWithData - ошибка с подробностями в структурированном виде, например списком нарушений
+func NewBadResponseWithData[T any](message, details string, data *T) ApiResponse[T] {
+	return ApiResponse[T]{
+		Success: false,
+		Message: message,
+		Data:    data,
+		Details: details,
+	}
+}
+
 func NewSuccessResponse[T any](data *T) ApiResponse[T] {
 	return ApiResponse[T]{
 		Success: true,
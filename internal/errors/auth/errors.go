@@ -3,8 +3,34 @@ package auth
 import "errors"
 
 var (
-	ErrInvalidUserCredentials = errors.New("неверен логин или пароль")
-	ErrUserAlreadyExists      = errors.New("пользователь уже существует")
-	ErrUserNotFound           = errors.New("пользователь не существует")
-	ErrUserArchived           = errors.New("ваш аккаунт удален, напишите письмо на почту \"phenirain@gmail.com\"")
+	ErrInvalidUserCredentials     = errors.New("неверен логин или пароль")
+	ErrUserAlreadyExists          = errors.New("пользователь уже существует")
+	ErrUserNotFound               = errors.New("пользователь не существует")
+	ErrInvalidResetToken          = errors.New("ссылка для сброса пароля недействительна или устарела")
+	ErrInvalidVerifyToken         = errors.New("ссылка для подтверждения email недействительна или устарела")
+	ErrEmailNotVerified           = errors.New("email не подтвержден, перейдите по ссылке из письма")
+	ErrEmailAlreadyVerified       = errors.New("email уже подтвержден")
+	ErrInvalidMFAToken            = errors.New("время на ввод кода истекло, войдите заново")
+	ErrInvalidTOTPCode            = errors.New("неверный код подтверждения")
+	ErrTOTPAlreadyEnabled         = errors.New("двухфакторная аутентификация уже включена")
+	ErrTOTPNotSetUp               = errors.New("двухфакторная аутентификация не настроена")
+	ErrInvalidPasskey             = errors.New("passkey не прошел проверку, попробуйте еще раз")
+	ErrPasskeyNotFound            = errors.New("passkey не найден")
+	ErrPasskeyAlreadyRegistered   = errors.New("этот passkey уже зарегистрирован")
+	ErrPasskeyUserNotVerified     = errors.New("устройство не подтвердило личность, включите на нем PIN-код или биометрию")
+	ErrPasskeyCloned              = errors.New("passkey отклонен: похоже, ключ скопирован на другое устройство")
+	ErrSessionNotFound            = errors.New("сессия не найдена или уже завершена")
+	ErrInvalidMagicLink           = errors.New("ссылка для входа недействительна или устарела")
+	ErrTooManyMagicLinks          = errors.New("слишком много запросов ссылки для входа, попробуйте позже")
+	ErrAccountLocked              = errors.New("слишком много неудачных попыток входа, вход временно заблокирован")
+	ErrPasswordReused             = errors.New("пароль совпадает с одним из недавно использованных")
+	ErrInvalidPasswordChangeToken = errors.New("время на смену пароля истекло, войдите заново")
+	ErrUnknownExternalProvider    = errors.New("вход через этот сервис не поддерживается")
+	ErrInvalidExternalState       = errors.New("время на вход через внешний сервис истекло, попробуйте еще раз")
+	ErrExternalEmailNotVerified   = errors.New("внешний сервис не подтвердил email, войдите другим способом")
+	ErrExternalLoginForbidden     = errors.New("вход через внешние сервисы недоступен для сотрудников")
+	ErrExternalAccountNotVerified = errors.New("аккаунт с этим email не подтвержден, войдите по паролю и подтвердите email")
+	ErrDirectoryAccessDenied      = errors.New("у вашей учетной записи каталога нет доступа, обратитесь к администратору")
+	ErrDirectoryAccount           = errors.New("пароль этой учетной записи управляется корпоративным каталогом, войдите с паролем каталога")
+	ErrUserArchived               = errors.New("ваш аккаунт удален, напишите письмо на почту \"phenirain@gmail.com\"")
 )
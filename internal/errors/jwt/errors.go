@@ -2,7 +2,9 @@ package jwt
 
 import "errors"
 
-
 var (
-	ErrInvalidToken = errors.New("invalid token")
-)
\ No newline at end of file
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrWrongTokenType     = errors.New("wrong token type")
+	ErrClientToken        = errors.New("token was issued to an oauth client")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, all sessions of this login were revoked")
+)
@@ -0,0 +1,25 @@
+package oauth
+
+// Error - ошибка протокола OAuth2 (RFC 6749, раздел 5.2) с кодом, который уходит клиенту в поле error
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	ErrInvalidRequest          = &Error{Code: "invalid_request", Description: "некорректный запрос"}
+	ErrInvalidClient           = &Error{Code: "invalid_client", Description: "неизвестный клиент или неверный секрет"}
+	ErrUnauthorizedClient      = &Error{Code: "unauthorized_client", Description: "клиенту не разрешен этот тип гранта"}
+	ErrInvalidScope            = &Error{Code: "invalid_scope", Description: "запрошенный scope не разрешен клиенту"}
+	ErrInvalidRedirectURI      = &Error{Code: "invalid_request", Description: "redirect_uri не зарегистрирован для клиента"}
+	ErrInvalidGrant            = &Error{Code: "invalid_grant", Description: "код авторизации недействителен или истек"}
+	ErrInvalidCodeVerifier     = &Error{Code: "invalid_grant", Description: "code_verifier не соответствует code_challenge"}
+	ErrUnsupportedGrantType    = &Error{Code: "unsupported_grant_type", Description: "тип гранта не поддерживается"}
+	ErrUnsupportedResponseType = &Error{Code: "unsupported_response_type", Description: "поддерживается только response_type=code"}
+	ErrInvalidClientMetadata   = &Error{Code: "invalid_client_metadata", Description: "некорректные параметры клиента"}
+	ErrPKCERequired            = &Error{Code: "invalid_request", Description: "требуется PKCE с code_challenge_method=S256"}
+)
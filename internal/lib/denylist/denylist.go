@@ -0,0 +1,68 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Denylist - in-memory список отозванных идентификаторов токенов (jti).
+// Запись живет ровно столько, сколько оставалось жить самому токену,
+// после чего токен и так не пройдет проверку exp.
+type Denylist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func New() *Denylist {
+	return &Denylist{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Revoke добавляет идентификатор в список на время ttl
+func (d *Denylist) Revoke(ctx context.Context, tokenId string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[tokenId] = time.Now().Add(ttl)
+	return nil
+}
+
+func (d *Denylist) IsRevoked(ctx context.Context, tokenId string) bool {
+	d.mu.RLock()
+	expiresAt, ok := d.entries[tokenId]
+	d.mu.RUnlock()
+
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Start периодически удаляет истекшие записи, пока не завершится ctx
+func (d *Denylist) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.cleanup()
+		}
+	}
+}
+
+func (d *Denylist) cleanup() {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for tokenId, expiresAt := range d.entries {
+		if now.After(expiresAt) {
+			delete(d.entries, tokenId)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package denylist
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+
+	if err := d.Revoke(ctx, "revoked", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !d.IsRevoked(ctx, "revoked") {
+		t.Fatal("revoked token is not denied")
+	}
+	if d.IsRevoked(ctx, "other") {
+		t.Fatal("other token is denied")
+	}
+
+	// токен уже истек - запоминать его незачем
+	if err := d.Revoke(ctx, "expired", 0); err != nil {
+		t.Fatal(err)
+	}
+	if d.IsRevoked(ctx, "expired") || len(d.entries) != 1 {
+		t.Fatal("expired token was stored")
+	}
+}
+
+func TestEntryExpiresWithToken(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	if err := d.Revoke(ctx, "short", time.Millisecond*10); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Revoke(ctx, "long", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 20)
+	if d.IsRevoked(ctx, "short") {
+		t.Fatal("entry outlived the token")
+	}
+
+	d.cleanup()
+	if _, ok := d.entries["short"]; ok {
+		t.Fatal("expired entry was not removed")
+	}
+	if !d.IsRevoked(ctx, "long") {
+		t.Fatal("live entry was removed")
+	}
+}
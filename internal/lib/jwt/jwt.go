@@ -3,64 +3,433 @@ package jwt
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
-	"time"
 )
 
+// Значения claim typ, по которому различаются access, refresh и сервисные токены
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeService - токен сервиса, выданный по client_credentials: вместо роли несет scope
+	TokenTypeService = "service"
+	// TokenTypeEmailVerification - токен из письма подтверждения email, к API доступа не дает
+	TokenTypeEmailVerification = "email_verification"
+	// TokenTypeMFA - промежуточный токен входа: пароль проверен, ожидается второй фактор
+	TokenTypeMFA = "mfa"
+	// TokenTypePasswordChange - промежуточный токен входа: пароль устарел, токены выдаются только после его смены
+	TokenTypePasswordChange = "password_change"
+)
+
+// Options - параметры выпуска и проверки токенов
+type Options struct {
+	// Issuer - значение claim iss, проверяется при разборе
+	Issuer string
+	// Audience - значение claim aud, проверяется при разборе
+	Audience string
+	// AccessDuration и RefreshDuration - время жизни токенов
+	AccessDuration  time.Duration
+	RefreshDuration time.Duration
+	// ServiceDuration - время жизни сервисного токена (client_credentials)
+	ServiceDuration time.Duration
+	// VerificationDuration - время жизни ссылки подтверждения email
+	VerificationDuration time.Duration
+	// MFADuration - сколько действует токен ожидания второго фактора. Столько же дается на смену устаревшего пароля
+	MFADuration time.Duration
+	// Leeway - допустимое расхождение часов при проверке exp, nbf и iat
+	Leeway time.Duration
+}
+
+// TokenInfo - проверенные claims токена любого типа, используется для introspection
+type TokenInfo struct {
+	Type    string
+	TokenId string
+	// Subject - id пользователя или client_id сервиса
+	Subject string
+	// RoleId - роль пользователя, 0 для сервисных токенов
+	RoleId    int64
+	ClientId  string
+	Scope     string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
 type JwtLib struct {
-	duration time.Duration
-	secret []byte
+	opts    Options
+	keyring *Keyring
 }
 
-func NewJwtLib(duration time.Duration, secret []byte) *JwtLib {
+func NewJwtLib(opts Options, keyring *Keyring) *JwtLib {
 	return &JwtLib{
-		duration: duration,
-		secret: secret,
+		opts:    opts,
+		keyring: keyring,
 	}
 }
 
-func (j *JwtLib) NewToken(userId, role int64) (accessToken string, refreshToken string, error error) {
+// JWKS возвращает публичные ключи проверки подписи всех ключей связки. HMAC ключи не публикуются
+func (j *JwtLib) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range j.keyring.Keys() {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// AccessDuration - время жизни access токена
+func (j *JwtLib) AccessDuration() time.Duration {
+	return j.opts.AccessDuration
+}
+
+// RefreshDuration - время жизни refresh токена
+func (j *JwtLib) RefreshDuration() time.Duration {
+	return j.opts.RefreshDuration
+}
+
+// ServiceDuration - время жизни сервисного токена
+func (j *JwtLib) ServiceDuration() time.Duration {
+	return j.opts.ServiceDuration
+}
+
+// NewToken выпускает пару токенов. accessTokenId и refreshTokenId попадают в claim jti
+// соответствующих токенов: первый используется для отзыва, второй - ключ серверной записи о refresh токене.
+// Если токены выпускаются для OAuth клиента, clientId добавляется в aud и azp:
+// такой access токен ParseAccessToken не принимает, API SSO открыт только собственному фронтенду
+func (j *JwtLib) NewToken(userId, role int64, refreshTokenId, accessTokenId, clientId string) (accessToken string, refreshToken string, error error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"iss":  j.opts.Issuer,
+		"aud":  j.audience(clientId),
 		"sub":  userId,
 		"role": role,
+		"typ":  TokenTypeAccess,
+		"jti":  accessTokenId,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+	}
+	claims["exp"] = now.Add(j.opts.AccessDuration).Unix()
+	if clientId != "" {
+		claims["azp"] = clientId
 	}
-	claims["exp"] = time.Now().Add(j.duration).Unix()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString(j.secret)
+	accessToken, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 
-	claims["exp"] = time.Now().Add(time.Hour*24*30).Unix()
-	token = jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refreshToken, err = token.SignedString(j.secret)
+	claims["exp"] = now.Add(j.opts.RefreshDuration).Unix()
+	claims["jti"] = refreshTokenId
+	claims["typ"] = TokenTypeRefresh
+	refreshToken, err = j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 	return
 }
 
-func (j *JwtLib) ParseToken(tokenString string) (userId int64, roleId int64, err error) {
+// NewIDToken выпускает OpenID Connect ID токен для клиента clientId.
+// sub в ID токене - строка, как требует спецификация
+func (j *JwtLib) NewIDToken(userId, role int64, email, clientId, nonce string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   j.opts.Issuer,
+		"aud":   clientId,
+		"azp":   clientId,
+		"sub":   strconv.FormatInt(userId, 10),
+		"email": email,
+		"role":  role,
+		"iat":   now.Unix(),
+		"exp":   now.Add(j.opts.AccessDuration).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return j.sign(claims)
+}
+
+// NewServiceToken выпускает токен сервиса по client_credentials. Субъект токена - сам клиент,
+// права определяются списком scope, а не ролью. Refresh токен не выпускается
+func (j *JwtLib) NewServiceToken(clientId string, scopes []string, tokenId string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   j.opts.Issuer,
+		"aud":   j.audience(clientId),
+		"sub":   clientId,
+		"azp":   clientId,
+		"scope": strings.Join(scopes, " "),
+		"typ":   TokenTypeService,
+		"jti":   tokenId,
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"exp":   now.Add(j.opts.ServiceDuration).Unix(),
+	}
+	return j.sign(claims)
+}
+
+// NewEmailVerificationToken выпускает токен подтверждения email. Адрес входит в подпись,
+// поэтому после смены логина старая ссылка перестает действовать
+func (j *JwtLib) NewEmailVerificationToken(userId int64, email string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   j.opts.Issuer,
+		"aud":   j.audience(""),
+		"sub":   userId,
+		"email": email,
+		"typ":   TokenTypeEmailVerification,
+		"iat":   now.Unix(),
+		"exp":   now.Add(j.opts.VerificationDuration).Unix(),
+	}
+	return j.sign(claims)
+}
+
+// ParseEmailVerificationToken разбирает токен подтверждения email
+func (j *JwtLib) ParseEmailVerificationToken(tokenString string) (userId int64, email string, err error) {
+	claims, err := j.parse(tokenString, TokenTypeEmailVerification)
+	if err != nil {
+		return -1, "", err
+	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return -1, "", errors.New("can't get sub from claims")
+	}
+	email, ok = claims["email"].(string)
+	if !ok || email == "" {
+		return -1, "", errors.New("can't get email from claims")
+	}
+	return int64(sub), email, nil
+}
+
+// NewMFAToken выпускает токен ожидания второго фактора после успешной проверки пароля.
+// tokenId (jti) - ключ серверной записи, которая ограничивает число попыток ввода кода
+func (j *JwtLib) NewMFAToken(userId, role int64, tokenId string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":  j.opts.Issuer,
+		"aud":  j.audience(""),
+		"sub":  userId,
+		"role": role,
+		"typ":  TokenTypeMFA,
+		"jti":  tokenId,
+		"iat":  now.Unix(),
+		"exp":  now.Add(j.opts.MFADuration).Unix(),
+	}
+	return j.sign(claims)
+}
+
+// ParseMFAToken разбирает токен ожидания второго фактора и возвращает его jti
+func (j *JwtLib) ParseMFAToken(tokenString string) (userId int64, roleId int64, tokenId string, err error) {
+	return j.parseChallengeToken(tokenString, TokenTypeMFA)
+}
+
+// NewPasswordChangeToken выпускает токен смены устаревшего пароля после успешного входа.
+// tokenId (jti) - ключ серверной записи, которая делает токен одноразовым
+func (j *JwtLib) NewPasswordChangeToken(userId, role int64, tokenId string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":  j.opts.Issuer,
+		"aud":  j.audience(""),
+		"sub":  userId,
+		"role": role,
+		"typ":  TokenTypePasswordChange,
+		"jti":  tokenId,
+		"iat":  now.Unix(),
+		"exp":  now.Add(j.opts.MFADuration).Unix(),
+	}
+	return j.sign(claims)
+}
+
+// ParsePasswordChangeToken разбирает токен смены устаревшего пароля и возвращает его jti
+func (j *JwtLib) ParsePasswordChangeToken(tokenString string) (userId int64, roleId int64, tokenId string, err error) {
+	return j.parseChallengeToken(tokenString, TokenTypePasswordChange)
+}
+
+// parseChallengeToken разбирает токен незавершенного входа. Без jti токен не принимается:
+// по нему сервис находит серверную запись, без которой токен нельзя было бы погасить
+func (j *JwtLib) parseChallengeToken(tokenString, tokenType string) (userId int64, roleId int64, tokenId string, err error) {
+	claims, err := j.parse(tokenString, tokenType)
+	if err != nil {
+		return -1, -1, "", err
+	}
+	userId, roleId, err = claimsIdentity(claims)
+	if err != nil {
+		return -1, -1, "", err
+	}
+
+	tokenId, ok := claims["jti"].(string)
+	if !ok || tokenId == "" {
+		return -1, -1, "", jwtErrors.ErrInvalidToken
+	}
+	return userId, roleId, tokenId, nil
+}
+
+// audience - собственная аудитория SSO и, при наличии, клиент, которому выдан токен
+func (j *JwtLib) audience(clientId string) jwt.ClaimStrings {
+	if clientId == "" {
+		return jwt.ClaimStrings{j.opts.Audience}
+	}
+	return jwt.ClaimStrings{j.opts.Audience, clientId}
+}
+
+// SigningAlgorithm - алгоритм, которым подписываются новые токены
+func (j *JwtLib) SigningAlgorithm() string {
+	return j.keyring.Current().Algorithm()
+}
+
+// sign всегда подписывает текущим (самым новым) ключом связки
+func (j *JwtLib) sign(claims jwt.MapClaims) (string, error) {
+	key := j.keyring.Current()
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.Id != "" {
+		token.Header["kid"] = key.Id
+	}
+	return token.SignedString(key.private)
+}
+
+// ParseAccessToken разбирает access токен и возвращает его идентификатор (jti).
+// Refresh токен отклоняется с ErrWrongTokenType. Токен, выданный OAuth клиенту (с azp), отклоняется
+// с ErrClientToken: он предназначен клиенту, и сторонний сервис не должен входить с ним в API SSO
+func (j *JwtLib) ParseAccessToken(tokenString string) (userId int64, roleId int64, tokenId string, err error) {
+	claims, err := j.parse(tokenString, TokenTypeAccess)
+	if err != nil {
+		return -1, -1, "", err
+	}
+	if clientId, _ := claims["azp"].(string); clientId != "" {
+		return -1, -1, "", jwtErrors.ErrClientToken
+	}
+
+	userId, roleId, err = claimsIdentity(claims)
+	if err != nil {
+		return -1, -1, "", err
+	}
+
+	tokenId, _ = claims["jti"].(string)
+	return userId, roleId, tokenId, nil
+}
+
+// ParseRefreshToken разбирает refresh токен и дополнительно возвращает его идентификатор (jti).
+// Access токен отклоняется с ErrWrongTokenType
+func (j *JwtLib) ParseRefreshToken(tokenString string) (userId int64, roleId int64, tokenId string, err error) {
+	claims, err := j.parse(tokenString, TokenTypeRefresh)
+	if err != nil {
+		return -1, -1, "", err
+	}
+
+	userId, roleId, err = claimsIdentity(claims)
+	if err != nil {
+		return -1, -1, "", err
+	}
+
+	tokenId, ok := claims["jti"].(string)
+	if !ok || tokenId == "" {
+		return -1, -1, "", jwtErrors.ErrInvalidToken
+	}
+	return userId, roleId, tokenId, nil
+}
+
+// ParseServiceToken разбирает токен сервиса и возвращает клиента, его scope и jti.
+// Пользовательские токены отклоняются с ErrWrongTokenType
+func (j *JwtLib) ParseServiceToken(tokenString string) (clientId string, scopes []string, tokenId string, err error) {
+	claims, err := j.parse(tokenString, TokenTypeService)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	clientId, ok := claims["sub"].(string)
+	if !ok || clientId == "" {
+		return "", nil, "", errors.New("can't get sub from claims")
+	}
+
+	scope, _ := claims["scope"].(string)
+	tokenId, _ = claims["jti"].(string)
+	return clientId, strings.Fields(scope), tokenId, nil
+}
+
+// Inspect разбирает токен любого типа, выпущенный этим сервисом, с полной проверкой подписи и сроков
+func (j *JwtLib) Inspect(tokenString string) (*TokenInfo, error) {
+	claims, err := j.parse(tokenString, "")
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TokenInfo{}
+	info.Type, _ = claims["typ"].(string)
+	info.TokenId, _ = claims["jti"].(string)
+	info.ClientId, _ = claims["azp"].(string)
+	info.Scope, _ = claims["scope"].(string)
+
+	switch sub := claims["sub"].(type) {
+	case string:
+		info.Subject = sub
+	case float64:
+		info.Subject = strconv.FormatInt(int64(sub), 10)
+	default:
+		return nil, errors.New("can't get sub from claims")
+	}
+	if role, ok := claims["role"].(float64); ok {
+		info.RoleId = int64(role)
+	}
+
+	if aud, err := claims.GetAudience(); err == nil {
+		info.Audience = aud
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		info.IssuedAt = iat.Time
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+	return info, nil
+}
+
+// parse проверяет подпись, iss, aud и сроки токена. Пустой tokenType допускает любой тип
+func (j *JwtLib) parse(tokenString, tokenType string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keyring.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+		if token.Method.Alg() != key.Algorithm() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return j.secret, nil
-	})
+		return key.public, nil
+	},
+		jwt.WithIssuer(j.opts.Issuer),
+		jwt.WithAudience(j.opts.Audience),
+		jwt.WithLeeway(j.opts.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
-		return -1, -1, fmt.Errorf("token parse error: %s", err.Error())
+		return nil, fmt.Errorf("token parse error: %s", err.Error())
 	}
 	if !token.Valid {
-		return -1, -1, jwtErrors.ErrInvalidToken
+		return nil, jwtErrors.ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return -1, -1, errors.New("can't get claims")
+		return nil, errors.New("can't get claims")
 	}
 
+	typ, _ := claims["typ"].(string)
+	switch {
+	case tokenType == "" && typ != TokenTypeAccess && typ != TokenTypeRefresh && typ != TokenTypeService:
+		return nil, jwtErrors.ErrWrongTokenType
+	case tokenType != "" && typ != tokenType:
+		return nil, jwtErrors.ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+func claimsIdentity(claims jwt.MapClaims) (userId int64, roleId int64, err error) {
 	uid, ok := claims["sub"]
 	if !ok {
 		return -1, -1, errors.New("can't get sub from claims")
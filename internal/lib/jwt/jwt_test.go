@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+)
+
+// validClaims - claims access токена, которые проходят все проверки newTestLib
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":  "sso-test",
+		"aud":  []string{"sso-test"},
+		"sub":  1,
+		"role": 2,
+		"typ":  TokenTypeAccess,
+		"jti":  "access-id",
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  now.Add(time.Minute).Unix(),
+	}
+}
+
+func TestParseRegisteredClaims(t *testing.T) {
+	// leeway newTestLib - 5 секунд
+	now := time.Now()
+	tests := []struct {
+		name    string
+		change  func(claims jwt.MapClaims)
+		wantErr bool
+	}{
+		{"valid", func(jwt.MapClaims) {}, false},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "other-sso" }, true},
+		{"missing issuer", func(claims jwt.MapClaims) { delete(claims, "iss") }, true},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = []string{"other-service"} }, true},
+		{"missing audience", func(claims jwt.MapClaims) { delete(claims, "aud") }, true},
+		// токен выдан клиенту: в aud кроме SSO есть client_id
+		{"audience with client", func(claims jwt.MapClaims) { claims["aud"] = []string{"sso-test", "web"} }, false},
+		{"missing exp", func(claims jwt.MapClaims) { delete(claims, "exp") }, true},
+		{"expired beyond leeway", func(claims jwt.MapClaims) { claims["exp"] = now.Add(-time.Minute).Unix() }, true},
+		{"expired within leeway", func(claims jwt.MapClaims) { claims["exp"] = now.Add(-time.Second * 2).Unix() }, false},
+		{"nbf in the future", func(claims jwt.MapClaims) { claims["nbf"] = now.Add(time.Minute).Unix() }, true},
+		{"nbf within leeway", func(claims jwt.MapClaims) { claims["nbf"] = now.Add(time.Second * 2).Unix() }, false},
+		{"iat in the future", func(claims jwt.MapClaims) { claims["iat"] = now.Add(time.Minute).Unix() }, true},
+		{"iat within leeway", func(claims jwt.MapClaims) { claims["iat"] = now.Add(time.Second * 2).Unix() }, false},
+	}
+	lib := newTestLib(NewKeyring(NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.change(claims)
+			token, err := lib.sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, _, err = lib.ParseAccessToken(token)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTokenSetsRegisteredClaims(t *testing.T) {
+	lib := newTestLib(NewKeyring(NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+	before := time.Now().Truncate(time.Second)
+	accessToken, refreshToken, err := lib.NewToken(1, 2, "refresh-id", "access-id", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token    string
+		tokenId  string
+		lifetime time.Duration
+	}{
+		{accessToken, "access-id", time.Minute * 15},
+		{refreshToken, "refresh-id", time.Hour},
+	}
+	for _, tt := range tests {
+		info, err := lib.Inspect(tt.token)
+		if err != nil {
+			t.Fatalf("inspect: %v", err)
+		}
+		if info.TokenId != tt.tokenId || info.ClientId != "web" {
+			t.Fatalf("jti %q, azp %q, want %q, web", info.TokenId, info.ClientId, tt.tokenId)
+		}
+		if len(info.Audience) != 2 || info.Audience[0] != "sso-test" || info.Audience[1] != "web" {
+			t.Fatalf("aud %v, want sso-test and web", info.Audience)
+		}
+		if info.IssuedAt.Before(before) || info.ExpiresAt.Sub(info.IssuedAt) != tt.lifetime {
+			t.Fatalf("iat %v, exp %v, want lifetime %v", info.IssuedAt, info.ExpiresAt, tt.lifetime)
+		}
+	}
+}
+
+func TestParseChecksTokenType(t *testing.T) {
+	lib := newTestLib(NewKeyring(NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+	lib.opts.ServiceDuration = time.Minute
+	lib.opts.MFADuration = time.Minute
+	lib.opts.VerificationDuration = time.Minute
+
+	accessToken, refreshToken, err := lib.NewToken(1, 2, "refresh-id", "access-id", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceToken, err := lib.NewServiceToken("service", []string{"manager"}, "service-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaToken, err := lib.NewMFAToken(1, 2, "mfa-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordChangeToken, err := lib.NewPasswordChangeToken(1, 2, "password-change-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verificationToken, err := lib.NewEmailVerificationToken(1, "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsers := map[string]func(token string) error{
+		TokenTypeAccess: func(token string) error {
+			_, _, _, err := lib.ParseAccessToken(token)
+			return err
+		},
+		TokenTypeRefresh: func(token string) error {
+			_, _, _, err := lib.ParseRefreshToken(token)
+			return err
+		},
+		TokenTypeService: func(token string) error {
+			_, _, _, err := lib.ParseServiceToken(token)
+			return err
+		},
+		TokenTypeMFA: func(token string) error {
+			_, _, _, err := lib.ParseMFAToken(token)
+			return err
+		},
+		TokenTypePasswordChange: func(token string) error {
+			_, _, _, err := lib.ParsePasswordChangeToken(token)
+			return err
+		},
+		TokenTypeEmailVerification: func(token string) error {
+			_, _, err := lib.ParseEmailVerificationToken(token)
+			return err
+		},
+	}
+	tokens := map[string]string{
+		TokenTypeAccess:            accessToken,
+		TokenTypeRefresh:           refreshToken,
+		TokenTypeService:           serviceToken,
+		TokenTypeMFA:               mfaToken,
+		TokenTypePasswordChange:    passwordChangeToken,
+		TokenTypeEmailVerification: verificationToken,
+	}
+	for tokenType, token := range tokens {
+		for parserType, parse := range parsers {
+			err := parse(token)
+			if tokenType == parserType && err != nil {
+				t.Errorf("%s parser rejected %s token: %v", parserType, tokenType, err)
+			}
+			if tokenType != parserType && !errors.Is(err, jwtErrors.ErrWrongTokenType) {
+				t.Errorf("%s parser: %s token err = %v, want ErrWrongTokenType", parserType, tokenType, err)
+			}
+		}
+
+		// introspection знает только токены доступа к API
+		_, err := lib.Inspect(token)
+		wantInspect := tokenType == TokenTypeAccess || tokenType == TokenTypeRefresh || tokenType == TokenTypeService
+		if gotInspect := err == nil; gotInspect != wantInspect {
+			t.Errorf("inspect %s token: err = %v", tokenType, err)
+		}
+	}
+}
+
+// токен, выданный OAuth клиенту, проверяется через introspection, но не принимается как сессия SSO
+func TestParseAccessTokenRejectsClientToken(t *testing.T) {
+	lib := newTestLib(NewKeyring(NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+	accessToken, _, err := lib.NewToken(1, 3, "refresh-id", "access-id", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := lib.ParseAccessToken(accessToken); !errors.Is(err, jwtErrors.ErrClientToken) {
+		t.Fatalf("err = %v, want ErrClientToken", err)
+	}
+	if info, err := lib.Inspect(accessToken); err != nil || info.ClientId != "web" {
+		t.Fatalf("inspect: %+v, %v", info, err)
+	}
+}
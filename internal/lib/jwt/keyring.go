@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"sort"
+	"sync"
+)
+
+// Keyring - набор ключей подписи: текущий (им подписываются новые токены)
+// и предыдущие, которые еще нужны для проверки ранее выпущенных токенов.
+// Содержимое можно заменить на лету при ротации.
+type Keyring struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*SigningKey
+}
+
+func NewKeyring(current *SigningKey, previous ...*SigningKey) *Keyring {
+	k := &Keyring{}
+	k.Replace(current, previous...)
+	return k
+}
+
+// Replace атомарно заменяет текущий и предыдущие ключи
+func (k *Keyring) Replace(current *SigningKey, previous ...*SigningKey) {
+	keys := make(map[string]*SigningKey, len(previous)+1)
+	for _, key := range previous {
+		keys[key.Id] = key
+	}
+	keys[current.Id] = current
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current = current
+	k.keys = keys
+}
+
+// Current возвращает ключ, которым подписываются новые токены
+func (k *Keyring) Current() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// Lookup ищет ключ проверки по kid. Токены без kid проверяются ключом с пустым идентификатором
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// Keys возвращает все ключи проверки, упорядоченные по kid
+func (k *Keyring) Keys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Id < keys[j].Id })
+	return keys
+}
@@ -0,0 +1,220 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey - ключ подписи токенов вместе с его идентификатором (kid)
+type SigningKey struct {
+	Id     string
+	method jwt.SigningMethod
+	// private используется для подписи, public - для проверки.
+	// Для HMAC оба - общий секрет
+	private any
+	public  any
+}
+
+// NewHMACKey создает симметричный ключ HS256 из общего секрета
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		Id:      id,
+		method:  jwt.SigningMethodHS256,
+		private: secret,
+		public:  secret,
+	}
+}
+
+// LoadSigningKey читает приватный ключ из PEM файла (PKCS#8, для RSA также PKCS#1).
+// Если id пуст, в качестве kid используется отпечаток публичного ключа по RFC 7638
+func LoadSigningKey(algorithm, path, id string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	return ParseSigningKey(algorithm, data, id)
+}
+
+// ParseSigningKey разбирает приватный ключ из PEM
+func ParseSigningKey(algorithm string, pemData []byte, id string) (*SigningKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found in key file")
+	}
+
+	var private any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	return newAsymmetricKey(algorithm, private, id)
+}
+
+// GenerateSigningKey создает новый случайный ключ для алгоритма algorithm
+func GenerateSigningKey(algorithm, id string) (*SigningKey, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, 64)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+		return NewHMACKey(id, secret), nil
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("generate rsa key: %w", err)
+		}
+		return newAsymmetricKey(algorithm, private, id)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ed25519 key: %w", err)
+		}
+		return newAsymmetricKey(algorithm, private, id)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// UnmarshalSigningKey восстанавливает ключ из результата MarshalPrivateKey
+func UnmarshalSigningKey(algorithm, id string, data []byte) (*SigningKey, error) {
+	if algorithm == AlgorithmHS256 {
+		return NewHMACKey(id, data), nil
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	return newAsymmetricKey(algorithm, private, id)
+}
+
+// MarshalPrivateKey сериализует приватную часть ключа: PKCS#8 DER для асимметричных ключей,
+// сам секрет для HMAC
+func (k *SigningKey) MarshalPrivateKey() ([]byte, error) {
+	if secret, ok := k.private.([]byte); ok {
+		return secret, nil
+	}
+	return x509.MarshalPKCS8PrivateKey(k.private)
+}
+
+func newAsymmetricKey(algorithm string, private any, id string) (*SigningKey, error) {
+	key := &SigningKey{private: private}
+	switch algorithm {
+	case AlgorithmRS256:
+		rsaKey, ok := private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key is not an RSA key, but algorithm is %s", algorithm)
+		}
+		key.method = jwt.SigningMethodRS256
+		key.public = &rsaKey.PublicKey
+	case AlgorithmEdDSA:
+		edKey, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key is not an Ed25519 key, but algorithm is %s", algorithm)
+		}
+		key.method = jwt.SigningMethodEdDSA
+		key.public = edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported asymmetric algorithm: %s", algorithm)
+	}
+
+	key.Id = id
+	if key.Id == "" {
+		jwk, _ := key.JWK()
+		key.Id = jwk.thumbprint()
+	}
+	return key, nil
+}
+
+// Algorithm возвращает имя алгоритма для заголовка alg
+func (k *SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+// JWK - публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet - набор публичных ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK возвращает публичную часть ключа. Для симметричных ключей false
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{
+		Use: "sig",
+		Alg: k.Algorithm(),
+		Kid: k.Id,
+	}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// thumbprint - отпечаток ключа по RFC 7638: sha256 от обязательных полей в лексикографическом порядке
+func (j JWK) thumbprint() string {
+	var members any
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestLib(keyring *Keyring) *JwtLib {
+	return NewJwtLib(Options{
+		Issuer:          "sso-test",
+		Audience:        "sso-test",
+		AccessDuration:  time.Minute * 15,
+		RefreshDuration: time.Hour,
+		Leeway:          time.Second * 5,
+	}, keyring)
+}
+
+func generateKey(t *testing.T, algorithm, id string) *SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(algorithm, id)
+	if err != nil {
+		t.Fatalf("generate %s key: %v", algorithm, err)
+	}
+	return key
+}
+
+func issueAccessToken(t *testing.T, lib *JwtLib) string {
+	t.Helper()
+	accessToken, _, err := lib.NewToken(1, 2, "refresh-id", "access-id", "")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return accessToken
+}
+
+func TestSignAndParseRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := generateKey(t, algorithm, "")
+			lib := newTestLib(NewKeyring(key))
+			accessToken := issueAccessToken(t, lib)
+
+			token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// ключ HMAC без id подписывает токены без kid
+			kid, _ := token.Header["kid"].(string)
+			if token.Header["alg"] != algorithm || kid != key.Id {
+				t.Fatalf("header %v, want alg %s and kid %q", token.Header, algorithm, key.Id)
+			}
+
+			userId, roleId, tokenId, err := lib.ParseAccessToken(accessToken)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if userId != 1 || roleId != 2 || tokenId != "access-id" {
+				t.Fatalf("parsed %d, %d, %q", userId, roleId, tokenId)
+			}
+		})
+	}
+}
+
+// асимметричный ключ без заданного id получает kid - отпечаток RFC 7638
+func TestGeneratedKeyIdIsThumbprint(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := generateKey(t, algorithm, "")
+			jwk, ok := key.JWK()
+			if !ok {
+				t.Fatal("no JWK for an asymmetric key")
+			}
+			if key.Id == "" || key.Id != jwk.thumbprint() {
+				t.Fatalf("kid %q, want thumbprint %q", key.Id, jwk.thumbprint())
+			}
+			if explicit := generateKey(t, algorithm, "explicit"); explicit.Id != "explicit" {
+				t.Fatalf("kid %q, want explicit", explicit.Id)
+			}
+		})
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+		want string
+	}{
+		// RFC 7638, раздел 3.1
+		{"RSA", JWK{
+			Kty: "RSA",
+			E:   "AQAB",
+			N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+			// поля, не входящие в отпечаток, на него не влияют
+			Alg: "RS256",
+			Kid: "2011-04-29",
+			Use: "sig",
+		}, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		// RFC 8037, приложение A.3
+		{"Ed25519", JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+		}, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.jwk.thumbprint(); got != tt.want {
+				t.Fatalf("thumbprint %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKEncodesPublicKey(t *testing.T) {
+	rsaKey := generateKey(t, AlgorithmRS256, "rsa")
+	jwk, _ := rsaKey.JWK()
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := rsaKey.public.(*rsa.PublicKey)
+	if jwk.Kty != "RSA" || jwk.Alg != AlgorithmRS256 || jwk.Use != "sig" || jwk.Kid != "rsa" {
+		t.Fatalf("RSA JWK %+v", jwk)
+	}
+	if !bytes.Equal(n, public.N.Bytes()) || jwk.E != "AQAB" {
+		t.Fatal("RSA JWK does not match the public key")
+	}
+
+	edKey := generateKey(t, AlgorithmEdDSA, "ed")
+	jwk, _ = edKey.JWK()
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != AlgorithmEdDSA {
+		t.Fatalf("Ed25519 JWK %+v", jwk)
+	}
+	if !bytes.Equal(x, edKey.public.(ed25519.PublicKey)) {
+		t.Fatal("Ed25519 JWK does not match the public key")
+	}
+
+	// общий секрет HMAC не публикуется
+	hmacKey := generateKey(t, AlgorithmHS256, "hmac")
+	if _, ok := hmacKey.JWK(); ok {
+		t.Fatal("HMAC key has a JWK")
+	}
+	set := newTestLib(NewKeyring(hmacKey, rsaKey, edKey)).JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "ed" || set.Keys[1].Kid != "rsa" {
+		t.Fatalf("JWKS %+v, want ed and rsa keys", set.Keys)
+	}
+}
+
+func TestMarshalPrivateKeyRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := generateKey(t, algorithm, "key")
+			data, err := key.MarshalPrivateKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored, err := UnmarshalSigningKey(algorithm, key.Id, data)
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			// токен, подписанный исходным ключом, проверяется восстановленным
+			accessToken := issueAccessToken(t, newTestLib(NewKeyring(key)))
+			if _, _, _, err := newTestLib(NewKeyring(restored)).ParseAccessToken(accessToken); err != nil {
+				t.Fatalf("parse with restored key: %v", err)
+			}
+		})
+	}
+}
+
+// после ротации токены, подписанные прежним ключом, проверяются, пока ключ остается в связке
+func TestRetiredKeyVerifiesUntilRemoved(t *testing.T) {
+	previous := generateKey(t, AlgorithmEdDSA, "")
+	keyring := NewKeyring(previous)
+	lib := newTestLib(keyring)
+	accessToken := issueAccessToken(t, lib)
+
+	current := generateKey(t, AlgorithmRS256, "")
+	keyring.Replace(current, previous)
+	if _, _, _, err := lib.ParseAccessToken(accessToken); err != nil {
+		t.Fatalf("token of the retired key: %v", err)
+	}
+	if _, _, _, err := lib.ParseAccessToken(issueAccessToken(t, lib)); err != nil {
+		t.Fatalf("token of the current key: %v", err)
+	}
+
+	keyring.Replace(current)
+	if _, _, _, err := lib.ParseAccessToken(accessToken); err == nil {
+		t.Fatal("token of the removed key accepted")
+	}
+}
+
+func TestParseRejectsUnknownKey(t *testing.T) {
+	trusted := generateKey(t, AlgorithmRS256, "trusted")
+	lib := newTestLib(NewKeyring(trusted))
+
+	tests := []struct {
+		name string
+		key  *SigningKey
+	}{
+		{"unknown kid", generateKey(t, AlgorithmRS256, "unknown")},
+		// чужой ключ с тем же kid: подпись не сойдется
+		{"forged key with known kid", generateKey(t, AlgorithmRS256, "trusted")},
+		// подмена алгоритма: HMAC с kid асимметричного ключа
+		{"algorithm confusion", NewHMACKey("trusted", []byte("test-secret-test-secret-test-secret"))},
+		{"missing kid", generateKey(t, AlgorithmHS256, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessToken := issueAccessToken(t, newTestLib(NewKeyring(tt.key)))
+			if _, _, _, err := lib.ParseAccessToken(accessToken); err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
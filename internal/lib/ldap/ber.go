@@ -0,0 +1,178 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Минимальная реализация BER (X.690) - ровно то, что нужно для LDAPv3 (RFC 4511)
+
+// maxPacketSize - ответ сервера больше считается ошибкой
+const maxPacketSize = 1 << 20
+
+// Теги BER, используемые в LDAP
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+
+	// бит конструированного (составного) значения
+	constructed = 0x20
+)
+
+var errMalformedPacket = errors.New("некорректный ответ LDAP сервера")
+
+// packet - разобранное значение BER. У составных значений заполнены children
+type packet struct {
+	tag      byte
+	data     []byte
+	children []packet
+}
+
+func (p packet) isConstructed() bool {
+	return p.tag&constructed != 0
+}
+
+func (p packet) string() string {
+	return string(p.data)
+}
+
+func (p packet) integer() (int64, error) {
+	if len(p.data) == 0 || len(p.data) > 8 {
+		return 0, errMalformedPacket
+	}
+	value := int64(int8(p.data[0]))
+	for _, b := range p.data[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// encodeTLV кодирует значение с тегом tag и содержимым content
+func encodeTLV(tag byte, content []byte) []byte {
+	result := []byte{tag}
+	result = append(result, encodeLength(len(content))...)
+	return append(result, content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var octets []byte
+	for length > 0 {
+		octets = append([]byte{byte(length)}, octets...)
+		length >>= 8
+	}
+	return append([]byte{0x80 | byte(len(octets))}, octets...)
+}
+
+func encodeSequence(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, child := range children {
+		content = append(content, child...)
+	}
+	return encodeTLV(tag, content)
+}
+
+func encodeString(tag byte, value string) []byte {
+	return encodeTLV(tag, []byte(value))
+}
+
+func encodeInteger(tag byte, value int64) []byte {
+	// минимальное дополнительное представление: лишние старшие байты 0x00/0xff отбрасываются
+	content := []byte{byte(value)}
+	for value >>= 8; !(value == 0 && content[0]&0x80 == 0) && !(value == -1 && content[0]&0x80 != 0); value >>= 8 {
+		content = append([]byte{byte(value)}, content...)
+	}
+	return encodeTLV(tag, content)
+}
+
+func encodeBoolean(value bool) []byte {
+	if value {
+		return encodeTLV(tagBoolean, []byte{0xff})
+	}
+	return encodeTLV(tagBoolean, []byte{0x00})
+}
+
+// readPacket читает из r одно значение BER целиком и разбирает его
+func readPacket(r io.Reader) (packet, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return packet{}, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 4 {
+			return packet{}, errMalformedPacket
+		}
+		lengthBytes := make([]byte, octets)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return packet{}, err
+		}
+		length = 0
+		for _, b := range lengthBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("%w: слишком большой ответ", errMalformedPacket)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return packet{}, err
+	}
+	return parsePacket(header[0], content)
+}
+
+func parsePacket(tag byte, content []byte) (packet, error) {
+	result := packet{tag: tag, data: content}
+	if !result.isConstructed() {
+		return result, nil
+	}
+
+	for rest := content; len(rest) > 0; {
+		child, size, err := decodeTLV(rest)
+		if err != nil {
+			return packet{}, err
+		}
+		result.children = append(result.children, child)
+		rest = rest[size:]
+	}
+	return result, nil
+}
+
+// decodeTLV разбирает первое значение из data и возвращает его размер вместе с заголовком
+func decodeTLV(data []byte) (packet, int, error) {
+	if len(data) < 2 {
+		return packet{}, 0, errMalformedPacket
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 4 || len(data) < offset+octets {
+			return packet{}, 0, errMalformedPacket
+		}
+		length = 0
+		for _, b := range data[offset : offset+octets] {
+			length = length<<8 | int(b)
+		}
+		offset += octets
+	}
+	if length < 0 || len(data) < offset+length {
+		return packet{}, 0, errMalformedPacket
+	}
+
+	child, err := parsePacket(tag, data[offset:offset+length])
+	if err != nil {
+		return packet{}, 0, err
+	}
+	return child, offset + length, nil
+}
@@ -0,0 +1,160 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func mustHex(t testing.TB, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// mustDecode разбирает одно значение BER, занимающее data целиком
+func mustDecode(t testing.TB, data []byte) packet {
+	t.Helper()
+	p, size, err := decodeTLV(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(data) {
+		t.Fatalf("%d bytes left", len(data)-size)
+	}
+	return p
+}
+
+func TestEncodeLength(t *testing.T) {
+	tests := []struct {
+		length int
+		want   string
+	}{
+		{0, "00"},
+		{0x7f, "7f"},
+		{0x80, "8180"},
+		{0xff, "81ff"},
+		{0x100, "820100"},
+		{maxPacketSize, "83100000"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(encodeLength(tt.length)); got != tt.want {
+			t.Errorf("encodeLength(%d) = %s, want %s", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestIntegerRoundTrip(t *testing.T) {
+	tests := []struct {
+		value int64
+		want  string
+	}{
+		{0, "020100"},
+		{127, "02017f"},
+		{128, "02020080"},
+		{256, "02020100"},
+		{-1, "0201ff"},
+		{-128, "020180"},
+		{-129, "0202ff7f"},
+		{1 << 40, "0206010000000000"},
+	}
+	for _, tt := range tests {
+		encoded := encodeInteger(tagInteger, tt.value)
+		if got := hex.EncodeToString(encoded); got != tt.want {
+			t.Errorf("encodeInteger(%d) = %s, want %s", tt.value, got, tt.want)
+		}
+		decoded, err := mustDecode(t, encoded).integer()
+		if err != nil || decoded != tt.value {
+			t.Errorf("integer() of %d = %d, %v", tt.value, decoded, err)
+		}
+	}
+}
+
+func TestIntegerRejectsInvalidLength(t *testing.T) {
+	for _, data := range []string{"", "010203040506070809"} {
+		if _, err := (packet{tag: tagInteger, data: mustHex(t, data)}).integer(); !errors.Is(err, errMalformedPacket) {
+			t.Errorf("integer() of %q: err = %v, want errMalformedPacket", data, err)
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	long := bytes.Repeat([]byte{'a'}, 300)
+	tests := []struct {
+		name string
+		data []byte
+		want packet
+	}{
+		{"short form", mustHex(t, "040161"), packet{tag: tagOctetString, data: []byte("a")}},
+		{"long form", encodeString(tagOctetString, string(long)), packet{tag: tagOctetString, data: long}},
+		// длина в длинной форме с лишним нулевым байтом допустима в BER
+		{"long form with leading zero", mustHex(t, "0482000161"), packet{tag: tagOctetString, data: []byte("a")}},
+		{"constructed", mustHex(t, "3006020101040161"), packet{tag: tagSequence, data: mustHex(t, "020101040161"), children: []packet{
+			{tag: tagInteger, data: []byte{1}},
+			{tag: tagOctetString, data: []byte("a")},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPacket(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.tag != tt.want.tag || !bytes.Equal(got.data, tt.want.data) || len(got.children) != len(tt.want.children) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i, child := range got.children {
+				if child.tag != tt.want.children[i].tag || !bytes.Equal(child.data, tt.want.children[i].data) {
+					t.Fatalf("child %d: got %+v, want %+v", i, child, tt.want.children[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadPacketRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"empty", "", io.EOF},
+		{"header truncated", "04", io.ErrUnexpectedEOF},
+		{"content truncated", "0404616263", io.ErrUnexpectedEOF},
+		{"length octets truncated", "048200", io.ErrUnexpectedEOF},
+		// неопределенная длина в LDAP запрещена (RFC 4511, п. 5.1)
+		{"indefinite length", "3080", errMalformedPacket},
+		{"length of five octets", "04850000000001", errMalformedPacket},
+		// заявленная длина больше предела: память под нее не выделяется
+		{"above packet limit", "0483100001", errMalformedPacket},
+		{"huge length", "0484ffffffff", errMalformedPacket},
+		{"child longer than parent", "3003040561", errMalformedPacket},
+		{"child length truncated", "30020481", errMalformedPacket},
+		{"child header truncated", "300104", errMalformedPacket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readPacket(bytes.NewReader(mustHex(t, tt.data))); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadPacketReadsOneMessage(t *testing.T) {
+	reader := bytes.NewReader(mustHex(t, "040161040162"))
+	for _, want := range []string{"a", "b"} {
+		p, err := readPacket(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.string() != want {
+			t.Fatalf("got %q, want %q", p.string(), want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Теги операций протокола (RFC 4511, п. 4.2 - 4.12)
+const (
+	opBindRequest         = 0x60
+	opBindResponse        = 0x61
+	opUnbindRequest       = 0x42
+	opSearchRequest       = 0x63
+	opSearchResultEntry   = 0x64
+	opSearchResultDone    = 0x65
+	opSearchResultRef     = 0x73
+	opExtendedRequest     = 0x77
+	opExtendedResponse    = 0x78
+	tagSimpleAuth         = 0x80
+	tagExtendedName       = 0x80
+	oidStartTLS           = "1.3.6.1.4.1.1466.20037"
+	scopeWholeSubtree     = 2
+	derefNever            = 0
+	resultSuccess         = 0
+	resultSizeLimit       = 4
+	resultInvalidCredents = 49
+)
+
+var ErrInvalidCredentials = errors.New("неверное имя пользователя или пароль каталога")
+
+// ResultError - операция завершилась с ненулевым кодом результата LDAP
+type ResultError struct {
+	Code    int64
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("LDAP сервер вернул код %d: %s", e.Code, e.Message)
+}
+
+// Entry - найденная запись каталога
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values возвращает значения атрибута без учета регистра его имени
+func (e *Entry) Values(name string) []string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Conn - синхронное соединение с LDAP сервером. Не безопасно для одновременного использования
+type Conn struct {
+	host      string
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageId int64
+}
+
+// Dial подключается к серверу по адресу ldap://host:port или ldaps://host:port.
+// tlsConfig используется для ldaps и StartTLS, ServerName по умолчанию берется из адреса
+func Dial(ctx context.Context, address string, tlsConfig *tls.Config) (*Conn, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес LDAP сервера: %w", err)
+	}
+
+	host := parsed.Host
+	var secure bool
+	switch parsed.Scheme {
+	case "ldap":
+		if parsed.Port() == "" {
+			host = net.JoinHostPort(parsed.Hostname(), "389")
+		}
+	case "ldaps":
+		secure = true
+		if parsed.Port() == "" {
+			host = net.JoinHostPort(parsed.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемая схема адреса LDAP сервера: %s", parsed.Scheme)
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c := &Conn{host: parsed.Hostname(), conn: conn, reader: bufio.NewReader(conn)}
+	if secure {
+		if err := c.upgradeTLS(ctx, tlsConfig); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// StartTLS переводит открытое соединение ldap:// на TLS (RFC 4511, п. 4.14)
+func (c *Conn) StartTLS(ctx context.Context, tlsConfig *tls.Config) error {
+	request := encodeSequence(opExtendedRequest, encodeString(tagExtendedName, oidStartTLS))
+	response, err := c.roundTrip(request, opExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := checkResult(response); err != nil {
+		return fmt.Errorf("сервер отклонил StartTLS: %w", err)
+	}
+	return c.upgradeTLS(ctx, tlsConfig)
+}
+
+func (c *Conn) upgradeTLS(ctx context.Context, tlsConfig *tls.Config) error {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tlsConfig != nil {
+		cfg = tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = c.host
+	}
+
+	tlsConn := tls.Client(c.conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("ошибка TLS соединения с LDAP сервером: %w", err)
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind выполняет простую аутентификацию. Пустой пароль запрещен: по RFC 4513, п. 5.1.2
+// сервер принимает его как анонимный вход, и любой пароль считался бы верным
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+
+	request := encodeSequence(opBindRequest,
+		encodeInteger(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	)
+	response, err := c.roundTrip(request, opBindResponse)
+	if err != nil {
+		return err
+	}
+
+	err = checkResult(response)
+	var resultErr *ResultError
+	if errors.As(err, &resultErr) && resultErr.Code == resultInvalidCredents {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search ищет записи по фильтру RFC 4515 во всем поддереве baseDN.
+// sizeLimit ограничивает число записей, 0 - без ограничения
+func (c *Conn) Search(baseDN, filter string, attributes []string, sizeLimit int64) ([]Entry, error) {
+	encodedFilter, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs [][]byte
+	for _, attr := range attributes {
+		attrs = append(attrs, encodeString(tagOctetString, attr))
+	}
+	request := encodeSequence(opSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInteger(tagEnumerated, scopeWholeSubtree),
+		encodeInteger(tagEnumerated, derefNever),
+		encodeInteger(tagInteger, sizeLimit),
+		encodeInteger(tagInteger, 0),
+		encodeBoolean(false),
+		encodedFilter,
+		encodeSequence(tagSequence, attrs...),
+	)
+	if err := c.send(request); err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for {
+		op, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case opSearchResultEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultRef:
+			// ссылки на другие серверы не обрабатываются
+		case opSearchResultDone:
+			err := checkResult(op)
+			var resultErr *ResultError
+			if errors.As(err, &resultErr) && resultErr.Code == resultSizeLimit {
+				return entries, nil
+			}
+			return entries, err
+		default:
+			return nil, errMalformedPacket
+		}
+	}
+}
+
+// Close отправляет UnbindRequest и закрывает соединение
+func (c *Conn) Close() error {
+	_ = c.send(encodeTLV(opUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+func (c *Conn) roundTrip(request []byte, responseTag byte) (packet, error) {
+	if err := c.send(request); err != nil {
+		return packet{}, err
+	}
+	response, err := c.receive()
+	if err != nil {
+		return packet{}, err
+	}
+	if response.tag != responseTag {
+		return packet{}, errMalformedPacket
+	}
+	return response, nil
+}
+
+func (c *Conn) send(op []byte) error {
+	c.messageId++
+	message := encodeSequence(tagSequence, encodeInteger(tagInteger, c.messageId), op)
+	_, err := c.conn.Write(message)
+	return err
+}
+
+// receive читает очередное сообщение текущей операции и возвращает его protocolOp
+func (c *Conn) receive() (packet, error) {
+	message, err := readPacket(c.reader)
+	if err != nil {
+		return packet{}, err
+	}
+	if message.tag != tagSequence || len(message.children) < 2 {
+		return packet{}, errMalformedPacket
+	}
+	messageId, err := message.children[0].integer()
+	if err != nil {
+		return packet{}, err
+	}
+	if messageId != c.messageId {
+		return packet{}, fmt.Errorf("%w: неожиданный номер сообщения %d", errMalformedPacket, messageId)
+	}
+	return message.children[1], nil
+}
+
+// checkResult проверяет LDAPResult в начале ответа
+func checkResult(op packet) error {
+	if len(op.children) < 3 {
+		return errMalformedPacket
+	}
+	code, err := op.children[0].integer()
+	if err != nil {
+		return err
+	}
+	if code != resultSuccess {
+		return &ResultError{Code: code, Message: op.children[2].string()}
+	}
+	return nil
+}
+
+func parseEntry(op packet) (Entry, error) {
+	if len(op.children) < 2 {
+		return Entry{}, errMalformedPacket
+	}
+
+	entry := Entry{DN: op.children[0].string(), Attributes: map[string][]string{}}
+	for _, attr := range op.children[1].children {
+		if len(attr.children) < 2 {
+			return Entry{}, errMalformedPacket
+		}
+		name := attr.children[0].string()
+		for _, value := range attr.children[1].children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.string())
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,151 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func dialTestServer(t *testing.T, server *stubServer) *Conn {
+	t.Helper()
+	conn, err := Dial(context.Background(), server.url(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	if err := conn.Bind(testServiceDN, testServicePassword); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	return conn
+}
+
+func encodeDone(code int64, message string) []byte {
+	return encodeSequence(opSearchResultDone,
+		encodeInteger(tagEnumerated, code),
+		encodeString(tagOctetString, ""),
+		encodeString(tagOctetString, message),
+	)
+}
+
+func TestSearchParsesResults(t *testing.T) {
+	alice := Entry{DN: "CN=Alice,DC=corp", Attributes: map[string][]string{"memberOf": {"CN=A,DC=corp", "CN=B,DC=corp"}}}
+	bob := Entry{DN: "CN=Bob,DC=corp", Attributes: map[string][]string{}}
+	referral := encodeSequence(opSearchResultRef, encodeString(tagOctetString, "ldap://other.corp/DC=corp"))
+
+	tests := []struct {
+		name      string
+		responses [][]byte
+		want      []Entry
+		wantCode  int64
+	}{
+		{"entries", [][]byte{encodeEntry(alice), encodeEntry(bob), encodeDone(resultSuccess, "")}, []Entry{alice, bob}, 0},
+		{"no entries", [][]byte{encodeDone(resultSuccess, "")}, nil, 0},
+		{"referral is skipped", [][]byte{referral, encodeEntry(alice), encodeDone(resultSuccess, "")}, []Entry{alice}, 0},
+		{"size limit exceeded", [][]byte{encodeEntry(alice), encodeDone(resultSizeLimit, "")}, []Entry{alice}, 0},
+		{"error result", [][]byte{encodeDone(32, "no such object")}, nil, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubServer(t, nil, testPasswords)
+			server.searchResponse = tt.responses
+
+			entries, err := dialTestServer(t, server).Search("DC=corp", "(cn=a)", []string{"memberOf"}, 2)
+			if tt.wantCode != 0 {
+				var resultErr *ResultError
+				if !errors.As(err, &resultErr) || resultErr.Code != tt.wantCode || resultErr.Message != "no such object" {
+					t.Fatalf("err = %v, want result code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("entries %+v, want %+v", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRejectsMalformedResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []byte
+	}{
+		{"entry without attributes", encodeSequence(opSearchResultEntry, encodeString(tagOctetString, "CN=Alice"))},
+		{"attribute without values", encodeSequence(opSearchResultEntry,
+			encodeString(tagOctetString, "CN=Alice"),
+			encodeSequence(tagSequence, encodeSequence(tagSequence, encodeString(tagOctetString, "memberOf"))),
+		)},
+		{"result without message", encodeSequence(opSearchResultDone, encodeInteger(tagEnumerated, resultSuccess))},
+		{"result code of nine bytes", encodeSequence(opSearchResultDone,
+			encodeTLV(tagEnumerated, make([]byte, 9)),
+			encodeString(tagOctetString, ""),
+			encodeString(tagOctetString, ""),
+		)},
+		{"unexpected operation", encodeResult(opBindResponse, resultSuccess)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubServer(t, nil, testPasswords)
+			server.searchResponse = [][]byte{tt.response, encodeDone(resultSuccess, "")}
+
+			if _, err := dialTestServer(t, server).Search("DC=corp", "(cn=a)", nil, 0); !errors.Is(err, errMalformedPacket) {
+				t.Fatalf("err = %v, want errMalformedPacket", err)
+			}
+		})
+	}
+}
+
+func TestSearchRejectsInvalidFilter(t *testing.T) {
+	server := newStubServer(t, nil, testPasswords)
+
+	if _, err := dialTestServer(t, server).Search("DC=corp", "(cn=a*)", nil, 0); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("err = %v, want ErrInvalidFilter", err)
+	}
+	if len(server.receivedFilters()) != 0 {
+		t.Fatal("invalid filter was sent to the server")
+	}
+}
+
+func TestReceiveRejectsOtherMessageId(t *testing.T) {
+	tests := []struct {
+		name    string
+		message []byte
+	}{
+		{"other message id", encodeSequence(tagSequence, encodeInteger(tagInteger, 2), encodeDone(resultSuccess, ""))},
+		{"without operation", encodeSequence(tagSequence, encodeInteger(tagInteger, 1))},
+		{"not a sequence", encodeString(tagOctetString, "a")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conn{reader: bufio.NewReader(bytes.NewReader(tt.message)), messageId: 1}
+			if _, err := c.receive(); !errors.Is(err, errMalformedPacket) {
+				t.Fatalf("err = %v, want errMalformedPacket", err)
+			}
+		})
+	}
+}
+
+func TestBindRejectsEmptyPassword(t *testing.T) {
+	server := newStubServer(t, nil, testPasswords)
+	conn := dialTestServer(t, server)
+
+	if err := conn.Bind("CN=Alice,DC=corp", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+	if binds := server.receivedBinds(); len(binds) != 1 {
+		t.Fatalf("unauthenticated bind was sent to the server: %v", binds)
+	}
+}
+
+func TestDialRejectsUnknownScheme(t *testing.T) {
+	if _, err := Dial(context.Background(), "http://127.0.0.1:389", nil); err == nil {
+		t.Fatal("http scheme accepted")
+	}
+}
@@ -0,0 +1,135 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUserNotFound       = errors.New("пользователь не найден в каталоге")
+	ErrInsecureConnection = errors.New("ldap:// без StartTLS передает пароль открытым текстом")
+)
+
+// Config - подключение к каталогу одного домена входа (LDAP или Active Directory)
+type Config struct {
+	// URL - ldap://host:389 или ldaps://host:636
+	URL      string
+	StartTLS bool
+	// AllowInsecure разрешает ldap:// без StartTLS - только для тестовых стендов
+	AllowInsecure      bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+
+	// BindDN и BindPassword - сервисная учетная запись для поиска пользователя.
+	// Если BindDN пустой, поиск выполняется анонимно
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// UserFilter - фильтр поиска пользователя, %s заменяется экранированным логином,
+	// например (&(objectClass=user)(userPrincipalName=%s))
+	UserFilter string
+	// GroupAttribute - атрибут записи пользователя со списком DN его групп, по умолчанию memberOf
+	GroupAttribute string
+	// GroupRoles - DN группы каталога и id роли, которую она дает
+	GroupRoles map[string]int64
+}
+
+// Directory проверяет пароль пользователя в каталоге: поиск записи сервисной учетной записью
+// и bind от имени найденного пользователя. Роль определяется группами пользователя
+type Directory struct {
+	cfg Config
+}
+
+func New(cfg Config) *Directory {
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	// DN сравниваются без учета регистра
+	groupRoles := make(map[string]int64, len(cfg.GroupRoles))
+	for group, roleId := range cfg.GroupRoles {
+		groupRoles[normalizeDN(group)] = roleId
+	}
+	cfg.GroupRoles = groupRoles
+	return &Directory{cfg: cfg}
+}
+
+// Authenticate проверяет логин и пароль и возвращает id роли пользователя. Если пользователь
+// состоит в нескольких сопоставленных группах, выбирается роль с наибольшим id.
+// 0 - ни одна группа пользователя не сопоставлена роли
+func (d *Directory) Authenticate(ctx context.Context, login, password string) (int64, error) {
+	if password == "" {
+		return 0, ErrInvalidCredentials
+	}
+	if d.isInsecure() && !d.cfg.AllowInsecure {
+		return 0, ErrInsecureConnection
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+	conn, err := Dial(ctx, d.cfg.URL, tlsConfig)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка подключения к каталогу: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(ctx, tlsConfig); err != nil {
+			return 0, err
+		}
+	}
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return 0, fmt.Errorf("ошибка входа сервисной учетной записи каталога: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(d.cfg.UserFilter, "%s", EscapeFilter(login))
+	entries, err := conn.Search(d.cfg.BaseDN, filter, []string{d.cfg.GroupAttribute}, 2)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка поиска пользователя в каталоге: %w", err)
+	}
+	if len(entries) != 1 {
+		// несколько записей на один логин - ошибка фильтра, войти под любой из них нельзя
+		return 0, ErrUserNotFound
+	}
+
+	if err := conn.Bind(entries[0].DN, password); err != nil {
+		return 0, err
+	}
+
+	var roleId int64
+	for _, group := range entries[0].Values(d.cfg.GroupAttribute) {
+		if groupRole, ok := d.cfg.GroupRoles[normalizeDN(group)]; ok && groupRole > roleId {
+			roleId = groupRole
+		}
+	}
+	return roleId, nil
+}
+
+// isInsecure - соединение не будет зашифровано ни ldaps, ни StartTLS
+func (d *Directory) isInsecure() bool {
+	return !d.cfg.StartTLS && !strings.HasPrefix(strings.ToLower(d.cfg.URL), "ldaps://")
+}
+
+// normalizeDN приводит DN к виду для сравнения: нижний регистр, без пробелов вокруг разделителей
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		attr, value, _ := strings.Cut(part, "=")
+		parts[i] = strings.TrimSpace(attr) + "=" + strings.TrimSpace(value)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
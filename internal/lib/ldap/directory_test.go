@@ -0,0 +1,203 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	testServiceDN       = "CN=sso,OU=Service Accounts,DC=corp,DC=example,DC=ru"
+	testServicePassword = "service-password"
+	testUserFilter      = "(&(objectClass=user)(userPrincipalName=%s))"
+	testManagersGroup   = "CN=Store Managers,OU=Groups,DC=corp,DC=example,DC=ru"
+	testAdminsGroup     = "CN=Store Admins,OU=Groups,DC=corp,DC=example,DC=ru"
+)
+
+func testEntry(login, dn string, groups ...string) Entry {
+	return Entry{DN: dn, Attributes: map[string][]string{
+		"objectClass":       {"top", "user"},
+		"userPrincipalName": {login},
+		"memberOf":          groups,
+	}}
+}
+
+var testEntries = []Entry{
+	testEntry("alice@corp.example.ru", "CN=Alice,OU=Staff,DC=corp,DC=example,DC=ru", testManagersGroup),
+	// группы в записи пользователя могут отличаться от конфига регистром и пробелами
+	testEntry("bob@corp.example.ru", "CN=Bob,OU=Staff,DC=corp,DC=example,DC=ru",
+		"cn=store managers, ou=groups, dc=corp, dc=example, dc=ru", "CN=Store Admins,OU=Groups,DC=corp,DC=example,DC=ru"),
+	testEntry("carol@corp.example.ru", "CN=Carol,OU=Staff,DC=corp,DC=example,DC=ru", "CN=Developers,OU=Groups,DC=corp,DC=example,DC=ru"),
+	// два объекта с одним логином - ошибка заполнения каталога
+	testEntry("twin@corp.example.ru", "CN=Twin 1,OU=Staff,DC=corp,DC=example,DC=ru", testAdminsGroup),
+	testEntry("twin@corp.example.ru", "CN=Twin 2,OU=Staff,DC=corp,DC=example,DC=ru", testAdminsGroup),
+}
+
+var testPasswords = map[string]string{
+	testServiceDN: testServicePassword,
+	"CN=Alice,OU=Staff,DC=corp,DC=example,DC=ru":  "alice-password",
+	"CN=Bob,OU=Staff,DC=corp,DC=example,DC=ru":    "bob-password",
+	"CN=Carol,OU=Staff,DC=corp,DC=example,DC=ru":  "carol-password",
+	"CN=Twin 1,OU=Staff,DC=corp,DC=example,DC=ru": "twin-password",
+	"CN=Twin 2,OU=Staff,DC=corp,DC=example,DC=ru": "twin-password",
+}
+
+func newTestDirectory(server *stubServer) *Directory {
+	return New(Config{
+		URL:           server.url(),
+		AllowInsecure: true,
+		Timeout:       time.Second * 5,
+		BindDN:        testServiceDN,
+		BindPassword:  testServicePassword,
+		BaseDN:        "DC=corp,DC=example,DC=ru",
+		UserFilter:    testUserFilter,
+		GroupRoles: map[string]int64{
+			testManagersGroup: 2,
+			testAdminsGroup:   3,
+		},
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := newStubServer(t, testEntries, testPasswords)
+	directory := newTestDirectory(server)
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantRole int64
+		wantErr  error
+	}{
+		{"manager", "alice@corp.example.ru", "alice-password", 2, nil},
+		{"login in other case", "Alice@Corp.Example.RU", "alice-password", 2, nil},
+		{"highest role of several groups", "bob@corp.example.ru", "bob-password", 3, nil},
+		{"groups without role", "carol@corp.example.ru", "carol-password", 0, nil},
+		{"wrong password", "alice@corp.example.ru", "bob-password", 0, ErrInvalidCredentials},
+		{"empty password", "alice@corp.example.ru", "", 0, ErrInvalidCredentials},
+		{"unknown user", "dave@corp.example.ru", "alice-password", 0, ErrUserNotFound},
+		{"several entries for login", "twin@corp.example.ru", "twin-password", 0, ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roleId, err := directory.Authenticate(context.Background(), tt.login, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if roleId != tt.wantRole {
+				t.Fatalf("role %d, want %d", roleId, tt.wantRole)
+			}
+		})
+	}
+
+	for _, dn := range server.receivedBinds() {
+		if dn == "CN=Twin 1,OU=Staff,DC=corp,DC=example,DC=ru" || dn == "CN=Twin 2,OU=Staff,DC=corp,DC=example,DC=ru" {
+			t.Fatalf("bind as %q although the login matched several entries", dn)
+		}
+	}
+}
+
+// Логин подставляется в фильтр экранированным: спецсимволы не меняют условие поиска
+func TestAuthenticateEscapesLoginInFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		login string
+	}{
+		{"wildcard", "*"},
+		{"wildcard suffix", "alice*"},
+		{"closing parenthesis", "alice@corp.example.ru)(objectClass=*"},
+		{"or injection", "*)(|(userPrincipalName=*"},
+		{"escape sequence", `alice\2a`},
+		{"nul byte", "alice@corp.example.ru\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubServer(t, testEntries, testPasswords)
+
+			_, err := newTestDirectory(server).Authenticate(context.Background(), tt.login, "alice-password")
+			if !errors.Is(err, ErrUserNotFound) {
+				t.Fatalf("err = %v, want ErrUserNotFound", err)
+			}
+
+			want, err := compileFilter(`(&(objectClass=user)(userPrincipalName=` + EscapeFilter(tt.login) + `))`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filters := server.receivedFilters()
+			if len(filters) != 1 || filters[0] != string(want) {
+				t.Fatalf("server received filter %x, want %x", filters, want)
+			}
+			// на равенство отправлен ровно введенный логин
+			equality := mustDecode(t, want).children[1].children[1]
+			if equality.string() != tt.login {
+				t.Fatalf("searched for %q, want %q", equality.string(), tt.login)
+			}
+		})
+	}
+}
+
+func TestAuthenticateServiceBindFailure(t *testing.T) {
+	server := newStubServer(t, testEntries, testPasswords)
+	directory := newTestDirectory(server)
+	directory.cfg.BindPassword = "wrong"
+
+	_, err := directory.Authenticate(context.Background(), "alice@corp.example.ru", "alice-password")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+	if len(server.receivedFilters()) != 0 {
+		t.Fatal("search was performed after failed service bind")
+	}
+}
+
+// Без сервисной учетной записи поиск выполняется анонимно, тестовый сервер его запрещает
+func TestAuthenticateAnonymousSearchDenied(t *testing.T) {
+	server := newStubServer(t, testEntries, testPasswords)
+	directory := newTestDirectory(server)
+	directory.cfg.BindDN = ""
+
+	_, err := directory.Authenticate(context.Background(), "alice@corp.example.ru", "alice-password")
+	var resultErr *ResultError
+	if !errors.As(err, &resultErr) || resultErr.Code != resultInsufficientAccess {
+		t.Fatalf("err = %v, want insufficient access result", err)
+	}
+}
+
+func TestAuthenticateRequiresEncryption(t *testing.T) {
+	server := newStubServer(t, testEntries, testPasswords)
+	directory := newTestDirectory(server)
+	directory.cfg.AllowInsecure = false
+
+	if _, err := directory.Authenticate(context.Background(), "alice@corp.example.ru", "alice-password"); !errors.Is(err, ErrInsecureConnection) {
+		t.Fatalf("err = %v, want ErrInsecureConnection", err)
+	}
+	if binds := server.receivedBinds(); len(binds) != 0 {
+		t.Fatalf("password was sent in clear text: binds %v", binds)
+	}
+
+	// с StartTLS пароль не уходит, пока сервер не переведет соединение на TLS
+	directory.cfg.StartTLS = true
+	if _, err := directory.Authenticate(context.Background(), "alice@corp.example.ru", "alice-password"); err == nil {
+		t.Fatal("server without StartTLS accepted")
+	}
+	if binds := server.receivedBinds(); len(binds) != 0 {
+		t.Fatalf("password was sent before StartTLS: binds %v", binds)
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	groups := []string{
+		"CN=Store Managers,OU=Groups,DC=corp,DC=example,DC=ru",
+		"cn=store managers, ou=groups, dc=corp, dc=example, dc=ru",
+		" CN = Store Managers , OU = Groups , DC=corp,DC=example,DC=ru",
+	}
+	for _, group := range groups[1:] {
+		if normalizeDN(group) != normalizeDN(groups[0]) {
+			t.Fatalf("%q normalized to %q, want %q", group, normalizeDN(group), normalizeDN(groups[0]))
+		}
+	}
+	if normalizeDN("CN=Store Admins,OU=Groups") == normalizeDN("CN=Store Managers,OU=Groups") {
+		t.Fatal("different groups normalized equally")
+	}
+}
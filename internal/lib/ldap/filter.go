@@ -0,0 +1,150 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Теги фильтров поиска (RFC 4511, п. 4.5.1.7)
+const (
+	filterAnd            = 0xa0
+	filterOr             = 0xa1
+	filterNot            = 0xa2
+	filterEqualityMatch  = 0xa3
+	filterGreaterOrEqual = 0xa5
+	filterLessOrEqual    = 0xa6
+	filterPresent        = 0x87
+	filterApproxMatch    = 0xa8
+)
+
+var ErrInvalidFilter = errors.New("некорректный фильтр поиска LDAP")
+
+// EscapeFilter экранирует значение для подстановки в фильтр (RFC 4515, п. 3)
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			b.WriteByte('\\')
+			b.WriteString(hex.EncodeToString([]byte{c}))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter переводит строковый фильтр RFC 4515 в BER. Поддерживаются &, |, !,
+// сравнения =, >=, <=, ~= и проверка наличия атрибута (attr=*). Фильтры по подстрокам не поддерживаются
+func compileFilter(filter string) ([]byte, error) {
+	encoded, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, ErrInvalidFilter
+	}
+	return encoded, nil
+}
+
+func parseFilter(filter string) ([]byte, string, error) {
+	if len(filter) < 3 || filter[0] != '(' {
+		return nil, "", ErrInvalidFilter
+	}
+
+	switch filter[1] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if filter[1] == '|' {
+			tag = filterOr
+		}
+		var children [][]byte
+		rest := filter[2:]
+		for len(rest) > 0 && rest[0] == '(' {
+			child, next, err := parseFilter(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			children = append(children, child)
+			rest = next
+		}
+		if len(children) == 0 || len(rest) == 0 || rest[0] != ')' {
+			return nil, "", ErrInvalidFilter
+		}
+		return encodeSequence(tag, children...), rest[1:], nil
+	case '!':
+		child, rest, err := parseFilter(filter[2:])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", ErrInvalidFilter
+		}
+		return encodeSequence(filterNot, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(filter, ')')
+	if end < 0 {
+		return nil, "", ErrInvalidFilter
+	}
+	encoded, err := parseItem(filter[1:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return encoded, filter[end+1:], nil
+}
+
+// parseItem разбирает простое условие вида attr=value
+func parseItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, ErrInvalidFilter
+	}
+
+	attr, value := item[:eq], item[eq+1:]
+	tag := byte(filterEqualityMatch)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		tag, attr = filterApproxMatch, attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, ErrInvalidFilter
+	}
+
+	if tag == filterEqualityMatch && value == "*" {
+		return encodeString(filterPresent, attr), nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, ErrInvalidFilter
+	}
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSequence(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, unescaped)), nil
+}
+
+func unescapeFilter(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", ErrInvalidFilter
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", ErrInvalidFilter
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,106 @@
+package ldap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"alice@corp.example.ru", "alice@corp.example.ru"},
+		{"*", `\2a`},
+		{"a(b)c", `a\28b\29c`},
+		{`domain\user`, `domain\5cuser`},
+		{"nul\x00", `nul\00`},
+		{"*)(|(cn=*", `\2a\29\28|\28cn=\2a`},
+		{"пользователь", "пользователь"},
+	}
+	for _, tt := range tests {
+		if got := EscapeFilter(tt.value); got != tt.want {
+			t.Errorf("EscapeFilter(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// Экранированное значение после разбора фильтра совпадает с исходным
+func TestEscapeFilterRoundTrip(t *testing.T) {
+	for _, value := range []string{"*", "a(b)c", `\`, `\2a`, "nul\x00", "*)(|(cn=*", "пользователь"} {
+		encoded, err := compileFilter("(cn=" + EscapeFilter(value) + ")")
+		if err != nil {
+			t.Fatalf("%q: %v", value, err)
+		}
+		filter := mustDecode(t, encoded)
+		if filter.tag != filterEqualityMatch || filter.children[1].string() != value {
+			t.Fatalf("%q: decoded as tag %#x value %q", value, filter.tag, filter.children[1].string())
+		}
+	}
+}
+
+func equality(attr, value string) []byte {
+	return encodeSequence(filterEqualityMatch, encodeString(tagOctetString, attr), encodeString(tagOctetString, value))
+}
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []byte
+	}{
+		{"(cn=a)", mustHex(t, "a3070402636e040161")},
+		{"(cn=*)", mustHex(t, "8702636e")},
+		{" (cn=a) ", equality("cn", "a")},
+		{`(cn=a\2a\28\29)`, equality("cn", "a*()")},
+		{"(uidNumber>=1000)", encodeSequence(filterGreaterOrEqual, encodeString(tagOctetString, "uidNumber"), encodeString(tagOctetString, "1000"))},
+		{"(uidNumber<=1000)", encodeSequence(filterLessOrEqual, encodeString(tagOctetString, "uidNumber"), encodeString(tagOctetString, "1000"))},
+		{"(cn~=alice)", encodeSequence(filterApproxMatch, encodeString(tagOctetString, "cn"), encodeString(tagOctetString, "alice"))},
+		{"(&(objectClass=user)(cn=a))", encodeSequence(filterAnd, equality("objectClass", "user"), equality("cn", "a"))},
+		{"(|(cn=a)(cn=b))", encodeSequence(filterOr, equality("cn", "a"), equality("cn", "b"))},
+		{"(!(cn=a))", encodeSequence(filterNot, equality("cn", "a"))},
+		{"(&(cn=a)(|(ou=b)(!(ou=c))))", encodeSequence(filterAnd,
+			equality("cn", "a"),
+			encodeSequence(filterOr, equality("ou", "b"), encodeSequence(filterNot, equality("ou", "c"))),
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := compileFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterRejectsInvalid(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"cn=a",
+		"()",
+		"(cn=a",
+		"(cn=a))",
+		"(cn=a)(cn=b)",
+		"(=a)",
+		"(>=a)",
+		"(cn)",
+		"(&)",
+		"(&(cn=a)",
+		"(!(cn=a)",
+		"(!cn=a)",
+		"(cn=a*)",
+		"(cn=*a*)",
+		`(cn=\2)`,
+		`(cn=\zz)`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			if _, err := compileFilter(filter); !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("err = %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
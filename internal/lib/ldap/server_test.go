@@ -0,0 +1,220 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Коды результата и тег SET OF, которые нужны только тестовому серверу
+const (
+	resultProtocolError      = 2
+	resultInsufficientAccess = 50
+	tagSet                   = 0x31
+)
+
+// stubServer - LDAP сервер в памяти: bind по паролям из passwords, поиск по entries с
+// поддержкой &, |, !, = и наличия атрибута. Поиск без успешного bind запрещен
+type stubServer struct {
+	listener  net.Listener
+	passwords map[string]string
+	entries   []Entry
+
+	mu      sync.Mutex
+	filters []string
+	binds   []string
+	// searchResponse, если задан, отдается на любой поиск вместо найденных записей
+	searchResponse [][]byte
+}
+
+func newStubServer(t *testing.T, entries []Entry, passwords map[string]string) *stubServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubServer{listener: listener, passwords: passwords, entries: entries}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *stubServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// receivedFilters возвращает фильтры всех поисков в виде BER, закодированного в строку
+func (s *stubServer) receivedFilters() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.filters...)
+}
+
+// receivedBinds возвращает DN всех попыток bind
+func (s *stubServer) receivedBinds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *stubServer) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+
+	var bound bool
+	for {
+		message, err := readPacket(reader)
+		if err != nil || len(message.children) < 2 {
+			return
+		}
+		messageId, err := message.children[0].integer()
+		if err != nil {
+			return
+		}
+
+		var responses [][]byte
+		switch op := message.children[1]; op.tag {
+		case opBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			responses = append(responses, encodeResult(opBindResponse, code))
+		case opSearchRequest:
+			if !bound {
+				responses = append(responses, encodeResult(opSearchResultDone, resultInsufficientAccess))
+				break
+			}
+			responses = s.search(op)
+		case opUnbindRequest:
+			return
+		default:
+			responses = append(responses, encodeResult(opExtendedResponse, resultProtocolError))
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(encodeSequence(tagSequence, encodeInteger(tagInteger, messageId), response)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *stubServer) bind(op packet) int64 {
+	if len(op.children) < 3 {
+		return resultProtocolError
+	}
+	dn, password := op.children[1].string(), op.children[2].string()
+
+	s.mu.Lock()
+	s.binds = append(s.binds, dn)
+	s.mu.Unlock()
+
+	if want, ok := s.passwords[dn]; ok && password != "" && password == want {
+		return resultSuccess
+	}
+	return resultInvalidCredents
+}
+
+func (s *stubServer) search(op packet) [][]byte {
+	if len(op.children) < 8 {
+		return [][]byte{encodeResult(opSearchResultDone, resultProtocolError)}
+	}
+	sizeLimit, _ := op.children[3].integer()
+	filter := op.children[6]
+
+	s.mu.Lock()
+	s.filters = append(s.filters, string(encodeTLV(filter.tag, filter.data)))
+	override := s.searchResponse
+	s.mu.Unlock()
+	if override != nil {
+		return override
+	}
+
+	var responses [][]byte
+	code := int64(resultSuccess)
+	for _, entry := range s.entries {
+		if !matchFilter(filter, &entry) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			code = resultSizeLimit
+			break
+		}
+		responses = append(responses, encodeEntry(entry))
+	}
+	return append(responses, encodeResult(opSearchResultDone, code))
+}
+
+// matchFilter проверяет запись по фильтру в BER. Значения сравниваются без учета регистра
+func matchFilter(filter packet, entry *Entry) bool {
+	switch filter.tag {
+	case filterAnd:
+		for _, child := range filter.children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.children) == 1 && !matchFilter(filter.children[0], entry)
+	case filterPresent:
+		return len(entry.Values(filter.string())) > 0
+	case filterEqualityMatch:
+		if len(filter.children) != 2 {
+			return false
+		}
+		for _, value := range entry.Values(filter.children[0].string()) {
+			if strings.EqualFold(value, filter.children[1].string()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func encodeResult(tag byte, code int64) []byte {
+	return encodeSequence(tag,
+		encodeInteger(tagEnumerated, code),
+		encodeString(tagOctetString, ""),
+		encodeString(tagOctetString, ""),
+	)
+}
+
+func encodeEntry(entry Entry) []byte {
+	var attributes [][]byte
+	for name, values := range entry.Attributes {
+		var encodedValues [][]byte
+		for _, value := range values {
+			encodedValues = append(encodedValues, encodeString(tagOctetString, value))
+		}
+		attributes = append(attributes, encodeSequence(tagSequence,
+			encodeString(tagOctetString, name),
+			encodeSequence(tagSet, encodedValues...),
+		))
+	}
+	return encodeSequence(opSearchResultEntry,
+		encodeString(tagOctetString, entry.DN),
+		encodeSequence(tagSequence, attributes...),
+	)
+}
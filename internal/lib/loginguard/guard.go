@@ -0,0 +1,124 @@
+package loginguard
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Policy - параметры защиты от перебора паролей
+type Policy struct {
+	// Window - окно, в котором считаются неудачные попытки
+	Window time.Duration
+	// LockoutDuration - на сколько блокируется вход после превышения лимита
+	LockoutDuration time.Duration
+	// MaxLoginFailures - лимит неудач на один логин, MaxIPFailures - на один IP.
+	// С одного IP перебирают разные логины, поэтому его лимит обычно выше
+	MaxLoginFailures int
+	MaxIPFailures    int
+	// BaseDelay удваивается с каждой неудачей подряд, но не больше MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Guard считает неудачные попытки входа по логину и по IP клиента
+type Guard struct {
+	store  Store
+	policy Policy
+}
+
+func New(store Store, policy Policy) *Guard {
+	return &Guard{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Check возвращает, сколько осталось до снятия блокировки с логина или IP. 0 - вход разрешен
+func (g *Guard) Check(ctx context.Context, login, ip string) (time.Duration, error) {
+	var lockedFor time.Duration
+	for _, key := range g.keys(login, ip) {
+		entry, err := g.store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if left := time.Until(entry.LockedUntil); left > lockedFor {
+			lockedFor = left
+		}
+	}
+	return lockedFor, nil
+}
+
+// Fail учитывает неудачную попытку и возвращает задержку перед ответом.
+// При превышении лимита логин или IP блокируется на LockoutDuration
+func (g *Guard) Fail(ctx context.Context, login, ip string) (time.Duration, error) {
+	loginEntry, err := g.fail(ctx, loginKey(login), g.policy.MaxLoginFailures)
+	if err != nil {
+		return 0, err
+	}
+	failures := loginEntry.Failures
+
+	if ip != "" {
+		ipEntry, err := g.fail(ctx, ipKey(ip), g.policy.MaxIPFailures)
+		if err != nil {
+			return 0, err
+		}
+		failures = max(failures, ipEntry.Failures)
+	}
+
+	return g.delay(failures), nil
+}
+
+// Succeed сбрасывает счетчик логина. Счетчик IP не сбрасывается:
+// удачный вход в свой аккаунт не должен обнулять перебор чужих
+func (g *Guard) Succeed(ctx context.Context, login string) error {
+	return g.store.Reset(ctx, loginKey(login))
+}
+
+// Unlock снимает блокировку с логина досрочно
+func (g *Guard) Unlock(ctx context.Context, login string) error {
+	return g.store.Reset(ctx, loginKey(login))
+}
+
+func (g *Guard) fail(ctx context.Context, key string, limit int) (Entry, error) {
+	entry, err := g.store.Fail(ctx, key, g.policy.Window)
+	if err != nil {
+		return entry, err
+	}
+	if limit > 0 && entry.Failures >= limit && time.Now().After(entry.LockedUntil) {
+		until := time.Now().Add(g.policy.LockoutDuration)
+		if err := g.store.Lock(ctx, key, until); err != nil {
+			return entry, err
+		}
+		slog.Warn("login locked out", "key", key, "failures", entry.Failures, "until", until)
+	}
+	return entry, nil
+}
+
+func (g *Guard) delay(failures int) time.Duration {
+	if failures <= 1 || g.policy.BaseDelay <= 0 {
+		return 0
+	}
+	delay := g.policy.BaseDelay
+	for i := 2; i < failures && delay < g.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.policy.MaxDelay)
+}
+
+func (g *Guard) keys(login, ip string) []string {
+	keys := []string{loginKey(login)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
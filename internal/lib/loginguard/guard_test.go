@@ -0,0 +1,155 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestGuard(policy Policy) (*Guard, *MemoryStore) {
+	store := NewMemoryStore(time.Hour)
+	return New(store, policy), store
+}
+
+func fail(t *testing.T, g *Guard, login, ip string, times int) time.Duration {
+	t.Helper()
+	var delay time.Duration
+	for range times {
+		var err error
+		if delay, err = g.Fail(context.Background(), login, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return delay
+}
+
+func lockedFor(t *testing.T, g *Guard, login, ip string) time.Duration {
+	t.Helper()
+	left, err := g.Check(context.Background(), login, ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return left
+}
+
+func TestLoginLockout(t *testing.T) {
+	g, _ := newTestGuard(Policy{Window: time.Hour, LockoutDuration: time.Hour, MaxLoginFailures: 3, MaxIPFailures: 100})
+
+	fail(t, g, "user@example.com", "10.0.0.1", 2)
+	if left := lockedFor(t, g, "user@example.com", "10.0.0.1"); left != 0 {
+		t.Fatalf("locked for %v below the limit", left)
+	}
+
+	fail(t, g, "user@example.com", "10.0.0.1", 1)
+	// блокировка по логину действует с любого IP и без учета регистра
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", ""} {
+		if left := lockedFor(t, g, " User@Example.com", ip); left <= time.Hour-time.Minute || left > time.Hour {
+			t.Fatalf("from %q: locked for %v, want about an hour", ip, left)
+		}
+	}
+	if left := lockedFor(t, g, "other@example.com", "10.0.0.1"); left != 0 {
+		t.Fatalf("other login from the same IP locked for %v", left)
+	}
+}
+
+// перебор разных логинов с одного IP блокирует IP, но не сами логины
+func TestIPLockout(t *testing.T) {
+	g, _ := newTestGuard(Policy{Window: time.Hour, LockoutDuration: time.Hour, MaxLoginFailures: 3, MaxIPFailures: 4})
+
+	for _, login := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		fail(t, g, login, "10.0.0.1", 1)
+	}
+	if left := lockedFor(t, g, "e@example.com", "10.0.0.1"); left == 0 {
+		t.Fatal("IP is not locked")
+	}
+	if left := lockedFor(t, g, "a@example.com", "10.0.0.2"); left != 0 {
+		t.Fatalf("login locked for %v from another IP", left)
+	}
+}
+
+func TestFailDelay(t *testing.T) {
+	g, _ := newTestGuard(Policy{Window: time.Hour, BaseDelay: time.Second, MaxDelay: time.Second * 5})
+
+	// первая неудача без задержки, дальше задержка удваивается до MaxDelay
+	want := []time.Duration{0, time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5}
+	for i, delay := range want {
+		if got := fail(t, g, "user@example.com", "", 1); got != delay {
+			t.Fatalf("failure %d: delay %v, want %v", i+1, got, delay)
+		}
+	}
+
+	// задержка считается по большему из счетчиков логина и IP
+	g, _ = newTestGuard(Policy{Window: time.Hour, BaseDelay: time.Second, MaxDelay: time.Second * 5})
+	fail(t, g, "a@example.com", "10.0.0.1", 3)
+	if got := fail(t, g, "b@example.com", "10.0.0.1", 1); got != time.Second*4 {
+		t.Fatalf("delay %v, want 4s by the IP counter", got)
+	}
+}
+
+func TestFailWindowExpires(t *testing.T) {
+	g, store := newTestGuard(Policy{Window: time.Minute, LockoutDuration: time.Hour, MaxLoginFailures: 3})
+
+	fail(t, g, "user@example.com", "", 2)
+	// окно первой неудачи истекло - счет начинается заново
+	entry := store.entries[loginKey("user@example.com")]
+	entry.WindowStart = time.Now().Add(-time.Minute * 2)
+	store.entries[loginKey("user@example.com")] = entry
+
+	fail(t, g, "user@example.com", "", 2)
+	if left := lockedFor(t, g, "user@example.com", ""); left != 0 {
+		t.Fatalf("locked for %v after the window expired", left)
+	}
+	fail(t, g, "user@example.com", "", 1)
+	if left := lockedFor(t, g, "user@example.com", ""); left == 0 {
+		t.Fatal("not locked after three failures in the window")
+	}
+}
+
+func TestSucceedKeepsIPCounter(t *testing.T) {
+	g, _ := newTestGuard(Policy{Window: time.Hour, LockoutDuration: time.Hour, MaxLoginFailures: 3, MaxIPFailures: 3})
+
+	fail(t, g, "user@example.com", "10.0.0.1", 2)
+	if err := g.Succeed(context.Background(), "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// счетчик логина обнулен: две новые неудачи не блокируют логин с другого IP
+	fail(t, g, "user@example.com", "10.0.0.2", 2)
+	if left := lockedFor(t, g, "user@example.com", "10.0.0.2"); left != 0 {
+		t.Fatalf("login locked for %v after success", left)
+	}
+	// а счетчик IP продолжает считать
+	fail(t, g, "other@example.com", "10.0.0.1", 1)
+	if left := lockedFor(t, g, "other@example.com", "10.0.0.1"); left == 0 {
+		t.Fatal("IP counter was reset by a successful login")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	g, _ := newTestGuard(Policy{Window: time.Hour, LockoutDuration: time.Hour, MaxLoginFailures: 3, MaxIPFailures: 100})
+
+	fail(t, g, "user@example.com", "10.0.0.1", 3)
+	if err := g.Unlock(context.Background(), "User@Example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if left := lockedFor(t, g, "user@example.com", "10.0.0.1"); left != 0 {
+		t.Fatalf("locked for %v after unlock", left)
+	}
+	// после снятия блокировки счет неудач начинается заново
+	fail(t, g, "user@example.com", "10.0.0.1", 2)
+	if left := lockedFor(t, g, "user@example.com", "10.0.0.1"); left != 0 {
+		t.Fatalf("locked for %v below the limit after unlock", left)
+	}
+}
+
+// повторные неудачи во время блокировки не продлевают ее
+func TestFailDuringLockoutKeepsDeadline(t *testing.T) {
+	g, store := newTestGuard(Policy{Window: time.Hour, LockoutDuration: time.Hour, MaxLoginFailures: 2})
+
+	fail(t, g, "user@example.com", "", 2)
+	until := store.entries[loginKey("user@example.com")].LockedUntil
+	fail(t, g, "user@example.com", "", 3)
+	if got := store.entries[loginKey("user@example.com")].LockedUntil; !got.Equal(until) {
+		t.Fatalf("lockout moved from %v to %v", until, got)
+	}
+}
@@ -0,0 +1,102 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Entry - состояние счетчика неудачных попыток по одному ключу (логину или IP)
+type Entry struct {
+	Failures    int
+	WindowStart time.Time
+	LockedUntil time.Time
+}
+
+// Store хранит счетчики. По умолчанию используется MemoryStore - счетчики живут в процессе.
+// Для нескольких экземпляров сервиса достаточно реализовать Store поверх общего хранилища
+type Store interface {
+	Get(ctx context.Context, key string) (Entry, error)
+	// Fail увеличивает счетчик. Если с первой неудачи прошло больше window, счет начинается заново
+	Fail(ctx context.Context, key string, window time.Duration) (Entry, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	// ttl - сколько хранить запись после окна и блокировки
+	ttl time.Duration
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]Entry),
+		ttl:     ttl,
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, window time.Duration) (Entry, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[key]
+	if entry.Failures == 0 || now.Sub(entry.WindowStart) > window {
+		entry.Failures = 0
+		entry.WindowStart = now
+	}
+	entry.Failures++
+	s.entries[key] = entry
+	return entry, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[key]
+	entry.LockedUntil = until
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Start периодически удаляет устаревшие записи, пока не завершится ctx
+func (s *MemoryStore) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+func (s *MemoryStore) cleanup() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if now.Sub(entry.WindowStart) > s.ttl && now.After(entry.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
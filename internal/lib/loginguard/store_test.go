@@ -0,0 +1,30 @@
+package loginguard
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Minute)
+	now := time.Now()
+	store.entries = map[string]Entry{
+		"stale":  {Failures: 1, WindowStart: now.Add(-time.Hour)},
+		"recent": {Failures: 1, WindowStart: now},
+		// окно давно закончилось, но блокировка еще действует
+		"locked": {Failures: 5, WindowStart: now.Add(-time.Hour), LockedUntil: now.Add(time.Hour)},
+	}
+
+	store.cleanup()
+	for key, want := range map[string]bool{"stale": false, "recent": true, "locked": true} {
+		entry, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := entry.Failures > 0; got != want {
+			t.Fatalf("%s kept %v, want %v", key, got, want)
+		}
+	}
+}
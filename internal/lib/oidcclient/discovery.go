@@ -0,0 +1,86 @@
+package oidcclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataTTL - как долго используется загруженный документ discovery
+const metadataTTL = 24 * time.Hour
+
+// endpoints - часть документа /.well-known/openid-configuration, которая нужна клиенту
+type endpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type metadata struct {
+	issuer string
+
+	mu        sync.Mutex
+	cached    *endpoints
+	fetchedAt time.Time
+}
+
+// endpoints возвращает эндпоинты провайдера: из discovery, если задан Issuer,
+// с приоритетом явно заданных в конфиге адресов
+func (p *Provider) endpoints(ctx context.Context) (*endpoints, error) {
+	result := endpoints{}
+	if p.cfg.Issuer != "" {
+		discovered, err := p.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result = *discovered
+	}
+
+	if p.cfg.AuthURL != "" {
+		result.AuthorizationEndpoint = p.cfg.AuthURL
+	}
+	if p.cfg.TokenURL != "" {
+		result.TokenEndpoint = p.cfg.TokenURL
+	}
+	if p.cfg.UserInfoURL != "" {
+		result.UserinfoEndpoint = p.cfg.UserInfoURL
+	}
+	return &result, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*endpoints, error) {
+	p.meta.mu.Lock()
+	defer p.meta.mu.Unlock()
+
+	if p.meta.cached != nil && time.Since(p.meta.fetchedAt) < metadataTTL {
+		return p.meta.cached, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.meta.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered endpoints
+	status, err := doJSON(p.client, req, &discovered)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: discovery вернул статус %d", ErrInvalidResponse, status)
+	}
+	// OpenID Connect Discovery 1.0, п. 4.3: issuer документа должен совпадать с запрошенным
+	if strings.TrimSuffix(discovered.Issuer, "/") != strings.TrimSuffix(p.meta.issuer, "/") {
+		return nil, fmt.Errorf("%w: issuer %s не совпадает с настроенным", ErrInvalidResponse, discovered.Issuer)
+	}
+
+	p.meta.cached = &discovered
+	p.meta.fetchedAt = time.Now()
+	return p.meta.cached, nil
+}
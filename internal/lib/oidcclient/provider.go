@@ -0,0 +1,259 @@
+package oidcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResponseSize - ответы провайдера больше не читаются
+const maxResponseSize = 1 << 20
+
+var (
+	ErrExchangeFailed  = errors.New("провайдер не выдал токен по коду авторизации")
+	ErrInvalidIDToken  = errors.New("id_token провайдера не прошел проверку")
+	ErrNoSubject       = errors.New("провайдер не вернул идентификатор пользователя")
+	ErrNotConfigured   = errors.New("у провайдера не настроены эндпоинты")
+	ErrInvalidResponse = errors.New("некорректный ответ провайдера")
+)
+
+// Config - внешний провайдер OAuth2/OIDC. Если задан Issuer, эндпоинты и ключи берутся
+// из /.well-known/openid-configuration, иначе используются AuthURL, TokenURL и UserInfoURL
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RedirectURL - страница фронтенда, на которую провайдер вернет code и state
+	RedirectURL string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	// Имена полей с идентификатором и email пользователя, по умолчанию sub, email и email_verified
+	SubjectClaim       string
+	EmailClaim         string
+	EmailVerifiedClaim string
+	// TrustEmail - провайдер отдает только подтвержденные email и не присылает email_verified
+	TrustEmail bool
+}
+
+// Identity - пользователь, подтвержденный провайдером
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider - клиент одного внешнего провайдера по authorization code flow с PKCE
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	meta   *metadata
+	keys   *keySet
+}
+
+func New(cfg Config) *Provider {
+	if cfg.SubjectClaim == "" {
+		cfg.SubjectClaim = "sub"
+	}
+	if cfg.EmailClaim == "" {
+		cfg.EmailClaim = "email"
+	}
+	if cfg.EmailVerifiedClaim == "" {
+		cfg.EmailVerifiedClaim = "email_verified"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &Provider{
+		cfg:    cfg,
+		client: client,
+		meta:   &metadata{issuer: cfg.Issuer},
+		keys:   &keySet{client: client},
+	}
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера. codeChallenge - BASE64URL(SHA256(code_verifier))
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+	if endpoints.AuthorizationEndpoint == "" {
+		return "", ErrNotConfigured
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(endpoints.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return endpoints.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange обменивает код авторизации на токены и возвращает пользователя: из проверенного
+// id_token, а если его нет или в нем нет email - из userinfo
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints.TokenEndpoint == "" {
+		return nil, ErrNotConfigured
+	}
+
+	tokens, err := p.exchangeCode(ctx, endpoints.TokenEndpoint, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if tokens.IDToken != "" && endpoints.JWKSURI != "" {
+		claims, err = p.verifyIDToken(ctx, endpoints, tokens.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (claims == nil || claims[p.cfg.EmailClaim] == nil) && endpoints.UserinfoEndpoint != "" {
+		userInfo, err := p.userInfo(ctx, endpoints.UserinfoEndpoint, tokens.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if claims == nil {
+			claims = userInfo
+		} else if claimString(userInfo, p.cfg.SubjectClaim) == claimString(claims, p.cfg.SubjectClaim) {
+			// userinfo другого пользователя (подмена access токена) игнорируется
+			claims[p.cfg.EmailClaim] = userInfo[p.cfg.EmailClaim]
+			claims[p.cfg.EmailVerifiedClaim] = userInfo[p.cfg.EmailVerifiedClaim]
+		}
+	}
+	if claims == nil {
+		return nil, ErrNotConfigured
+	}
+
+	identity := &Identity{
+		Subject:       claimString(claims, p.cfg.SubjectClaim),
+		Email:         strings.ToLower(strings.TrimSpace(claimString(claims, p.cfg.EmailClaim))),
+		EmailVerified: p.cfg.TrustEmail || claimBool(claims, p.cfg.EmailVerifiedClaim),
+	}
+	if identity.Subject == "" {
+		return nil, ErrNoSubject
+	}
+	return identity, nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *Provider) exchangeCode(ctx context.Context, tokenURL, code, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens tokenResponse
+	status, err := doJSON(p.client, req, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || tokens.Error != "" || tokens.AccessToken == "" {
+		return nil, fmt.Errorf("%w: %s", ErrExchangeFailed, strings.TrimSpace(tokens.Error+" "+tokens.ErrorDescription))
+	}
+	return &tokens, nil
+}
+
+func (p *Provider) userInfo(ctx context.Context, userInfoURL, accessToken string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var claims map[string]any
+	status, err := doJSON(p.client, req, &claims)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: userinfo вернул статус %d", ErrInvalidResponse, status)
+	}
+	return claims, nil
+}
+
+// doJSON выполняет запрос и разбирает JSON ответа в target, числа сохраняются как json.Number
+func doJSON(client *http.Client, req *http.Request, target any) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка запроса к провайдеру: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения ответа провайдера: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// claimString - строковое значение поля. Некоторые провайдеры отдают идентификатор числом
+func claimString(claims map[string]any, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	}
+	return ""
+}
+
+// claimBool - email_verified бывает и булевым, и строкой "true"
+func claimBool(claims map[string]any, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package oidcclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "sso-client"
+	testNonce    = "nonce-1"
+	testKid      = "key-1"
+)
+
+// stubIdP - провайдер OIDC в памяти: discovery, JWKS, токен-эндпоинт и userinfo.
+// idToken формирует id_token ответа токен-эндпоинта, по умолчанию - корректный
+type stubIdP struct {
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	idToken  func(issuer string) string
+	userInfo map[string]any
+	form     url.Values
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key}
+	idp.idToken = func(issuer string) string {
+		return idp.sign(t, testKid, key, idp.claims(issuer))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		point, _ := key.PublicKey.ECDH()
+		raw := point.Bytes()
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": testKid,
+			"use": "sig",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(raw[1:33]),
+			"y":   base64.RawURLEncoding.EncodeToString(raw[33:]),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.mu.Lock()
+		idp.form = r.PostForm
+		idToken := idp.idToken
+		idp.mu.Unlock()
+		writeJSON(w, map[string]string{"access_token": "access", "id_token": idToken(idp.server.URL)})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		if idp.userInfo == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, idp.userInfo)
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *stubIdP) claims(issuer string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            issuer,
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"email":          "User@Example.com",
+		"email_verified": true,
+		"nonce":          testNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+func (idp *stubIdP) sign(t *testing.T, kid string, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// withClaims подменяет id_token токеном с измененными полями; nil значение удаляет поле
+func (idp *stubIdP) withClaims(t *testing.T, changes map[string]any) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.idToken = func(issuer string) string {
+		claims := idp.claims(issuer)
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return idp.sign(t, testKid, idp.key, claims)
+	}
+}
+
+func (idp *stubIdP) provider() *Provider {
+	return New(Config{
+		Issuer:       idp.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		Scopes:       []string{"openid", "email"},
+		RedirectURL:  "https://sso.example.com/callback",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func TestExchangeVerifiesIDToken(t *testing.T) {
+	idp := newStubIdP(t)
+
+	identity, err := idp.provider().Exchange(context.Background(), "code", "verifier", testNonce)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	want := Identity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true}
+	if *identity != want {
+		t.Fatalf("identity %+v, want %+v", *identity, want)
+	}
+	if idp.form.Get("code_verifier") != "verifier" || idp.form.Get("client_id") != testClientID {
+		t.Fatalf("unexpected token request: %v", idp.form)
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, idp *stubIdP)
+	}{
+		{"other issuer", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"iss": "https://evil.example.com"})
+		}},
+		{"other audience", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"aud": "other-client"})
+		}},
+		{"expired", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"exp": time.Now().Add(-2 * idTokenLeeway).Unix()})
+		}},
+		{"without expiration", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"exp": nil})
+		}},
+		{"other nonce", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"nonce": "replayed"})
+		}},
+		{"without nonce", func(t *testing.T, idp *stubIdP) {
+			idp.withClaims(t, map[string]any{"nonce": nil})
+		}},
+		{"signed by other key", func(t *testing.T, idp *stubIdP) {
+			idp.idToken = func(issuer string) string {
+				return idp.sign(t, testKid, otherKey, idp.claims(issuer))
+			}
+		}},
+		{"unknown key id", func(t *testing.T, idp *stubIdP) {
+			idp.idToken = func(issuer string) string {
+				return idp.sign(t, "key-2", idp.key, idp.claims(issuer))
+			}
+		}},
+		{"unsigned", func(t *testing.T, idp *stubIdP) {
+			idp.idToken = func(issuer string) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims(issuer))
+				signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			}
+		}},
+		{"tampered payload", func(t *testing.T, idp *stubIdP) {
+			idp.idToken = func(issuer string) string {
+				parts := strings.Split(idp.sign(t, testKid, idp.key, idp.claims(issuer)), ".")
+				claims := idp.claims(issuer)
+				claims["sub"] = "admin"
+				payload, _ := json.Marshal(claims)
+				parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+				return strings.Join(parts, ".")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			tt.prepare(t, idp)
+
+			_, err := idp.provider().Exchange(context.Background(), "code", "verifier", testNonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeIgnoresUserInfoOfOtherSubject(t *testing.T) {
+	idp := newStubIdP(t)
+	idp.withClaims(t, map[string]any{"email": nil, "email_verified": nil})
+	// userinfo по подмененному access токену принадлежит другому пользователю
+	idp.userInfo = map[string]any{"sub": "subject-2", "email": "victim@example.com", "email_verified": true}
+
+	identity, err := idp.provider().Exchange(context.Background(), "code", "verifier", testNonce)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if identity.Email != "" || identity.EmailVerified {
+		t.Fatalf("email of other subject was used: %+v", *identity)
+	}
+
+	idp.userInfo = map[string]any{"sub": "subject-1", "email": "user@example.com", "email_verified": "true"}
+	identity, err = idp.provider().Exchange(context.Background(), "code", "verifier", testNonce)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if identity.Email != "user@example.com" || !identity.EmailVerified {
+		t.Fatalf("email from userinfo was not used: %+v", *identity)
+	}
+}
+
+func TestDiscoveryRejectsOtherIssuer(t *testing.T) {
+	idp := newStubIdP(t)
+	provider := New(Config{Issuer: idp.server.URL + "/tenant", ClientID: testClientID})
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", testNonce, "challenge")
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newStubIdP(t)
+
+	authURL, err := idp.provider().AuthCodeURL(context.Background(), "state", testNonce, "challenge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("state") != "state" || query.Get("nonce") != testNonce ||
+		query.Get("code_challenge") != "challenge" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization url %s", authURL)
+	}
+}
@@ -0,0 +1,44 @@
+package passwordhash
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func newBcrypt(cost int) (*bcryptAlgorithm, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("стоимость bcrypt должна быть от %d до %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &bcryptAlgorithm{cost: cost}, nil
+}
+
+// recognizes - bcrypt хранится в модульном формате crypt ($2a$10$...), его же понимает PHC
+func (b *bcryptAlgorithm) recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *bcryptAlgorithm) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *bcryptAlgorithm) verify(encoded, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+func (b *bcryptAlgorithm) weaker(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost < b.cost
+}
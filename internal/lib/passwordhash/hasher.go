@@ -0,0 +1,100 @@
+package passwordhash
+
+import (
+	"fmt"
+)
+
+// Поддерживаемые алгоритмы - значения password_hash.algorithm
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Config - алгоритм для новых хешей и его параметры
+type Config struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2id   Argon2idParams
+}
+
+// algorithm - один алгоритм хеширования со своим форматом закодированного хеша
+type algorithm interface {
+	// recognizes - хеш закодирован в формате этого алгоритма
+	recognizes(encoded string) bool
+	hash(password string) (string, error)
+	verify(encoded, password string) bool
+	// weaker - хеш посчитан с параметрами слабее текущих
+	weaker(encoded string) bool
+}
+
+// Hasher хеширует пароли выбранным в конфиге алгоритмом, а проверяет хеши
+// любого из поддерживаемых - так пароли со старыми параметрами продолжают работать
+type Hasher struct {
+	current algorithm
+	known   []algorithm
+}
+
+func New(cfg Config) (*Hasher, error) {
+	bcryptAlgorithm, err := newBcrypt(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	argon2idAlgorithm, err := newArgon2id(cfg.Argon2id)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := &Hasher{known: []algorithm{bcryptAlgorithm, argon2idAlgorithm}}
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt, "":
+		hasher.current = bcryptAlgorithm
+	case AlgorithmArgon2id:
+		hasher.current = argon2idAlgorithm
+	default:
+		return nil, fmt.Errorf("неизвестный алгоритм хеширования паролей: %s", cfg.Algorithm)
+	}
+	return hasher, nil
+}
+
+// Hash возвращает закодированный хеш пароля: bcrypt в формате $2a$, argon2id в формате PHC
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	encoded, err := h.current.hash(password)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encoded), nil
+}
+
+// Verify проверяет пароль по хешу любого поддерживаемого алгоритма.
+// Хеш неизвестного формата не совпадает ни с одним паролем
+func (h *Hasher) Verify(hash []byte, password string) bool {
+	encoded := string(hash)
+	algorithm := h.find(encoded)
+	if algorithm == nil {
+		return false
+	}
+	return algorithm.verify(encoded, password)
+}
+
+// NeedsRehash - хеш посчитан другим алгоритмом или с параметрами слабее текущих.
+// Вызывается после успешной проверки пароля, пока пароль в открытом виде еще доступен
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	encoded := string(hash)
+	algorithm := h.find(encoded)
+	if algorithm == nil {
+		return false
+	}
+	if algorithm != h.current {
+		return true
+	}
+	return algorithm.weaker(encoded)
+}
+
+func (h *Hasher) find(encoded string) algorithm {
+	for _, algorithm := range h.known {
+		if algorithm.recognizes(encoded) {
+			return algorithm
+		}
+	}
+	return nil
+}
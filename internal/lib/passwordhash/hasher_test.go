@@ -0,0 +1,244 @@
+package passwordhash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// параметры меньше рабочих, чтобы тесты не тратили секунды на каждый хеш
+var testArgon2id = Argon2idParams{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasher(t *testing.T, algorithm string, bcryptCost int, argon2id Argon2idParams) *Hasher {
+	t.Helper()
+	hasher, err := New(Config{Algorithm: algorithm, BcryptCost: bcryptCost, Argon2id: argon2id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+func mustHash(t *testing.T, hasher *Hasher, password string) []byte {
+	t.Helper()
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashAndVerify(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{AlgorithmBcrypt, "$2a$05$"},
+		{AlgorithmArgon2id, "$argon2id$v=19$m=1024,t=2,p=1$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hasher := newTestHasher(t, tt.algorithm, 5, testArgon2id)
+			hash := mustHash(t, hasher, "correct horse")
+
+			if !strings.HasPrefix(string(hash), tt.prefix) {
+				t.Fatalf("hash %s, want prefix %s", hash, tt.prefix)
+			}
+			if !hasher.Verify(hash, "correct horse") {
+				t.Fatal("password does not match its own hash")
+			}
+			for _, wrong := range []string{"", "correct horsE", "correct horse ", "correct"} {
+				if hasher.Verify(hash, wrong) {
+					t.Fatalf("password %q matches", wrong)
+				}
+			}
+			// соль случайная - одинаковые пароли дают разные хеши
+			if string(mustHash(t, hasher, "correct horse")) == string(hash) {
+				t.Fatal("hash is not salted")
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Fatal("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+// хеш чужого формата не совпадает ни с одним паролем и не требует пересчета
+func TestVerifyRejectsForeignHashes(t *testing.T) {
+	validKey := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	validSalt := base64.RawStdEncoding.EncodeToString(make([]byte, 16))
+	hashes := []string{
+		"",
+		"password",
+		"$",
+		"$2x$05$" + strings.Repeat("a", 53),
+		"$1$salt$md5cryptdigest",
+		"$5$rounds=5000$salt$sha256crypt",
+		"$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5",
+		"$argon2i$v=19$m=1024,t=2,p=1$" + validSalt + "$" + validKey,
+	}
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		hasher := newTestHasher(t, algorithm, 5, testArgon2id)
+		for _, hash := range hashes {
+			t.Run(algorithm+" "+hash, func(t *testing.T) {
+				for _, password := range []string{"", "password"} {
+					if hasher.Verify([]byte(hash), password) {
+						t.Fatalf("password %q matches", password)
+					}
+				}
+				if hasher.NeedsRehash([]byte(hash)) {
+					t.Fatal("foreign hash needs rehash")
+				}
+			})
+		}
+	}
+}
+
+// поврежденный хеш известного формата не совпадает ни с одним паролем, в том числе пустым
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	validKey := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	validSalt := base64.RawStdEncoding.EncodeToString(make([]byte, 16))
+	hashes := []string{
+		"$2a$",
+		"$2a$05$short",
+		"$2a$99$" + strings.Repeat("a", 53),
+		"$argon2id$",
+		"$argon2id$v=19$m=1024,t=2,p=1$" + validSalt,
+		"$argon2id$v=19$m=1024,t=2,p=1$" + validSalt + "$" + validKey + "$extra",
+		"$argon2id$v=16$m=1024,t=2,p=1$" + validSalt + "$" + validKey,
+		"$argon2id$v=x$m=1024,t=2,p=1$" + validSalt + "$" + validKey,
+		"$argon2id$v=19$m=0,t=2,p=1$" + validSalt + "$" + validKey,
+		"$argon2id$v=19$m=1024,t=0,p=1$" + validSalt + "$" + validKey,
+		"$argon2id$v=19$m=1024,t=2,p=0$" + validSalt + "$" + validKey,
+		"$argon2id$v=19$t=2,p=1$" + validSalt + "$" + validKey,
+		"$argon2id$v=19$m=1024,t=2,p=1$not base64!$" + validKey,
+		"$argon2id$v=19$m=1024,t=2,p=1$" + validSalt + "$not base64!",
+		"$argon2id$v=19$m=1024,t=2,p=1$" + validSalt + "$",
+	}
+	hasher := newTestHasher(t, AlgorithmArgon2id, 5, testArgon2id)
+	for _, hash := range hashes {
+		t.Run(hash, func(t *testing.T) {
+			for _, password := range []string{"", "password"} {
+				if hasher.Verify([]byte(hash), password) {
+					t.Fatalf("password %q matches", password)
+				}
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weaker := func(change func(params *Argon2idParams)) Argon2idParams {
+		params := testArgon2id
+		change(&params)
+		return params
+	}
+	tests := []struct {
+		name string
+		// hashedWith - конфиг, которым посчитан хеш, current - конфиг сервиса сейчас
+		hashedWith Config
+		current    Config
+		want       bool
+	}{
+		{"bcrypt same cost", Config{AlgorithmBcrypt, 5, testArgon2id}, Config{AlgorithmBcrypt, 5, testArgon2id}, false},
+		{"bcrypt lower cost", Config{AlgorithmBcrypt, 4, testArgon2id}, Config{AlgorithmBcrypt, 5, testArgon2id}, true},
+		{"bcrypt higher cost", Config{AlgorithmBcrypt, 6, testArgon2id}, Config{AlgorithmBcrypt, 5, testArgon2id}, false},
+		{"argon2id same params", Config{AlgorithmArgon2id, 5, testArgon2id}, Config{AlgorithmArgon2id, 5, testArgon2id}, false},
+		{"argon2id lower memory", Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.Memory = 512 })}, Config{AlgorithmArgon2id, 5, testArgon2id}, true},
+		{"argon2id fewer iterations", Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.Iterations = 1 })}, Config{AlgorithmArgon2id, 5, testArgon2id}, true},
+		{"argon2id lower parallelism", Config{AlgorithmArgon2id, 5, testArgon2id}, Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.Parallelism = 2 })}, true},
+		{"argon2id shorter salt", Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.SaltLength = 8 })}, Config{AlgorithmArgon2id, 5, testArgon2id}, true},
+		{"argon2id shorter key", Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.KeyLength = 16 })}, Config{AlgorithmArgon2id, 5, testArgon2id}, true},
+		{"argon2id stronger params", Config{AlgorithmArgon2id, 5, weaker(func(p *Argon2idParams) { p.Memory = 2048 })}, Config{AlgorithmArgon2id, 5, testArgon2id}, false},
+		{"bcrypt to argon2id", Config{AlgorithmBcrypt, 5, testArgon2id}, Config{AlgorithmArgon2id, 5, testArgon2id}, true},
+		{"argon2id to bcrypt", Config{AlgorithmArgon2id, 5, testArgon2id}, Config{AlgorithmBcrypt, 5, testArgon2id}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := newTestHasher(t, tt.hashedWith.Algorithm, tt.hashedWith.BcryptCost, tt.hashedWith.Argon2id)
+			after := newTestHasher(t, tt.current.Algorithm, tt.current.BcryptCost, tt.current.Argon2id)
+			hash := mustHash(t, before, "password")
+
+			if got := after.NeedsRehash(hash); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+			// хеш со старыми параметрами по-прежнему проверяется
+			if !after.Verify(hash, "password") {
+				t.Fatal("hash made with previous config does not verify")
+			}
+		})
+	}
+}
+
+func TestArgon2idEncoding(t *testing.T) {
+	hasher := newTestHasher(t, AlgorithmArgon2id, 0, testArgon2id)
+	hash := mustHash(t, hasher, "password")
+
+	params, salt, key, err := decodeArgon2id(string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != testArgon2id || len(salt) != 16 || len(key) != 32 {
+		t.Fatalf("decoded params %+v, salt %d bytes, key %d bytes", params, len(salt), len(key))
+	}
+
+	// хеш в формате PHC, посчитанный не нами, проверяется с его собственными параметрами
+	salt = []byte("somesaltsomesalt")
+	external := fmt.Sprintf("$argon2id$v=19$m=2048,t=1,p=2$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("password"), salt, 1, 2048, 2, 24)),
+	)
+	if !hasher.Verify([]byte(external), "password") || hasher.Verify([]byte(external), "Password") {
+		t.Fatal("external PHC hash is not verified with its own params")
+	}
+	// t=1 и ключ 24 байта слабее текущих параметров
+	if !hasher.NeedsRehash([]byte(external)) {
+		t.Fatal("external hash with weaker params does not need rehash")
+	}
+}
+
+// хеши bcrypt, сохраненные до появления Hasher, проверяются при любом текущем алгоритме
+func TestLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		hash := []byte(prefix + string(legacy[4:]))
+		for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+			t.Run(prefix+" "+algorithm, func(t *testing.T) {
+				hasher := newTestHasher(t, algorithm, 5, testArgon2id)
+				if !hasher.Verify(hash, "password") || hasher.Verify(hash, "wrong") {
+					t.Fatal("legacy bcrypt hash is not verified")
+				}
+				if !hasher.NeedsRehash(hash) {
+					t.Fatal("legacy bcrypt hash does not need rehash")
+				}
+			})
+		}
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown algorithm", Config{Algorithm: "scrypt", Argon2id: testArgon2id}},
+		{"bcrypt cost too low", Config{Algorithm: AlgorithmBcrypt, BcryptCost: 3, Argon2id: testArgon2id}},
+		{"bcrypt cost too high", Config{Algorithm: AlgorithmBcrypt, BcryptCost: 32, Argon2id: testArgon2id}},
+		{"argon2id without memory", Config{Algorithm: AlgorithmArgon2id, Argon2id: Argon2idParams{Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}}},
+		{"argon2id short salt", Config{Algorithm: AlgorithmArgon2id, Argon2id: Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32}}},
+		{"argon2id short key", Config{Algorithm: AlgorithmArgon2id, Argon2id: Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Fatal("config accepted")
+			}
+		})
+	}
+}
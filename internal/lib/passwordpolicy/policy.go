@@ -0,0 +1,148 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcrypt использует только первые 72 байта пароля, все, что дальше, молча отбрасывается
+const maxBytes = 72
+
+// Коды нарушений - по ним фронтенд подсвечивает конкретные требования
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeNoUpper       = "no_upper"
+	CodeNoLower       = "no_lower"
+	CodeNoDigit       = "no_digit"
+	CodeNoSymbol      = "no_symbol"
+	CodeContainsLogin = "contains_login"
+	CodeCommon        = "common"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = func() map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, line := range strings.Split(commonPasswordsList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return result
+}()
+
+// Policy - требования к паролю. Длина считается в символах
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DisallowLogin запрещает пароль, содержащий логин или его часть до @
+	DisallowLogin bool
+	// DisallowCommon запрещает пароли из встроенного списка самых распространенных
+	DisallowCommon bool
+}
+
+// Violation - одно невыполненное требование
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error - пароль не прошел проверку, Violations перечисляет все нарушения сразу
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Check возвращает *Error со всеми нарушениями или nil, если пароль подходит
+func (p *Policy) Check(password, login string) error {
+	if violations := p.Validate(password, login); len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+func (p *Policy) Validate(password, login string) []Violation {
+	var violations []Violation
+	add := func(code, message string) {
+		violations = append(violations, Violation{Code: code, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(CodeTooShort, fmt.Sprintf("пароль должен быть не короче %d символов", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(CodeTooLong, fmt.Sprintf("пароль должен быть не длиннее %d символов", p.MaxLength))
+	} else if len(password) > maxBytes {
+		add(CodeTooLong, fmt.Sprintf("пароль не должен занимать больше %d байт", maxBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		add(CodeNoUpper, "пароль должен содержать заглавную букву")
+	}
+	if p.RequireLower && !hasLower {
+		add(CodeNoLower, "пароль должен содержать строчную букву")
+	}
+	if p.RequireDigit && !hasDigit {
+		add(CodeNoDigit, "пароль должен содержать цифру")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add(CodeNoSymbol, "пароль должен содержать спецсимвол")
+	}
+
+	if p.DisallowLogin && containsLogin(password, login) {
+		add(CodeContainsLogin, "пароль не должен содержать логин")
+	}
+	if p.DisallowCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			add(CodeCommon, "пароль слишком распространенный")
+		}
+	}
+
+	return violations
+}
+
+func containsLogin(password, login string) bool {
+	password = strings.ToLower(password)
+	login = strings.ToLower(strings.TrimSpace(login))
+	if login == "" {
+		return false
+	}
+	if strings.Contains(password, login) {
+		return true
+	}
+	// для email проверяем и имя до @ - короткие имена не считаются, иначе запретим слишком много
+	if name, _, ok := strings.Cut(login, "@"); ok && utf8.RuneCountInString(name) >= 3 {
+		return strings.Contains(password, name)
+	}
+	return false
+}
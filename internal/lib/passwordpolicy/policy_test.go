@@ -0,0 +1,124 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) []string {
+	result := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		result = append(result, violation.Code)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	strict := Policy{
+		MinLength:      10,
+		MaxLength:      64,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		DisallowLogin:  true,
+		DisallowCommon: true,
+	}
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		login    string
+		want     []string
+	}{
+		{"strong password", strict, "Tr0ub4dor&3x", "alice@example.com", nil},
+		{"empty policy accepts anything short", Policy{}, "a", "", nil},
+
+		{"shorter than min", Policy{MinLength: 8}, "abc1234", "", []string{CodeTooShort}},
+		{"exactly min", Policy{MinLength: 8}, "abcd1234", "", nil},
+		// длина считается в символах, а не в байтах
+		{"min length in runes", Policy{MinLength: 8}, "пароль1", "", []string{CodeTooShort}},
+		{"longer than max", Policy{MaxLength: 8}, "abcdefghi", "", []string{CodeTooLong}},
+		{"exactly max", Policy{MaxLength: 8}, "abcdefgh", "", nil},
+		{"max length in runes", Policy{MaxLength: 8}, "пароль12", "", nil},
+
+		// bcrypt отбрасывает все после 72 байт - такой пароль запрещен при любом max_length
+		{"72 bytes", Policy{}, strings.Repeat("a", 72), "", nil},
+		{"73 bytes", Policy{}, strings.Repeat("a", 73), "", []string{CodeTooLong}},
+		{"73 bytes within max length", Policy{MaxLength: 100}, strings.Repeat("a", 73), "", []string{CodeTooLong}},
+		{"40 runes in 80 bytes", Policy{MaxLength: 64}, strings.Repeat("я", 40), "", []string{CodeTooLong}},
+		{"above max length and 72 bytes reported once", Policy{MaxLength: 64}, strings.Repeat("a", 80), "", []string{CodeTooLong}},
+
+		{"no upper", Policy{RequireUpper: true}, "abc", "", []string{CodeNoUpper}},
+		{"no lower", Policy{RequireLower: true}, "ABC", "", []string{CodeNoLower}},
+		{"no digit", Policy{RequireDigit: true}, "abc", "", []string{CodeNoDigit}},
+		{"no symbol", Policy{RequireSymbol: true}, "abc123", "", []string{CodeNoSymbol}},
+		{"cyrillic letters count as upper and lower", Policy{RequireUpper: true, RequireLower: true}, "Пароль", "", nil},
+		{"space is a symbol", Policy{RequireSymbol: true}, "abc 123", "", nil},
+		{"all classes missing", Policy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, "", "",
+			[]string{CodeNoUpper, CodeNoLower, CodeNoDigit, CodeNoSymbol}},
+
+		{"contains login", Policy{DisallowLogin: true}, "xalice@example.comx", "alice@example.com", []string{CodeContainsLogin}},
+		{"contains email local part", Policy{DisallowLogin: true}, "Alice2024!", "alice@example.com", []string{CodeContainsLogin}},
+		{"login in other case", Policy{DisallowLogin: true}, "ALICE2024!", " Alice@Example.com ", []string{CodeContainsLogin}},
+		// короткое имя до @ не проверяется, иначе запретили бы слишком много паролей
+		{"short local part", Policy{DisallowLogin: true}, "bob-the-builder", "bo@example.com", nil},
+		{"login without @", Policy{DisallowLogin: true}, "my-manager-pass", "manager", []string{CodeContainsLogin}},
+		{"unrelated login", Policy{DisallowLogin: true}, "Tr0ub4dor&3x", "alice@example.com", nil},
+		{"empty login", Policy{DisallowLogin: true}, "anything", "", nil},
+		{"login check disabled", Policy{}, "alice2024", "alice@example.com", nil},
+
+		{"common password", Policy{DisallowCommon: true}, "password1", "", []string{CodeCommon}},
+		{"common password in other case", Policy{DisallowCommon: true}, "QwErTy", "", []string{CodeCommon}},
+		{"uncommon password", Policy{DisallowCommon: true}, "Tr0ub4dor&3x", "", nil},
+		{"common check disabled", Policy{}, "password1", "", nil},
+
+		{"every violation at once", strict, "alice", "alice@example.com",
+			[]string{CodeTooShort, CodeNoUpper, CodeNoDigit, CodeNoSymbol, CodeContainsLogin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(tt.policy.Validate(tt.password, tt.login))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("violations %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, RequireDigit: true}
+
+	if err := policy.Check("password1", ""); err != nil {
+		t.Fatalf("valid password: %v", err)
+	}
+
+	err := policy.Check("short", "")
+	var policyErr *Error
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if got := codes(policyErr.Violations); !slices.Equal(got, []string{CodeTooShort, CodeNoDigit}) {
+		t.Fatalf("violations %v", got)
+	}
+	// все нарушения попадают в текст ошибки
+	for _, violation := range policyErr.Violations {
+		if !strings.Contains(err.Error(), violation.Message) {
+			t.Fatalf("error %q does not mention %q", err, violation.Message)
+		}
+	}
+}
+
+// встроенный список не пустой и разобран без пробелов и пустых строк
+func TestCommonPasswordsList(t *testing.T) {
+	if len(commonPasswords) < 100 {
+		t.Fatalf("only %d common passwords loaded", len(commonPasswords))
+	}
+	for password := range commonPasswords {
+		if password == "" || password != strings.TrimSpace(password) || password != strings.ToLower(password) {
+			t.Fatalf("malformed entry %q", password)
+		}
+	}
+}
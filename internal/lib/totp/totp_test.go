@@ -0,0 +1,128 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ SHA-1 из приложения B RFC 6238 ("12345678901234567890") в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238, приложение B: восьмизначные коды SHA-1, усеченные до последних шести цифр
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeMatchesRFCVectors(t *testing.T) {
+	for _, tt := range rfcVectors {
+		code, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/Period {
+			t.Errorf("validate at %d: step %d, ok %v, want step %d", tt.unix, step, ok, tt.unix/Period)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"same step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps back", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// код шага now проверяется в момент, сдвинутый на offset шагов
+			at := now.Add(time.Duration(tt.offset*Period) * time.Second)
+			step, ok := Validate(rfcSecret, code, at)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			// возвращается шаг самого кода, а не момента проверки
+			if ok && step != now.Unix()/Period {
+				t.Fatalf("step %d, want %d", step, now.Unix()/Period)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"secret with invalid characters", "GEZDGNBV!Y3TQOJQ", "005924"},
+		{"secret with padding", rfcSecret + "====", "005924"},
+		{"empty code", rfcSecret, ""},
+		{"short code", rfcSecret, "05924"},
+		{"long code", rfcSecret, "89005924"},
+		{"non-digit code", rfcSecret, "00592a"},
+		{"code with spaces", rfcSecret, "005 924"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok {
+				t.Fatal("code accepted")
+			}
+		})
+	}
+
+	if _, err := Code("GEZDGNBV!Y3TQOJQ", now); err == nil {
+		t.Fatal("code generated for a malformed secret")
+	}
+}
+
+// приложения часто показывают секрет строчными буквами
+func TestValidateAcceptsLowercaseSecret(t *testing.T) {
+	if _, ok := Validate(strings.ToLower(rfcSecret), "005924", time.Unix(1234567890, 0)); !ok {
+		t.Fatal("code rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("secret %q decodes to %d bytes, err = %v", secret, len(key), err)
+	}
+
+	code, err := Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Validate(secret, code, time.Now()); !ok {
+		t.Fatal("code of a generated secret rejected")
+	}
+}
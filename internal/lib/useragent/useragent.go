@@ -0,0 +1,65 @@
+package useragent
+
+import "strings"
+
+// marker - подстрока User-Agent и название, которое видит пользователь.
+// Порядок важен: Edge и Opera содержат "Chrome", Chrome содержит "Safari", Android - "Linux"
+type marker struct {
+	token string
+	name  string
+}
+
+var browsers = []marker{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var systems = []marker{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// Device возвращает понятное пользователю описание устройства, например "Chrome, Windows".
+// Для неизвестного User-Agent возвращает его начало, для пустого - пустую строку
+func Device(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+
+	browser := find(browsers, userAgent)
+	system := find(systems, userAgent)
+	switch {
+	case browser != "" && system != "":
+		return browser + ", " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	// приложения и утилиты обычно начинаются с имени/версии: "okhttp/4.12.0", "curl/8.5.0"
+	name, _, _ := strings.Cut(userAgent, " ")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return strings.ToValidUTF8(name, "")
+}
+
+func find(markers []marker, userAgent string) string {
+	for _, m := range markers {
+		if strings.Contains(userAgent, m.token) {
+			return m.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/phenirain/sso/internal/lib/webauthn/webauthntest"
+)
+
+func mustHex(t testing.TB, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want any
+	}{
+		{"small uint", "17", int64(23)},
+		{"uint8", "1818", int64(24)},
+		{"uint16", "190100", int64(256)},
+		{"uint32", "1a00010000", int64(65536)},
+		{"uint64", "1b0000000100000000", int64(1 << 32)},
+		{"negative", "26", int64(-7)},
+		{"negative uint16", "390100", int64(-257)},
+		{"bytes", "43010203", []byte{1, 2, 3}},
+		{"text", "63666d74", "fmt"},
+		{"array", "820102", []any{int64(1), int64(2)}},
+		{"map", "a2616101200a", map[any]any{"a": int64(1), int64(-1): int64(10)}},
+		{"tag", "c11a514b67b0", int64(1363896240)},
+		{"false", "f4", false},
+		{"true", "f5", true},
+		{"null", "f6", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(mustHex(t, tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 0 {
+				t.Fatalf("%d bytes left", len(rest))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORReturnsRest(t *testing.T) {
+	_, rest, err := decodeCBOR(mustHex(t, "0102"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, []byte{0x02}) {
+		t.Fatalf("rest = %x, want 02", rest)
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"uint8 without argument", "18"},
+		{"uint64 truncated", "1b000000"},
+		{"uint above int64", "1bffffffffffffffff"},
+		{"negative below int64", "3bffffffffffffffff"},
+		{"bytes truncated", "4301"},
+		{"text truncated", "6366"},
+		// длина заявлена огромной, а данных нет: декодер не должен выделять под нее память
+		{"bytes with huge length", "5bffffffffffffffff"},
+		{"array with huge length", "9b00000000ffffffff01"},
+		{"map with huge length", "bb00000000ffffffff01"},
+		{"array item missing", "8201"},
+		{"map value missing", "a16161"},
+		{"map with array key", "a1800101"},
+		{"indefinite bytes", "5f"},
+		{"indefinite array", "9f01ff"},
+		{"reserved argument", "1c"},
+		{"undefined simple value", "f8"},
+		{"float", "fa3f800000"},
+		{"tag without value", "c1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(mustHex(t, tt.data)); err == nil {
+				t.Fatal("malformed data accepted")
+			}
+		})
+	}
+}
+
+func TestDecodeCBORLimitsDepth(t *testing.T) {
+	nested := append(bytes.Repeat([]byte{0x81}, maxCBORDepth), 0x01)
+	if _, _, err := decodeCBOR(nested); err != nil {
+		t.Fatalf("depth %d: %v", maxCBORDepth, err)
+	}
+
+	tooDeep := append(bytes.Repeat([]byte{0x81}, maxCBORDepth+1), 0x01)
+	if _, _, err := decodeCBOR(tooDeep); err == nil {
+		t.Fatal("nesting deeper than the limit accepted")
+	}
+}
+
+// Любой обрезанный attestationObject должен отклоняться ошибкой, а не паникой
+func TestVerifyRegistrationRejectsTruncated(t *testing.T) {
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestationObject := authenticator.Register()
+	rp := newTestRelyingParty()
+
+	for i := range len(attestationObject) {
+		if _, err := rp.VerifyRegistration(attestationObject[:i]); err == nil {
+			t.Fatalf("attestationObject truncated to %d bytes accepted", i)
+		}
+	}
+	if _, err := rp.VerifyRegistration(append(attestationObject, 0)); err == nil {
+		t.Fatal("attestationObject with trailing data accepted")
+	}
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(authenticator.Register())
+	f.Add(authenticator.PublicKey())
+	for _, seed := range []string{"a2616101200a", "9b00000000ffffffff01", "c1c1c1c101", "5bffffffffffffffff"} {
+		f.Add(mustHex(f, seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, rest, err := decodeCBOR(data)
+		if err != nil {
+			return
+		}
+		if len(rest) >= len(data) || !bytes.Equal(rest, data[len(data)-len(rest):]) {
+			t.Fatalf("rest is not a proper suffix of the input")
+		}
+	})
+}
+
+func FuzzVerifyRegistration(f *testing.F) {
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(authenticator.Register())
+
+	rp := newTestRelyingParty()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		credential, err := rp.VerifyRegistration(data)
+		if err != nil {
+			return
+		}
+		if _, err := parseCOSEKey(credential.PublicKey); err != nil {
+			t.Fatalf("accepted credential with invalid key: %v", err)
+		}
+	})
+}
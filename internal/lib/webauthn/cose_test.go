@@ -0,0 +1,202 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+// coseEntry - пара ключ-значение COSE_Key в порядке записи
+type coseEntry struct {
+	key   int64
+	value any
+}
+
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= 0xff:
+		return append(buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	}
+}
+
+func appendCBORInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return appendCBORHead(buf, 1, uint64(-1-n))
+	}
+	return appendCBORHead(buf, 0, uint64(n))
+}
+
+func encodeCOSEKey(entries ...coseEntry) []byte {
+	buf := appendCBORHead(nil, 5, uint64(len(entries)))
+	for _, entry := range entries {
+		buf = appendCBORInt(buf, entry.key)
+		switch value := entry.value.(type) {
+		case int64:
+			buf = appendCBORInt(buf, value)
+		case []byte:
+			buf = append(appendCBORHead(buf, 2, uint64(len(value))), value...)
+		}
+	}
+	return buf
+}
+
+func TestParseCOSEKeyVerifiesSignatures(t *testing.T) {
+	message := []byte("authenticator data and client data hash")
+	digest := sha256.Sum256(message)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := ecKey.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       []byte
+		algorithm int64
+		signature []byte
+	}{
+		{"ES256", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeEC2},
+			coseEntry{coseKeyAlgorithm, AlgorithmES256},
+			coseEntry{coseCurve, coseCurveP256},
+			coseEntry{coseX, point.Bytes()[1:33]},
+			coseEntry{coseY, point.Bytes()[33:]},
+		), AlgorithmES256, ecSignature},
+		{"EdDSA", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeOKP},
+			coseEntry{coseKeyAlgorithm, AlgorithmEdDSA},
+			coseEntry{coseCurve, coseCurveEd25519},
+			coseEntry{coseX, []byte(edPublic)},
+		), AlgorithmEdDSA, ed25519.Sign(edPrivate, message)},
+		{"RS256", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeRSA},
+			coseEntry{coseKeyAlgorithm, AlgorithmRS256},
+			coseEntry{coseRSAModulus, rsaKey.N.Bytes()},
+			coseEntry{coseRSAExponent, []byte{1, 0, 1}},
+		), AlgorithmRS256, rsaSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseCOSEKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.algorithm != tt.algorithm {
+				t.Fatalf("algorithm %d, want %d", key.algorithm, tt.algorithm)
+			}
+			if err := key.verify(message, tt.signature); err != nil {
+				t.Fatalf("valid signature rejected: %v", err)
+			}
+			tampered := bytes.Clone(tt.signature)
+			tampered[len(tampered)-1] ^= 0xff
+			if err := key.verify(message, tampered); err == nil {
+				t.Fatal("tampered signature accepted")
+			}
+		})
+	}
+}
+
+func TestParseCOSEKeyRejectsInvalid(t *testing.T) {
+	x := bytes.Repeat([]byte{1}, 32)
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"not a map", appendCBORInt(nil, 1)},
+		{"unsupported algorithm", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeEC2},
+			coseEntry{coseKeyAlgorithm, -35}, // ES384
+		)},
+		{"algorithm of other key type", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeOKP},
+			coseEntry{coseKeyAlgorithm, AlgorithmES256},
+		)},
+		{"P-256 with other curve", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeEC2},
+			coseEntry{coseKeyAlgorithm, AlgorithmES256},
+			coseEntry{coseCurve, 2},
+			coseEntry{coseX, x},
+			coseEntry{coseY, x},
+		)},
+		{"P-256 point not on curve", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeEC2},
+			coseEntry{coseKeyAlgorithm, AlgorithmES256},
+			coseEntry{coseCurve, coseCurveP256},
+			coseEntry{coseX, x},
+			coseEntry{coseY, x},
+		)},
+		{"P-256 short coordinate", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeEC2},
+			coseEntry{coseKeyAlgorithm, AlgorithmES256},
+			coseEntry{coseCurve, coseCurveP256},
+			coseEntry{coseX, x[:31]},
+			coseEntry{coseY, x},
+		)},
+		{"Ed25519 short key", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeOKP},
+			coseEntry{coseKeyAlgorithm, AlgorithmEdDSA},
+			coseEntry{coseCurve, coseCurveEd25519},
+			coseEntry{coseX, x[:31]},
+		)},
+		{"RSA short modulus", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeRSA},
+			coseEntry{coseKeyAlgorithm, AlgorithmRS256},
+			coseEntry{coseRSAModulus, bytes.Repeat([]byte{0xff}, 128)},
+			coseEntry{coseRSAExponent, []byte{1, 0, 1}},
+		)},
+		{"RSA oversized exponent", encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeRSA},
+			coseEntry{coseKeyAlgorithm, AlgorithmRS256},
+			coseEntry{coseRSAModulus, bytes.Repeat([]byte{0xff}, 256)},
+			coseEntry{coseRSAExponent, []byte{1, 0, 0, 0, 1}},
+		)},
+		{"trailing data", append(encodeCOSEKey(
+			coseEntry{coseKeyType, coseKeyTypeOKP},
+			coseEntry{coseKeyAlgorithm, AlgorithmEdDSA},
+			coseEntry{coseCurve, coseCurveEd25519},
+			coseEntry{coseX, x},
+		), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCOSEKey(tt.key); err == nil {
+				t.Fatal("invalid key accepted")
+			}
+		})
+	}
+}
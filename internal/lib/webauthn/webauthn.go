@@ -0,0 +1,214 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Тип церемонии в clientDataJSON
+const (
+	CeremonyCreate = "webauthn.create"
+	CeremonyGet    = "webauthn.get"
+)
+
+const (
+	flagUserPresent  byte = 0x01
+	flagUserVerified byte = 0x04
+	flagAttestedData byte = 0x40
+	flagExtensions   byte = 0x80
+)
+
+var ErrInvalidResponse = errors.New("webauthn: некорректный ответ аутентификатора")
+
+// RelyingParty - наш сервис с точки зрения WebAuthn: rpId (домен) и разрешенные origin фронтенда
+type RelyingParty struct {
+	ID      string
+	Origins []string
+}
+
+// ClientData - разобранный clientDataJSON
+type ClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Credential - учетные данные, созданные аутентификатором при регистрации
+type Credential struct {
+	ID []byte
+	// PublicKey - COSE_Key в исходном CBOR виде, в нем же хранится в базе
+	PublicKey    []byte
+	Algorithm    int64
+	SignCount    uint32
+	UserVerified bool
+}
+
+// Assertion - результат проверки подписи при входе
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+type authenticatorData struct {
+	rpIdHash  []byte
+	flags     byte
+	signCount uint32
+	// заполняются, только если установлен флаг AT
+	credentialId []byte
+	publicKey    []byte
+}
+
+// ParseClientData разбирает clientDataJSON и проверяет тип церемонии и origin.
+// Challenge проверяет вызывающий, сопоставляя его с выданным
+func (rp *RelyingParty) ParseClientData(raw []byte, ceremony string) (*ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("%w: clientDataJSON: %s", ErrInvalidResponse, err.Error())
+	}
+	if clientData.Type != ceremony {
+		return nil, fmt.Errorf("%w: ожидался тип %s", ErrInvalidResponse, ceremony)
+	}
+	if !slices.Contains(rp.Origins, clientData.Origin) {
+		return nil, fmt.Errorf("%w: недопустимый origin %s", ErrInvalidResponse, clientData.Origin)
+	}
+	if clientData.Challenge == "" {
+		return nil, fmt.Errorf("%w: пустой challenge", ErrInvalidResponse)
+	}
+	return &clientData, nil
+}
+
+// VerifyRegistration проверяет attestationObject и извлекает новые учетные данные.
+// Аттестация не проверяется: при регистрации запрашивается attestation=none,
+// и нам важен только ключ, а не модель аутентификатора
+func (rp *RelyingParty) VerifyRegistration(attestationObject []byte) (*Credential, error) {
+	value, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: attestationObject", ErrInvalidResponse)
+	}
+	attestation, ok := value.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestationObject", ErrInvalidResponse)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: нет authData", ErrInvalidResponse)
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: нет данных учетной записи", ErrInvalidResponse)
+	}
+
+	key, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+	}
+
+	return &Credential{
+		ID:           authData.credentialId,
+		PublicKey:    authData.publicKey,
+		Algorithm:    key.algorithm,
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion проверяет подпись аутентификатора при входе: подписываются
+// authenticatorData и SHA-256 от clientDataJSON
+func (rp *RelyingParty) VerifyAssertion(publicKey, rawAuthData, clientDataJSON, signature []byte) (*Assertion, error) {
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+	}
+
+	return &Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// parseAuthenticatorData разбирает authenticatorData (WebAuthn, раздел 6.1)
+// и проверяет rpIdHash и присутствие пользователя
+func (rp *RelyingParty) parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticatorData слишком короткий", ErrInvalidResponse)
+	}
+
+	result := &authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	expected := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(result.rpIdHash, expected[:]) {
+		return nil, fmt.Errorf("%w: rpId не совпадает", ErrInvalidResponse)
+	}
+	if result.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: пользователь не подтвердил действие", ErrInvalidResponse)
+	}
+
+	rest := data[37:]
+	if result.flags&flagAttestedData != 0 {
+		// aaguid (16) + длина id (2) + id + COSE ключ
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: данные учетной записи обрезаны", ErrInvalidResponse)
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: некорректный id учетной записи", ErrInvalidResponse)
+		}
+		result.credentialId = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+		}
+		result.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+	if result.flags&flagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err.Error())
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: лишние данные в authenticatorData", ErrInvalidResponse)
+	}
+	return result, nil
+}
+
+// DecodeBase64URL декодирует поля ответа браузера: base64url, с выравниванием или без
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// EncodeBase64URL - обратное к DecodeBase64URL, формат PublicKeyCredential.toJSON()
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,164 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/phenirain/sso/internal/lib/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "sso.example.com"
+	testOrigin = "https://sso.example.com"
+)
+
+func newTestRelyingParty() *RelyingParty {
+	return &RelyingParty{ID: testRPID, Origins: []string{testOrigin}}
+}
+
+func newTestAuthenticator(t *testing.T) *webauthntest.Authenticator {
+	t.Helper()
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := newTestRelyingParty()
+	authenticator := newTestAuthenticator(t)
+
+	credential, err := rp.VerifyRegistration(authenticator.Register())
+	if err != nil {
+		t.Fatalf("registration: %v", err)
+	}
+	if !bytes.Equal(credential.ID, authenticator.CredentialID) {
+		t.Fatal("credential id does not match")
+	}
+	if credential.Algorithm != AlgorithmES256 || !credential.UserVerified {
+		t.Fatalf("unexpected credential: alg=%d uv=%v", credential.Algorithm, credential.UserVerified)
+	}
+
+	for want := uint32(1); want <= 2; want++ {
+		clientData := webauthntest.ClientDataJSON(CeremonyGet, "challenge", testOrigin)
+		authData, signature, err := authenticator.Assert(clientData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertion, err := rp.VerifyAssertion(credential.PublicKey, authData, clientData, signature)
+		if err != nil {
+			t.Fatalf("assertion: %v", err)
+		}
+		if assertion.SignCount != want {
+			t.Fatalf("sign count %d, want %d", assertion.SignCount, want)
+		}
+	}
+}
+
+func TestParseClientData(t *testing.T) {
+	rp := newTestRelyingParty()
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr bool
+	}{
+		{"valid", webauthntest.ClientDataJSON(CeremonyGet, "challenge", testOrigin), false},
+		{"wrong origin", webauthntest.ClientDataJSON(CeremonyGet, "challenge", "https://evil.example.com"), true},
+		{"origin of subdomain", webauthntest.ClientDataJSON(CeremonyGet, "challenge", "https://app.sso.example.com"), true},
+		{"registration ceremony", webauthntest.ClientDataJSON(CeremonyCreate, "challenge", testOrigin), true},
+		{"empty challenge", webauthntest.ClientDataJSON(CeremonyGet, "", testOrigin), true},
+		{"not json", []byte("{"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rp.ParseClientData(tt.raw, CeremonyGet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("err = %v, want ErrInvalidResponse", err)
+			}
+		})
+	}
+}
+
+func TestRegistrationRejectsOtherRPID(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	authenticator.RPID = "evil.example.com"
+
+	if _, err := newTestRelyingParty().VerifyRegistration(authenticator.Register()); !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestAssertionRejectsInvalidResponses(t *testing.T) {
+	rp := newTestRelyingParty()
+	authenticator := newTestAuthenticator(t)
+	credential, err := rp.VerifyRegistration(authenticator.Register())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientData := webauthntest.ClientDataJSON(CeremonyGet, "challenge", testOrigin)
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T) (authData, clientData, signature []byte)
+	}{
+		{"other rpId", func(t *testing.T) ([]byte, []byte, []byte) {
+			authenticator.RPID = "evil.example.com"
+			defer func() { authenticator.RPID = testRPID }()
+			authData, signature, err := authenticator.Assert(clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData, clientData, signature
+		}},
+		{"signed other client data", func(t *testing.T) ([]byte, []byte, []byte) {
+			authData, signature, err := authenticator.Assert(clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData, webauthntest.ClientDataJSON(CeremonyGet, "other", testOrigin), signature
+		}},
+		{"user not present", func(t *testing.T) ([]byte, []byte, []byte) {
+			authData := authenticator.AuthenticatorData(0, nil)
+			signature, err := authenticator.Sign(authData, clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData, clientData, signature
+		}},
+		{"trailing data", func(t *testing.T) ([]byte, []byte, []byte) {
+			authData := append(authenticator.AuthenticatorData(0x01, nil), 0)
+			signature, err := authenticator.Sign(authData, clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData, clientData, signature
+		}},
+		{"truncated", func(t *testing.T) ([]byte, []byte, []byte) {
+			authData, signature, err := authenticator.Assert(clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData[:36], clientData, signature
+		}},
+		{"other key", func(t *testing.T) ([]byte, []byte, []byte) {
+			authData, signature, err := newTestAuthenticator(t).Assert(clientData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authData, clientData, signature
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authData, clientData, signature := tt.assert(t)
+			if _, err := rp.VerifyAssertion(credential.PublicKey, authData, clientData, signature); !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("err = %v, want ErrInvalidResponse", err)
+			}
+		})
+	}
+}
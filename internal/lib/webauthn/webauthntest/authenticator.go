@@ -0,0 +1,160 @@
+// Package webauthntest - программный аутентификатор для тестов церемоний WebAuthn:
+// создает ключ ES256, отдает attestationObject с форматом none и подписывает вход
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+)
+
+const (
+	flagUserPresent  byte = 0x01
+	flagUserVerified byte = 0x04
+	flagAttestedData byte = 0x40
+)
+
+// Authenticator - passkey с одной учетной записью. RPID можно подменить, чтобы получить
+// ответ для чужого сайта; SignCount растет с каждой подписью, его можно откатить.
+// SkipUserVerification снимает флаг UV - как ключ без PIN-кода и биометрии
+type Authenticator struct {
+	RPID                 string
+	CredentialID         []byte
+	SignCount            uint32
+	SkipUserVerification bool
+
+	key *ecdsa.PrivateKey
+}
+
+func NewAuthenticator(rpId string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credentialId := make([]byte, 16)
+	if _, err := rand.Read(credentialId); err != nil {
+		return nil, err
+	}
+	return &Authenticator{RPID: rpId, CredentialID: credentialId, key: key}, nil
+}
+
+// ClientDataJSON собирает clientDataJSON так, как его формирует браузер
+func ClientDataJSON(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	return data
+}
+
+// Register возвращает attestationObject новой учетной записи (формат none)
+func (a *Authenticator) Register() []byte {
+	// aaguid (16 нулей) + длина id + id + COSE ключ
+	attested := make([]byte, 16, 16+2+len(a.CredentialID))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.CredentialID)))
+	attested = append(attested, a.CredentialID...)
+	attested = append(attested, a.PublicKey()...)
+
+	authData := a.AuthenticatorData(a.flags()|flagAttestedData, attested)
+
+	var buf []byte
+	buf = appendHead(buf, 5, 3)
+	buf = appendText(buf, "fmt")
+	buf = appendText(buf, "none")
+	buf = appendText(buf, "attStmt")
+	buf = appendHead(buf, 5, 0)
+	buf = appendText(buf, "authData")
+	buf = appendBytes(buf, authData)
+	return buf
+}
+
+// Assert увеличивает счетчик и подписывает authenticatorData вместе с хешем clientDataJSON
+func (a *Authenticator) Assert(clientDataJSON []byte) (authData, signature []byte, err error) {
+	a.SignCount++
+	authData = a.AuthenticatorData(a.flags(), nil)
+	signature, err = a.Sign(authData, clientDataJSON)
+	return authData, signature, err
+}
+
+func (a *Authenticator) flags() byte {
+	if a.SkipUserVerification {
+		return flagUserPresent
+	}
+	return flagUserPresent | flagUserVerified
+}
+
+// Sign подписывает произвольные authenticatorData - для ответов с испорченными полями
+func (a *Authenticator) Sign(authData, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	return ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+}
+
+// AuthenticatorData собирает authenticatorData с текущими RPID и SignCount
+func (a *Authenticator) AuthenticatorData(flags byte, attested []byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.RPID))
+	data := append([]byte(nil), rpIdHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	return append(data, attested...)
+}
+
+// PublicKey возвращает открытый ключ в формате COSE_Key
+func (a *Authenticator) PublicKey() []byte {
+	point, _ := a.key.PublicKey.ECDH()
+	raw := point.Bytes() // 0x04 || x || y
+
+	var buf []byte
+	buf = appendHead(buf, 5, 5)
+	buf = appendInt(buf, 1) // kty: EC2
+	buf = appendInt(buf, 2)
+	buf = appendInt(buf, 3) // alg: ES256
+	buf = appendInt(buf, -7)
+	buf = appendInt(buf, -1) // crv: P-256
+	buf = appendInt(buf, 1)
+	buf = appendInt(buf, -2) // x
+	buf = appendBytes(buf, raw[1:33])
+	buf = appendInt(buf, -3) // y
+	buf = appendBytes(buf, raw[33:])
+	return buf
+}
+
+// EncodeBase64URL - формат полей PublicKeyCredential.toJSON()
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= 0xff:
+		return append(buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+	}
+}
+
+func appendInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return appendHead(buf, 1, uint64(-1-n))
+	}
+	return appendHead(buf, 0, uint64(n))
+}
+
+func appendBytes(buf, data []byte) []byte {
+	return append(appendHead(buf, 2, uint64(len(data))), data...)
+}
+
+func appendText(buf []byte, text string) []byte {
+	return append(appendHead(buf, 3, uint64(len(text))), text...)
+}
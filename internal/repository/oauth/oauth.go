@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type OAuthRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+func (r *OAuthRepository) CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error {
+	const op = "OAuth.CreateAuthorizationCode"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce,
+			code_challenge, code_challenge_method, created_at, expires_at)
+		VALUES (:code_hash, :client_id, :user_id, :redirect_uri, :scope, :nonce,
+			:code_challenge, :code_challenge_method, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, code); err != nil {
+		log.Error("failed to insert authorization code", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode атомарно удаляет и возвращает код - второй обмен того же кода невозможен
+func (r *OAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	const op = "OAuth.ConsumeAuthorizationCode"
+	log := slog.With(slog.String("op", op))
+
+	var code domain.AuthorizationCode
+	err := r.db.GetContext(ctx, &code, "DELETE FROM oauth_authorization_codes WHERE code_hash = $1 RETURNING *", codeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &code, nil
+}
+
+func (r *OAuthRepository) GetClient(ctx context.Context, clientId string) (*domain.OAuthClient, error) {
+	const op = "OAuth.GetClient"
+	log := slog.With(slog.String("op", op))
+
+	var client domain.OAuthClient
+	err := r.db.GetContext(ctx, &client, "SELECT * FROM oauth_clients WHERE id = $1", clientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &client, nil
+}
+
+func (r *OAuthRepository) GetClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	const op = "OAuth.GetClients"
+	log := slog.With(slog.String("op", op))
+
+	var clients []domain.OAuthClient
+	if err := r.db.SelectContext(ctx, &clients, "SELECT * FROM oauth_clients ORDER BY id"); err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return clients, nil
+}
+
+// SaveClient создает клиента или обновляет существующего с тем же id
+func (r *OAuthRepository) SaveClient(ctx context.Context, client *domain.OAuthClient) error {
+	const op = "OAuth.SaveClient"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, scopes, grant_types, is_public,
+			creation_datetime, update_datetime)
+		VALUES (:id, :name, :secret_hash, :redirect_uris, :scopes, :grant_types, :is_public,
+			:creation_datetime, :update_datetime)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			secret_hash = EXCLUDED.secret_hash,
+			redirect_uris = EXCLUDED.redirect_uris,
+			scopes = EXCLUDED.scopes,
+			grant_types = EXCLUDED.grant_types,
+			is_public = EXCLUDED.is_public,
+			update_datetime = NOW()
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, client); err != nil {
+		log.Error("failed to save oauth client", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *OAuthRepository) DeleteClient(ctx context.Context, clientId string) error {
+	const op = "OAuth.DeleteClient"
+	log := slog.With(slog.String("op", op))
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM oauth_clients WHERE id = $1", clientId)
+	if err != nil {
+		log.Error("failed to delete oauth client", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: client not found", op)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package signingkey
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+)
+
+type SigningKeyRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// GetSigningKeys возвращает все ключи, начиная с самого нового
+func (r *SigningKeyRepository) GetSigningKeys(ctx context.Context) ([]domain.SigningKey, error) {
+	const op = "SigningKey.GetSigningKeys"
+	log := slog.With(slog.String("op", op))
+
+	var keys []domain.SigningKey
+	if err := r.db.SelectContext(ctx, &keys, "SELECT * FROM signing_keys ORDER BY created_at DESC"); err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (r *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *domain.SigningKey) error {
+	const op = "SigningKey.CreateSigningKey"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO signing_keys (id, algorithm, private_key, sealed, created_at, retired_at)
+		VALUES (:id, :algorithm, :private_key, :sealed, :created_at, :retired_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, key); err != nil {
+		log.Error("failed to insert signing key", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RetireSigningKeys выводит из оборота все действующие ключи, кроме exceptId
+func (r *SigningKeyRepository) RetireSigningKeys(ctx context.Context, exceptId string) error {
+	const op = "SigningKey.RetireSigningKeys"
+	log := slog.With(slog.String("op", op))
+
+	const query = `UPDATE signing_keys SET retired_at = NOW() WHERE retired_at IS NULL AND id <> $1`
+	if _, err := r.db.ExecContext(ctx, query, exceptId); err != nil {
+		log.Error("failed to retire signing keys", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *SigningKeyRepository) DeleteSigningKey(ctx context.Context, id string) error {
+	const op = "SigningKey.DeleteSigningKey"
+	log := slog.With(slog.String("op", op))
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM signing_keys WHERE id = $1", id); err != nil {
+		log.Error("failed to delete signing key", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// SealSigningKey заменяет открыто сохраненный приватный ключ зашифрованным
+func (r *SigningKeyRepository) SealSigningKey(ctx context.Context, id string, sealedKey []byte) error {
+	const op = "SigningKey.SealSigningKey"
+	log := slog.With(slog.String("op", op))
+
+	const query = `UPDATE signing_keys SET private_key = $2, sealed = TRUE WHERE id = $1 AND NOT sealed`
+	if _, err := r.db.ExecContext(ctx, query, id, sealedKey); err != nil {
+		log.Error("failed to seal signing key", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+func (u *UserRepository) CreateExternalAuthState(ctx context.Context, state *domain.ExternalAuthState) error {
+	const op = "User.CreateExternalAuthState"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO external_auth_states (state_hash, provider, nonce, code_verifier, created_at, expires_at)
+		VALUES (:state_hash, :provider, :nonce, :code_verifier, :created_at, :expires_at)
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, state); err != nil {
+		log.Error("failed to insert external auth state", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ConsumeExternalAuthState атомарно удаляет и возвращает state - код провайдера нельзя обменять дважды
+func (u *UserRepository) ConsumeExternalAuthState(ctx context.Context, stateHash string) (*domain.ExternalAuthState, error) {
+	const op = "User.ConsumeExternalAuthState"
+	log := slog.With(slog.String("op", op))
+
+	var state domain.ExternalAuthState
+	err := u.db.GetContext(ctx, &state, "DELETE FROM external_auth_states WHERE state_hash = $1 RETURNING *", stateHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &state, nil
+}
+
+func (u *UserRepository) GetExternalIdentity(ctx context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	const op = "User.GetExternalIdentity"
+	log := slog.With(slog.String("op", op))
+
+	var identity domain.ExternalIdentity
+	err := u.db.GetContext(ctx, &identity,
+		"SELECT * FROM external_identities WHERE provider = $1 AND subject = $2", provider, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &identity, nil
+}
+
+// SaveExternalIdentity привязывает учетную запись провайдера к пользователю или отмечает вход через уже привязанную
+func (u *UserRepository) SaveExternalIdentity(ctx context.Context, identity *domain.ExternalIdentity) error {
+	const op = "User.SaveExternalIdentity"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO external_identities (provider, subject, user_id, email, created_at, last_login_at)
+		VALUES (:provider, :subject, :user_id, :email, :created_at, :last_login_at)
+		ON CONFLICT (provider, subject) DO UPDATE SET last_login_at = EXCLUDED.last_login_at
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, identity); err != nil {
+		log.Error("failed to save external identity", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
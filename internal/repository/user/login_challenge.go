@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+// CreateLoginChallenge сохраняет незавершенный вход и заодно удаляет истекшие записи пользователя
+func (u *UserRepository) CreateLoginChallenge(ctx context.Context, challenge *domain.LoginChallenge) error {
+	const op = "User.CreateLoginChallenge"
+	log := slog.With(slog.String("op", op))
+
+	_, err := u.db.ExecContext(ctx,
+		"DELETE FROM login_challenges WHERE user_id = $1 AND expires_at < NOW()", challenge.UserId)
+	if err != nil {
+		log.Error("failed to delete expired login challenges", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	const query = `
+		INSERT INTO login_challenges (id, user_id, purpose, attempts, created_at, expires_at)
+		VALUES (:id, :user_id, :purpose, :attempts, :created_at, :expires_at)
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, challenge); err != nil {
+		log.Error("failed to insert login challenge", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseLoginChallenge атомарно засчитывает попытку по незавершенному входу. Возвращает nil, если
+// записи нет, она истекла или попытки исчерпаны
+func (u *UserRepository) UseLoginChallenge(ctx context.Context, id, purpose string, maxAttempts int) (*domain.LoginChallenge, error) {
+	const op = "User.UseLoginChallenge"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND purpose = $2 AND attempts < $3 AND expires_at > NOW()
+		RETURNING *
+	`
+	var challenge domain.LoginChallenge
+	if err := u.db.GetContext(ctx, &challenge, query, id, purpose, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &challenge, nil
+}
+
+// DeleteLoginChallenge завершает незавершенный вход. false - запись уже удалена параллельным запросом
+func (u *UserRepository) DeleteLoginChallenge(ctx context.Context, id string) (bool, error) {
+	const op = "User.DeleteLoginChallenge"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, "DELETE FROM login_challenges WHERE id = $1", id)
+	if err != nil {
+		log.Error("failed to delete login challenge", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/pkg/database"
+)
+
+// CreateMagicLinkToken сохраняет новую ссылку для входа, если с момента since пользователь
+// запросил меньше limit ссылок. Возвращает false, если лимит исчерпан.
+// Заодно удаляет записи, вышедшие за окно лимита
+func (u *UserRepository) CreateMagicLinkToken(ctx context.Context, token *domain.MagicLinkToken, since time.Time, limit int) (bool, error) {
+	const op = "User.CreateMagicLinkToken"
+	log := slog.With(slog.String("op", op))
+
+	created, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (bool, error) {
+		// блокируем пользователя, чтобы параллельные запросы не обошли лимит
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", token.UserId); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx,
+			"DELETE FROM magic_link_tokens WHERE user_id = $1 AND created_at < $2 AND expires_at < NOW()",
+			token.UserId, since)
+		if err != nil {
+			return false, err
+		}
+
+		var count int
+		err = tx.GetContext(ctx, &count,
+			"SELECT COUNT(*) FROM magic_link_tokens WHERE user_id = $1 AND created_at >= $2",
+			token.UserId, since)
+		if err != nil {
+			return false, err
+		}
+		if count >= limit {
+			return false, nil
+		}
+
+		const query = `
+			INSERT INTO magic_link_tokens (token_hash, user_id, created_at, expires_at)
+			VALUES (:token_hash, :user_id, :created_at, :expires_at)
+		`
+		if _, err := tx.NamedExecContext(ctx, query, token); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		log.Error("failed to insert magic link token", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return created, nil
+}
+
+// ConsumeMagicLinkToken помечает ссылку использованной и возвращает ее.
+// Уже использованная ссылка не находится
+func (u *UserRepository) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (*domain.MagicLinkToken, error) {
+	const op = "User.ConsumeMagicLinkToken"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		UPDATE magic_link_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING *
+	`
+	var token domain.MagicLinkToken
+	err := u.db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
@@ -0,0 +1,128 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+func (u *UserRepository) CreateWebAuthnChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error {
+	const op = "User.CreateWebAuthnChallenge"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO webauthn_challenges (challenge_hash, user_id, ceremony, created_at, expires_at)
+		VALUES (:challenge_hash, :user_id, :ceremony, :created_at, :expires_at)
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, challenge); err != nil {
+		log.Error("failed to insert webauthn challenge", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ConsumeWebAuthnChallenge атомарно удаляет и возвращает challenge - ответ аутентификатора нельзя предъявить дважды
+func (u *UserRepository) ConsumeWebAuthnChallenge(ctx context.Context, challengeHash string) (*domain.WebAuthnChallenge, error) {
+	const op = "User.ConsumeWebAuthnChallenge"
+	log := slog.With(slog.String("op", op))
+
+	var challenge domain.WebAuthnChallenge
+	err := u.db.GetContext(ctx, &challenge, "DELETE FROM webauthn_challenges WHERE challenge_hash = $1 RETURNING *", challengeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &challenge, nil
+}
+
+func (u *UserRepository) CreatePasskey(ctx context.Context, passkey *domain.Passkey) error {
+	const op = "User.CreatePasskey"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO passkeys (id, user_id, public_key, sign_count, transports, name, created_at)
+		VALUES (:id, :user_id, :public_key, :sign_count, :transports, :name, :created_at)
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, passkey); err != nil {
+		log.Error("failed to insert passkey", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetPasskey(ctx context.Context, id string) (*domain.Passkey, error) {
+	const op = "User.GetPasskey"
+	log := slog.With(slog.String("op", op))
+
+	var passkey domain.Passkey
+	err := u.db.GetContext(ctx, &passkey, "SELECT * FROM passkeys WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &passkey, nil
+}
+
+func (u *UserRepository) GetUserPasskeys(ctx context.Context, userId int64) ([]domain.Passkey, error) {
+	const op = "User.GetUserPasskeys"
+	log := slog.With(slog.String("op", op))
+
+	var passkeys []domain.Passkey
+	err := u.db.SelectContext(ctx, &passkeys, "SELECT * FROM passkeys WHERE user_id = $1 ORDER BY created_at", userId)
+	if err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return passkeys, nil
+}
+
+// UpdatePasskeyUsage сохраняет новый счетчик подписей и время входа, только если счетчик вырос
+// (или ключ без счетчика и оба значения 0). Проверка в запросе не дает двум одновременным входам
+// клона ключа пройти с одним счетчиком. Возвращает false, если счетчик не вырос
+func (u *UserRepository) UpdatePasskeyUsage(ctx context.Context, id string, signCount int64) (bool, error) {
+	const op = "User.UpdatePasskeyUsage"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, `UPDATE passkeys SET sign_count = $1, last_used_at = NOW()
+		WHERE id = $2 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))`, signCount, id)
+	if err != nil {
+		log.Error("failed to update passkey", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// DeletePasskey удаляет passkey пользователя. Возвращает false, если такого у пользователя нет
+func (u *UserRepository) DeletePasskey(ctx context.Context, userId int64, id string) (bool, error) {
+	const op = "User.DeletePasskey"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, "DELETE FROM passkeys WHERE id = $1 AND user_id = $2", id, userId)
+	if err != nil {
+		log.Error("failed to delete passkey", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return rowsAffected == 1, nil
+}
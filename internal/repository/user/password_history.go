@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+// passwordHistoryLimit - сколько последних паролей хранится на пользователя
+const passwordHistoryLimit = 24
+
+// GetPasswordHistory возвращает limit последних паролей пользователя, новые первыми
+func (u *UserRepository) GetPasswordHistory(ctx context.Context, userId int64, limit int) ([]domain.PasswordHistoryEntry, error) {
+	const op = "User.GetPasswordHistory"
+	log := slog.With(slog.String("op", op))
+
+	history := []domain.PasswordHistoryEntry{}
+	err := u.db.SelectContext(ctx, &history,
+		"SELECT * FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2",
+		userId, limit)
+	if err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return history, nil
+}
@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/pkg/database"
+)
+
+// CreatePasswordResetToken сохраняет новый токен сброса пароля, удаляя все ранее выданные
+// токены пользователя: действительна только последняя ссылка из письма
+func (u *UserRepository) CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetToken) error {
+	const op = "User.CreatePasswordResetToken"
+	log := slog.With(slog.String("op", op))
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = $1", token.UserId); err != nil {
+			return struct{}{}, err
+		}
+
+		const query = `
+			INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at)
+			VALUES (:token_hash, :user_id, :created_at, :expires_at)
+		`
+		_, err := tx.NamedExecContext(ctx, query, token)
+		return struct{}{}, err
+	})
+	if err != nil {
+		log.Error("failed to insert password reset token", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetPasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	const op = "User.GetPasswordResetToken"
+	log := slog.With(slog.String("op", op))
+
+	var token domain.PasswordResetToken
+	err := u.db.GetContext(ctx, &token, "SELECT * FROM password_reset_tokens WHERE token_hash = $1", tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
+
+// ConsumePasswordResetToken атомарно удаляет и возвращает токен - повторно воспользоваться ссылкой нельзя
+func (u *UserRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	const op = "User.ConsumePasswordResetToken"
+	log := slog.With(slog.String("op", op))
+
+	var token domain.PasswordResetToken
+	err := u.db.GetContext(ctx, &token, "DELETE FROM password_reset_tokens WHERE token_hash = $1 RETURNING *", tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/pkg/database"
+)
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новым набором
+func (u *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []domain.RecoveryCode) error {
+	const op = "User.ReplaceRecoveryCodes"
+	log := slog.With(slog.String("op", op))
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+			return struct{}{}, err
+		}
+		if len(codes) == 0 {
+			return struct{}{}, nil
+		}
+
+		const query = `
+			INSERT INTO recovery_codes (user_id, code_hash, lookup, created_at)
+			VALUES (:user_id, :code_hash, :lookup, :created_at)
+		`
+		_, err := tx.NamedExecContext(ctx, query, codes)
+		return struct{}{}, err
+	})
+	if err != nil {
+		log.Error("failed to replace recovery codes", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetUnusedRecoveryCodes(ctx context.Context, userId int64) ([]domain.RecoveryCode, error) {
+	const op = "User.GetUnusedRecoveryCodes"
+	log := slog.With(slog.String("op", op))
+
+	var codes []domain.RecoveryCode
+	err := u.db.SelectContext(ctx, &codes, "SELECT * FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userId)
+	if err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode помечает код использованным. Возвращает false, если его уже использовали
+func (u *UserRepository) UseRecoveryCode(ctx context.Context, id int64) (bool, error) {
+	const op = "User.UseRecoveryCode"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, "UPDATE recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		log.Error("failed to use recovery code", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,128 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/pkg/database"
+)
+
+func (u *UserRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	const op = "User.CreateRefreshToken"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO refresh_tokens (id, user_id, family, issued_at, expires_at, revoked, access_token_id, client_id)
+		VALUES (:id, :user_id, :family, :issued_at, :expires_at, :revoked, :access_token_id, :client_id)
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, token); err != nil {
+		log.Error("failed to insert refresh token", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error) {
+	const op = "User.GetRefreshToken"
+	log := slog.With(slog.String("op", op))
+
+	var token domain.RefreshToken
+	err := u.db.GetContext(ctx, &token, "SELECT * FROM refresh_tokens WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken помечает токен использованным. Возвращает false,
+// если токен уже был отозван ранее - это признак повторного использования.
+func (u *UserRepository) RevokeRefreshToken(ctx context.Context, id string) (bool, error) {
+	const op = "User.RevokeRefreshToken"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE id = $1 AND NOT revoked", id)
+	if err != nil {
+		log.Error("failed to revoke refresh token", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeRefreshTokenFamily отзывает все токены семейства вместе с сессией
+func (u *UserRepository) RevokeRefreshTokenFamily(ctx context.Context, family string) error {
+	const op = "User.RevokeRefreshTokenFamily"
+	log := slog.With(slog.String("op", op))
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE family = $1", family); err != nil {
+			return struct{}{}, err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked = TRUE WHERE id = $1", family)
+		return struct{}{}, err
+	})
+	if err != nil {
+		log.Error("failed to revoke refresh token family", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Warn("refresh token family revoked", "family", family)
+	return nil
+}
+
+func (u *UserRepository) GetRefreshTokensByFamily(ctx context.Context, family string) ([]domain.RefreshToken, error) {
+	const op = "User.GetRefreshTokensByFamily"
+	log := slog.With(slog.String("op", op))
+
+	var tokens []domain.RefreshToken
+	if err := u.db.SelectContext(ctx, &tokens, "SELECT * FROM refresh_tokens WHERE family = $1", family); err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, nil
+}
+
+func (u *UserRepository) GetRefreshTokensByUser(ctx context.Context, userId int64) ([]domain.RefreshToken, error) {
+	const op = "User.GetRefreshTokensByUser"
+	log := slog.With(slog.String("op", op))
+
+	var tokens []domain.RefreshToken
+	if err := u.db.SelectContext(ctx, &tokens, "SELECT * FROM refresh_tokens WHERE user_id = $1", userId); err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, nil
+}
+
+// RevokeUserRefreshTokens отзывает все токены и сессии пользователя
+func (u *UserRepository) RevokeUserRefreshTokens(ctx context.Context, userId int64) error {
+	const op = "User.RevokeUserRefreshTokens"
+	log := slog.With(slog.String("op", op))
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1", userId); err != nil {
+			return struct{}{}, err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked = TRUE WHERE user_id = $1", userId)
+		return struct{}{}, err
+	})
+	if err != nil {
+		log.Error("failed to revoke user refresh tokens", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("all refresh tokens of user revoked", "userId", userId)
+	return nil
+}
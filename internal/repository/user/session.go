@@ -0,0 +1,66 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/phenirain/sso/internal/domain"
+)
+
+// SaveSession создает сессию при входе или обновляет ее при ротации refresh токена.
+// Время начала сессии при обновлении не меняется
+func (u *UserRepository) SaveSession(ctx context.Context, session *domain.Session) error {
+	const op = "User.SaveSession"
+	log := slog.With(slog.String("op", op))
+
+	const query = `
+		INSERT INTO sessions (id, user_id, client_id, user_agent, ip, device, created_at, last_seen_at, expires_at, revoked)
+		VALUES (:id, :user_id, :client_id, :user_agent, :ip, :device, :created_at, :last_seen_at, :expires_at, :revoked)
+		ON CONFLICT (id) DO UPDATE SET
+			user_agent = EXCLUDED.user_agent,
+			ip = EXCLUDED.ip,
+			device = EXCLUDED.device,
+			last_seen_at = EXCLUDED.last_seen_at,
+			expires_at = EXCLUDED.expires_at
+	`
+	if _, err := u.db.NamedExecContext(ctx, query, session); err != nil {
+		log.Error("failed to save session", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (u *UserRepository) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	const op = "User.GetSession"
+	log := slog.With(slog.String("op", op))
+
+	var session domain.Session
+	err := u.db.GetContext(ctx, &session, "SELECT * FROM sessions WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &session, nil
+}
+
+// GetActiveSessions возвращает не отозванные и не истекшие сессии пользователя, последние активные первыми
+func (u *UserRepository) GetActiveSessions(ctx context.Context, userId int64) ([]domain.Session, error) {
+	const op = "User.GetActiveSessions"
+	log := slog.With(slog.String("op", op))
+
+	sessions := []domain.Session{}
+	err := u.db.SelectContext(ctx, &sessions,
+		"SELECT * FROM sessions WHERE user_id = $1 AND NOT revoked AND expires_at > NOW() ORDER BY last_seen_at DESC",
+		userId)
+	if err != nil {
+		log.Error("something went wrong", "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sessions, nil
+}
@@ -93,19 +93,84 @@ func (u *UserRepository) CreateUser(ctx context.Context, user *domain.User) (int
 	return result, nil
 }
 
+// UpdatePassword меняет пароль и записывает его в историю паролей пользователя
 func (u *UserRepository) UpdatePassword(ctx context.Context, login, newPasswordHash string) error {
 	const op = "User.UpdatePassword"
 	log := slog.With(slog.String("op", op))
 
 	log.Info("attempting to update password for user", "login", login)
 
-	query := `UPDATE users SET password = $1, update_datetime = NOW() WHERE login = $2`
-	result, err := u.db.ExecContext(ctx, query, newPasswordHash, login)
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		var userId int64
+		query := `UPDATE users SET password = $1, update_datetime = NOW(), password_changed_at = NOW() WHERE login = $2 RETURNING id`
+		if err := tx.GetContext(ctx, &userId, query, newPasswordHash, login); err != nil {
+			return struct{}{}, err
+		}
+
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, NOW())",
+			userId, newPasswordHash)
+		if err != nil {
+			return struct{}{}, err
+		}
+
+		// старше passwordHistoryLimit записей история не нужна ни при каких настройках
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM password_history
+			WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+			)`, userId, passwordHistoryLimit)
+		return struct{}{}, err
+	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: user not found", op)
+		}
 		log.Error("failed to update password", "err", err)
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	log.Info("password updated successfully", "login", login)
+	return nil
+}
+
+// RehashPassword заменяет хеш пароля на пересчитанный с текущими параметрами. Пароль при этом
+// не меняется: история паролей и дата смены пароля не трогаются. Если пароль успели сменить
+// (хеш уже не oldPasswordHash), ничего не делает
+func (u *UserRepository) RehashPassword(ctx context.Context, userId int64, oldPasswordHash, newPasswordHash string) error {
+	const op = "User.RehashPassword"
+	log := slog.With(slog.String("op", op))
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		// настройку читает триггер set_password_changed_at, действует до конца транзакции
+		if _, err := tx.ExecContext(ctx, "SET LOCAL sso.password_rehash = 'on'"); err != nil {
+			return struct{}{}, err
+		}
+		_, err := tx.ExecContext(ctx,
+			"UPDATE users SET password = $1 WHERE id = $2 AND password = $3",
+			newPasswordHash, userId, oldPasswordHash)
+		return struct{}{}, err
+	})
+	if err != nil {
+		log.Error("failed to rehash password", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password rehashed", "userId", userId)
+	return nil
+}
+
+func (u *UserRepository) SetEmailVerified(ctx context.Context, userId int64) error {
+	const op = "User.SetEmailVerified"
+	log := slog.With(slog.String("op", op))
+
+	query := `UPDATE users SET email_verified = TRUE, update_datetime = NOW() WHERE id = $1`
+	result, err := u.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		log.Error("failed to verify email", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Error("failed to get rows affected", "err", err)
@@ -116,6 +181,79 @@ func (u *UserRepository) UpdatePassword(ctx context.Context, login, newPasswordH
 		return fmt.Errorf("%s: user not found", op)
 	}
 
-	log.Info("password updated successfully", "login", login)
+	log.Info("email verified", "userId", userId)
+	return nil
+}
+
+// UpdateUserRole меняет роль пользователя, например по группам корпоративного каталога
+func (u *UserRepository) UpdateUserRole(ctx context.Context, userId, roleId int64) error {
+	const op = "User.UpdateUserRole"
+	log := slog.With(slog.String("op", op))
+
+	query := `UPDATE users SET role_id = $2, update_datetime = NOW() WHERE id = $1`
+	result, err := u.db.ExecContext(ctx, query, userId, roleId)
+	if err != nil {
+		log.Error("failed to update role", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: user not found", op)
+	}
+
+	log.Info("role updated", "userId", userId, "roleId", roleId)
+	return nil
+}
+
+// SetTotpSecret сохраняет новый (еще не подтвержденный) секрет TOTP. Двухфакторная
+// аутентификация выключается до подтверждения кодом
+func (u *UserRepository) SetTotpSecret(ctx context.Context, userId int64, secret []byte) error {
+	const op = "User.SetTotpSecret"
+	log := slog.With(slog.String("op", op))
+
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = FALSE, totp_last_step = 0, update_datetime = NOW() WHERE id = $2`
+	if _, err := u.db.ExecContext(ctx, query, secret, userId); err != nil {
+		log.Error("failed to set totp secret", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	return nil
 }
+
+// EnableTotp включает двухфакторную аутентификацию, запоминая шаг кода подтверждения
+func (u *UserRepository) EnableTotp(ctx context.Context, userId, step int64) error {
+	const op = "User.EnableTotp"
+	log := slog.With(slog.String("op", op))
+
+	query := `UPDATE users SET totp_enabled = TRUE, totp_last_step = $1, update_datetime = NOW() WHERE id = $2 AND totp_secret IS NOT NULL`
+	if _, err := u.db.ExecContext(ctx, query, step, userId); err != nil {
+		log.Error("failed to enable totp", "err", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseTotpStep атомарно отмечает шаг TOTP использованным. Возвращает false,
+// если код этого шага уже был принят - это повтор перехваченного кода
+func (u *UserRepository) UseTotpStep(ctx context.Context, userId, step int64) (bool, error) {
+	const op = "User.UseTotpStep"
+	log := slog.With(slog.String("op", op))
+
+	result, err := u.db.ExecContext(ctx, "UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1", step, userId)
+	if err != nil {
+		log.Error("failed to use totp step", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get rows affected", "err", err)
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return rowsAffected == 1, nil
+}
@@ -2,18 +2,26 @@ package internal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/phenirain/sso/internal/application"
 	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/lib/denylist"
 	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/loginguard"
+	"github.com/phenirain/sso/internal/lib/secretbox"
+	"github.com/phenirain/sso/internal/repository/signingkey"
+	"github.com/phenirain/sso/internal/services/keys"
 	"github.com/phenirain/sso/pkg/database"
 	"github.com/phenirain/sso/pkg/logger"
 	"github.com/phenirain/sso/pkg/metrics"
@@ -28,28 +36,89 @@ func Run(cfg *config.Config) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
-	startServers(ctx, g, db, cfg)
+	if err := startServers(ctx, g, db, cfg); err != nil {
+		// уже запущенные фоновые задачи завершаются по отмене контекста
+		stop()
+		_ = g.Wait()
+		return fmt.Errorf("failed to start servers: %w", err)
+	}
 	startPprofServer(ctx, g)
 
-	if err := g.Wait(); err != nil && errors.Is(err, context.Canceled) {
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
 		return fmt.Errorf("server exited with error: %w", err)
 	}
 
 	return nil
 }
 
-func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *config.Config) {
-	jwtLib := jwt.NewJwtLib(time.Minute*60, []byte(cfg.Secret))
+func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *config.Config) error {
+	signingKey, err := newSigningKey(cfg.JWT, cfg.Secret)
+	if err != nil {
+		return fmt.Errorf("load jwt signing key: %w", err)
+	}
+	keyring := jwt.NewKeyring(signingKey)
+	jwtLib := jwt.NewJwtLib(jwt.Options{
+		Issuer:               cfg.JWT.Issuer,
+		Audience:             cfg.JWT.Audience,
+		AccessDuration:       cfg.JWT.AccessTTL,
+		RefreshDuration:      cfg.JWT.RefreshTTL,
+		ServiceDuration:      cfg.JWT.ServiceTTL,
+		VerificationDuration: cfg.Email.VerificationTTL,
+		MFADuration:          cfg.MFA.ChallengeTTL,
+		Leeway:               cfg.JWT.Leeway,
+	}, keyring)
 
 	log, err := logger.Setup(cfg.Env)
 	if err != nil {
 		panic(err)
 	}
 
-	httpServer, m, err := application.SetupHTTPServer(cfg, db, jwtLib, log)
+	// Шифр для секретов TOTP и приватных ключей подписи в базе
+	secretBox, err := newSecretBox(cfg.Env, cfg.MFA, cfg.Secret)
+	if err != nil {
+		return fmt.Errorf("load mfa encryption key: %w", err)
+	}
+
+	// Ключи подписи: ротация хранится в базе и перечитывается без перезапуска -
+	// периодически и по SIGHUP
+	signingKeys := keys.New(signingkey.New(db), keyring, secretBox, signingKey, signingKey.Algorithm(), jwtLib.RefreshDuration(), cfg.JWT.Leeway)
+	if err := signingKeys.Reload(ctx); err != nil {
+		return fmt.Errorf("load signing keys: %w", err)
+	}
+	g.Go(func() error {
+		signingKeys.Start(ctx, time.Minute)
+		return nil
+	})
+	g.Go(func() error {
+		reloadOnHangup(ctx, signingKeys)
+		return nil
+	})
+
+	revoked := denylist.New()
+	g.Go(func() error {
+		revoked.Start(ctx, time.Minute)
+		return nil
+	})
+
+	// Счетчики неудачных попыток входа живут в процессе. Для нескольких экземпляров
+	// сервиса нужна реализация loginguard.Store поверх общего хранилища
+	lockoutStore := loginguard.NewMemoryStore(max(cfg.Lockout.Window, cfg.Lockout.Duration))
+	g.Go(func() error {
+		lockoutStore.Start(ctx, time.Minute)
+		return nil
+	})
+	guard := loginguard.New(lockoutStore, loginguard.Policy{
+		Window:           cfg.Lockout.Window,
+		LockoutDuration:  cfg.Lockout.Duration,
+		MaxLoginFailures: cfg.Lockout.MaxLoginFailures,
+		MaxIPFailures:    cfg.Lockout.MaxIPFailures,
+		BaseDelay:        cfg.Lockout.BaseDelay,
+		MaxDelay:         cfg.Lockout.MaxDelay,
+	})
+
+	httpServer, m, err := application.SetupHTTPServer(cfg, db, jwtLib, revoked, signingKeys, secretBox, guard, log)
 	if err != nil {
-		slog.Error("Failed to setup HTTP server", "err", err)
-		return
+		return fmt.Errorf("setup HTTP server: %w", err)
 	}
 
 	// Start metrics collector
@@ -67,6 +136,54 @@ func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *conf
 	}
 
 	startGroup(ctx, g, "http", fmt.Sprintf("%d", cfg.HTTP.Port), server, time.Second*5)
+	return nil
+}
+
+func newSigningKey(cfg config.JWTConfig, secret string) (*jwt.SigningKey, error) {
+	switch cfg.Algorithm {
+	case "", jwt.AlgorithmHS256:
+		return jwt.NewHMACKey(cfg.KeyId, []byte(secret)), nil
+	default:
+		return jwt.LoadSigningKey(cfg.Algorithm, cfg.PrivateKeyPath, cfg.KeyId)
+	}
+}
+
+// newSecretBox создает шифр для TOTP секретов и ключей подписи. Ключ из общего secret допускается только
+// в окружениях local и dev: иначе утечка secret раскрыла бы и секреты в базе, а его смена сделала бы их нечитаемыми
+func newSecretBox(env string, cfg config.MFAConfig, secret string) (*secretbox.Box, error) {
+	if cfg.EncryptionKey == "" {
+		if env != "local" && env != "dev" {
+			// прежний ключ по умолчанию - base64(sha256(secret)), его можно задать явно, чтобы не потерять сохраненные секреты
+			return nil, fmt.Errorf("mfa.encryption_key обязателен в окружении %q", env)
+		}
+		slog.Warn("mfa.encryption_key is not set, deriving it from secret", "env", env)
+		key := sha256.Sum256([]byte(secret))
+		return secretbox.New(key[:])
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("mfa.encryption_key должен быть в base64: %w", err)
+	}
+	return secretbox.New(key)
+}
+
+func reloadOnHangup(ctx context.Context, signingKeys *keys.Keys) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			slog.Info("reloading signing keys")
+			if err := signingKeys.Reload(ctx); err != nil {
+				slog.Error("failed to reload signing keys", "err", err)
+			}
+		}
+	}
 }
 
 func startPprofServer(ctx context.Context, g *errgroup.Group) {
@@ -1,50 +1,172 @@
 package auth
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/phenirain/sso/internal/config"
 	"github.com/phenirain/sso/internal/domain"
 	"github.com/phenirain/sso/internal/dto/auth"
 	authErrors "github.com/phenirain/sso/internal/errors/auth"
 	"github.com/phenirain/sso/internal/errors/jwt"
+	"github.com/phenirain/sso/internal/lib/oidcclient"
+	"github.com/phenirain/sso/internal/lib/randtoken"
 	"github.com/phenirain/sso/pkg/contextkeys"
 	api "gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api"
 	pb "gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api/client"
 )
 
 type Jwt interface {
-	NewToken(userId, role int64) (accessToken string, refreshToken string, error error)
-	ParseToken(tokenString string) (userId int64, roleId int64, err error)
+	NewToken(userId, role int64, refreshTokenId, accessTokenId, clientId string) (accessToken string, refreshToken string, error error)
+	ParseAccessToken(tokenString string) (userId int64, roleId int64, tokenId string, err error)
+	ParseRefreshToken(tokenString string) (userId int64, roleId int64, tokenId string, err error)
+	AccessDuration() time.Duration
+	RefreshDuration() time.Duration
+	NewEmailVerificationToken(userId int64, email string) (string, error)
+	ParseEmailVerificationToken(tokenString string) (userId int64, email string, err error)
+	NewMFAToken(userId, role int64, tokenId string) (string, error)
+	ParseMFAToken(tokenString string) (userId int64, roleId int64, tokenId string, err error)
+	NewPasswordChangeToken(userId, role int64, tokenId string) (string, error)
+	ParsePasswordChangeToken(tokenString string) (userId int64, roleId int64, tokenId string, err error)
+}
+
+// Cipher шифрует TOTP секреты перед сохранением в базу
+type Cipher interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// Denylist хранит идентификаторы отозванных access токенов до истечения их срока жизни
+type Denylist interface {
+	Revoke(ctx context.Context, tokenId string, ttl time.Duration) error
+}
+
+// LoginGuard считает неудачные попытки входа по логину и IP и блокирует перебор паролей
+type LoginGuard interface {
+	Check(ctx context.Context, login, ip string) (lockedFor time.Duration, err error)
+	Fail(ctx context.Context, login, ip string) (delay time.Duration, err error)
+	Succeed(ctx context.Context, login string) error
+	Unlock(ctx context.Context, login string) error
+}
+
+// PasswordPolicy проверяет новый пароль на соответствие требованиям
+type PasswordPolicy interface {
+	Check(password, login string) error
+}
+
+// Authenticator проверяет пароль сотрудника во внешнем каталоге (LDAP, Active Directory) и
+// возвращает id роли по его группам, 0 - ни одна группа не дает роли
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (roleId int64, err error)
+}
+
+// ExternalProvider - внешний провайдер OAuth2/OIDC, через которого пользователь входит без пароля
+type ExternalProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidcclient.Identity, error)
 }
 
 type Repository interface {
 	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
 	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
 	CreateUser(ctx context.Context, user *domain.User) (int64, error)
+	UpdateUserRole(ctx context.Context, userId, roleId int64) error
 	UpdatePassword(ctx context.Context, login, newPasswordHash string) error
+	RehashPassword(ctx context.Context, userId int64, oldPasswordHash, newPasswordHash string) error
+	GetPasswordHistory(ctx context.Context, userId int64, limit int) ([]domain.PasswordHistoryEntry, error)
+
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, family string) error
+	GetRefreshTokensByFamily(ctx context.Context, family string) ([]domain.RefreshToken, error)
+	GetRefreshTokensByUser(ctx context.Context, userId int64) ([]domain.RefreshToken, error)
+	RevokeUserRefreshTokens(ctx context.Context, userId int64) error
+	SaveSession(ctx context.Context, session *domain.Session) error
+	GetSession(ctx context.Context, id string) (*domain.Session, error)
+	GetActiveSessions(ctx context.Context, userId int64) ([]domain.Session, error)
+
+	CreateExternalAuthState(ctx context.Context, state *domain.ExternalAuthState) error
+	ConsumeExternalAuthState(ctx context.Context, stateHash string) (*domain.ExternalAuthState, error)
+	GetExternalIdentity(ctx context.Context, provider, subject string) (*domain.ExternalIdentity, error)
+	SaveExternalIdentity(ctx context.Context, identity *domain.ExternalIdentity) error
+
+	SetEmailVerified(ctx context.Context, userId int64) error
+	SetTotpSecret(ctx context.Context, userId int64, secret []byte) error
+	EnableTotp(ctx context.Context, userId, step int64) error
+	UseTotpStep(ctx context.Context, userId, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []domain.RecoveryCode) error
+	GetUnusedRecoveryCodes(ctx context.Context, userId int64) ([]domain.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id int64) (bool, error)
+
+	CreateLoginChallenge(ctx context.Context, challenge *domain.LoginChallenge) error
+	UseLoginChallenge(ctx context.Context, id, purpose string, maxAttempts int) (*domain.LoginChallenge, error)
+	DeleteLoginChallenge(ctx context.Context, id string) (bool, error)
+
+	CreateMagicLinkToken(ctx context.Context, token *domain.MagicLinkToken, since time.Time, limit int) (bool, error)
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (*domain.MagicLinkToken, error)
+
+	CreateWebAuthnChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error
+	ConsumeWebAuthnChallenge(ctx context.Context, challengeHash string) (*domain.WebAuthnChallenge, error)
+	CreatePasskey(ctx context.Context, passkey *domain.Passkey) error
+	GetPasskey(ctx context.Context, id string) (*domain.Passkey, error)
+	GetUserPasskeys(ctx context.Context, userId int64) ([]domain.Passkey, error)
+	UpdatePasskeyUsage(ctx context.Context, id string, signCount int64) (bool, error)
+	DeletePasskey(ctx context.Context, userId int64, id string) (bool, error)
+
+	CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetToken) error
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
 }
 
 type Auth struct {
-	s      pb.ClientServiceClient
-	repo   Repository
-	jwt    Jwt
-	config *config.Config
+	s        pb.ClientServiceClient
+	repo     Repository
+	jwt      Jwt
+	denylist Denylist
+	cipher   Cipher
+	guard    LoginGuard
+	policy   PasswordPolicy
+	hasher   domain.PasswordHasher
+	external map[string]ExternalProvider
+	// authenticators - каталоги сотрудников по домену логина
+	authenticators map[string]Authenticator
+	config         *config.Config
 }
 
-func New(repo Repository, jwt Jwt, clientService pb.ClientServiceClient, cfg *config.Config) *Auth {
+// Deps - зависимости сервиса. External и Authenticators могут быть пустыми
+type Deps struct {
+	Repo     Repository
+	Jwt      Jwt
+	Denylist Denylist
+	Cipher   Cipher
+	Guard    LoginGuard
+	Policy   PasswordPolicy
+	Hasher   domain.PasswordHasher
+	External map[string]ExternalProvider
+	// Authenticators - каталоги сотрудников по домену логина
+	Authenticators map[string]Authenticator
+	ClientService  pb.ClientServiceClient
+}
+
+func New(deps Deps, cfg *config.Config) *Auth {
 	return &Auth{
-		repo:   repo,
-		jwt:    jwt,
-		s:      clientService,
-		config: cfg,
+		repo:     deps.Repo,
+		jwt:      deps.Jwt,
+		denylist: deps.Denylist,
+		cipher:   deps.Cipher,
+		guard:    deps.Guard,
+		policy:   deps.Policy,
+		hasher:   deps.Hasher,
+		external: deps.External,
+		s:        deps.ClientService,
+
+		authenticators: deps.Authenticators,
+		config:         cfg,
 	}
 }
 
@@ -64,59 +186,141 @@ func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool) (
 		if user != nil {
 			return nil, authErrors.ErrUserAlreadyExists
 		}
+		// сотрудники с корпоративным адресом появляются при первом входе через каталог
+		if a.isDirectoryAccount(request.Login) {
+			return nil, authErrors.ErrDirectoryAccount
+		}
 
-		user = domain.NewUser(request.Login, request.Password, nil, nil)
+		if err := a.policy.Check(request.Password, request.Login); err != nil {
+			return nil, err
+		}
+		user, err = domain.NewUser(request.Login, request.Password, a.hasher, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 		role = 1
-		userId, err = a.repo.CreateUser(ctx, user)
+		userId, err = a.createClient(ctx, user)
 		if err != nil {
-			errText := fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
-			slog.Error(errText.Error())
-			return nil, errText
+			return nil, err
 		}
 
-		req := api.ClientRequest{
-			Email:  &request.Login,
-			UserId: &userId,
+		// письмо можно запросить повторно, поэтому ошибка отправки не отменяет регистрацию
+		if err := a.sendVerificationEmail(ctx, userId, request.Login); err != nil {
+			slog.Error("failed to send verification email", "userId", userId, "err", err)
 		}
-
-		ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
-		_, err = a.s.RegisterClient(ctx, &req)
-		if err != nil {
-			return nil, fmt.Errorf("ошибка регистрации клиента: %w", err)
+		if a.config.Email.RequireVerification {
+			return &auth.AuthResponse{EmailVerificationRequired: true}, nil
 		}
 	} else { // если авторизация
+		ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+		if err := a.checkLockout(ctx, request.Login, ip); err != nil {
+			return nil, err
+		}
+		if authenticator, ok := a.authenticatorFor(request.Login); ok {
+			return a.directoryLogin(ctx, authenticator, request, ip)
+		}
 		// если пользователь не найден
 		if user == nil {
-			return nil, authErrors.ErrInvalidUserCredentials
+			return nil, a.loginFailed(ctx, request.Login, ip)
 		}
 		// проверяем, не архивирован ли пользователь
 		if user.IsArchived {
 			return nil, authErrors.ErrUserArchived
 		}
-		valid := user.CheckPassword(request.Password)
+		valid := user.CheckPassword(a.hasher, request.Password)
 		// если пароль не верен
 		if !valid {
-			return nil, authErrors.ErrInvalidUserCredentials
+			return nil, a.loginFailed(ctx, request.Login, ip)
+		}
+		a.rehashPassword(ctx, user, request.Password)
+		if a.config.Email.RequireVerification && !user.EmailVerified {
+			return nil, authErrors.ErrEmailNotVerified
+		}
+		// пароль верен, но токены выдаются только после второго фактора. Счетчик неудач
+		// сбрасывается тоже только после него, иначе верный пароль обнулял бы перебор кодов
+		if user.TotpEnabled {
+			return a.mfaChallenge(ctx, user)
 		}
-		userId = user.Id
-		role = user.RoleId
+		if err := a.guard.Succeed(ctx, request.Login); err != nil {
+			slog.Error("failed to reset login failures", "err", err)
+		}
+		return a.completeLogin(ctx, user)
 	}
 
-	return a.getAuthResponse(userId, role)
+	return a.getAuthResponse(ctx, userId, role, "", "")
+}
+
+// createClient сохраняет нового пользователя и регистрирует его покупателем в сервисе клиентов
+func (a *Auth) createClient(ctx context.Context, user *domain.User) (int64, error) {
+	userId, err := a.repo.CreateUser(ctx, user)
+	if err != nil {
+		errText := fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
+		slog.Error(errText.Error())
+		return 0, errText
+	}
+
+	req := api.ClientRequest{
+		Email:  &user.Login,
+		UserId: &userId,
+	}
+
+	ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
+	_, err = a.s.RegisterClient(ctx, &req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка регистрации клиента: %w", err)
+	}
+	return userId, nil
 }
 
 func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResponse, error) {
+	return a.refresh(ctx, refreshToken, "")
+}
+
+// RefreshForClient обновляет токены, выданные OAuth клиенту clientId.
+// Токен другого клиента (или собственного фронтенда) отклоняется
+func (a *Auth) RefreshForClient(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error) {
+	return a.refresh(ctx, refreshToken, clientId)
+}
+
+func (a *Auth) refresh(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error) {
 
 	// проверка токена
-	userId, roleId, err := a.jwt.ParseToken(refreshToken)
+	userId, roleId, tokenId, err := a.jwt.ParseRefreshToken(refreshToken)
 	if err != nil {
-		if errors.Is(err, jwt.ErrInvalidToken) {
+		if errors.Is(err, jwt.ErrInvalidToken) || errors.Is(err, jwt.ErrWrongTokenType) {
 			return nil, err
 		}
 		slog.Error("ошибка парсинга токена", "err", err)
 		return nil, err
 	}
 
+	// проверка серверной записи о токене
+	stored, err := a.repo.GetRefreshToken(ctx, tokenId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка получения refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if stored == nil || stored.UserId != userId || stored.ClientId != clientId || stored.IsExpired() {
+		return nil, jwt.ErrInvalidToken
+	}
+
+	// ротация: токен одноразовый. Если он уже был использован -
+	// токен украден, отзываем все семейство
+	rotated, err := a.repo.RevokeRefreshToken(ctx, tokenId)
+	if err != nil {
+		errorText := fmt.Errorf("ошибка отзыва refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if !rotated {
+		slog.Warn("refresh token reuse detected", "userId", userId, "family", stored.Family)
+		if err := a.revokeFamily(ctx, stored.Family); err != nil {
+			return nil, err
+		}
+		return nil, jwt.ErrRefreshTokenReused
+	}
+
 	// проверка пользователя
 	user, err := a.repo.GetUserWithId(ctx, userId)
 	if err != nil {
@@ -128,6 +332,15 @@ func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResp
 	if user == nil || user.IsArchived {
 		return nil, authErrors.ErrUserNotFound
 	}
+	// токены, выданные до включения require_verification, не продлевают сессию без подтверждения
+	if a.config.Email.RequireVerification && !user.EmailVerified {
+		return nil, authErrors.ErrEmailNotVerified
+	}
+	// иначе сессия, начатая до истечения срока пароля, продлевалась бы без его смены.
+	// Использованный refresh токен уже отозван, так что сессия заканчивается здесь
+	if a.isPasswordExpired(user) {
+		return a.passwordExpiredResponse(ctx, user)
+	}
 
 	// Используем роль из токена, но можно проверить соответствие с ролью в БД
 	if roleId != user.RoleId {
@@ -136,17 +349,139 @@ func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResp
 		roleId = user.RoleId
 	}
 
-	return a.getAuthResponse(userId, roleId)
+	return a.getAuthResponse(ctx, userId, roleId, stored.Family, stored.ClientId)
 }
 
-func (a *Auth) getAuthResponse(userId, role int64) (*auth.AuthResponse, error) {
-	accessToken, refreshToken, err := a.jwt.NewToken(userId, role)
+// IssueTokens выпускает пару токенов для уже аутентифицированного пользователя
+// (например, после обмена кода авторизации OAuth2 клиентом clientId). Начинает новую сессию
+func (a *Auth) IssueTokens(ctx context.Context, userId, role int64, clientId string) (*auth.AuthResponse, error) {
+	return a.getAuthResponse(ctx, userId, role, "", clientId)
+}
+
+// Logout завершает сессию, к которой относится refreshToken: отзывает все
+// refresh токены семейства и еще живые access токены этой сессии
+func (a *Auth) Logout(ctx context.Context, userId int64, refreshToken string) error {
+	const op = "Auth.Logout"
+
+	tokenUserId, _, tokenId, err := a.jwt.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	stored, err := a.repo.GetRefreshToken(ctx, tokenId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// нельзя завершить чужую сессию
+	if stored == nil || stored.UserId != userId || tokenUserId != userId {
+		return jwt.ErrInvalidToken
+	}
+
+	if err := a.revokeFamily(ctx, stored.Family); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("user logged out", "userId", userId, "family", stored.Family)
+	return nil
+}
+
+// LogoutAll завершает все сессии пользователя на всех устройствах
+func (a *Auth) LogoutAll(ctx context.Context, userId int64) error {
+	const op = "Auth.LogoutAll"
+
+	tokens, err := a.repo.GetRefreshTokensByUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.repo.RevokeUserRefreshTokens(ctx, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.denyAccessTokens(ctx, tokens)
+
+	slog.Info("user logged out from all devices", "userId", userId)
+	return nil
+}
+
+// RevokeRefreshToken отзывает сессию, к которой относится refresh токен с идентификатором tokenId,
+// вместе с выпущенными в ней access токенами. Неизвестный токен не считается ошибкой (RFC 7009)
+func (a *Auth) RevokeRefreshToken(ctx context.Context, tokenId string) error {
+	const op = "Auth.RevokeRefreshToken"
+
+	stored, err := a.repo.GetRefreshToken(ctx, tokenId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if stored == nil {
+		return nil
+	}
+
+	if err := a.revokeFamily(ctx, stored.Family); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	slog.Info("refresh token revoked", "userId", stored.UserId, "family", stored.Family)
+	return nil
+}
+
+// IsRefreshTokenActive проверяет серверную запись refresh токена: она должна существовать,
+// не быть отозванной и не истечь
+func (a *Auth) IsRefreshTokenActive(ctx context.Context, tokenId string) (bool, error) {
+	stored, err := a.repo.GetRefreshToken(ctx, tokenId)
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения refresh токена: %w", err)
+	}
+	return stored != nil && !stored.Revoked && !stored.IsExpired(), nil
+}
+
+func (a *Auth) revokeFamily(ctx context.Context, family string) error {
+	tokens, err := a.repo.GetRefreshTokensByFamily(ctx, family)
+	if err != nil {
+		return fmt.Errorf("ошибка получения семейства refresh токенов: %w", err)
+	}
+	if err := a.repo.RevokeRefreshTokenFamily(ctx, family); err != nil {
+		return fmt.Errorf("ошибка отзыва семейства refresh токенов: %w", err)
+	}
+	a.denyAccessTokens(ctx, tokens)
+	return nil
+}
+
+// denyAccessTokens добавляет в denylist access токены, выпущенные в паре с tokens,
+// на оставшееся время их жизни. Уже истекшие пропускаются.
+func (a *Auth) denyAccessTokens(ctx context.Context, tokens []domain.RefreshToken) {
+	for _, token := range tokens {
+		if token.AccessTokenId == "" {
+			continue
+		}
+		ttl := time.Until(token.IssuedAt.Add(a.jwt.AccessDuration()))
+		if ttl <= 0 {
+			continue
+		}
+		if err := a.denylist.Revoke(ctx, token.AccessTokenId, ttl); err != nil {
+			slog.Error("failed to revoke access token", "tokenId", token.AccessTokenId, "err", err)
+		}
+	}
+}
+
+// getAuthResponse выпускает пару токенов и сохраняет запись о refresh токене.
+// Пустой family начинает новое семейство (новый вход), пустой clientId - токены собственного фронтенда.
+func (a *Auth) getAuthResponse(ctx context.Context, userId, role int64, family, clientId string) (*auth.AuthResponse, error) {
+	stored := domain.NewRefreshToken(userId, family, clientId, a.jwt.RefreshDuration())
+	accessToken, refreshToken, err := a.jwt.NewToken(userId, role, stored.Id, stored.AccessTokenId, clientId)
 	if err != nil {
 		errorText := fmt.Errorf("ошибка генерации токенов доступа: %w", err)
 		slog.Error(errorText.Error())
 		return nil, errorText
 	}
 
+	if err := a.repo.CreateRefreshToken(ctx, stored); err != nil {
+		errorText := fmt.Errorf("ошибка сохранения refresh токена: %w", err)
+		slog.Error(errorText.Error())
+		return nil, errorText
+	}
+	if err := a.saveSession(ctx, stored); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+
 	return &auth.AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -172,54 +507,64 @@ func (a *Auth) SendPasswordResetEmail(ctx context.Context, login string) error {
 	if user.IsArchived {
 		return authErrors.ErrUserArchived
 	}
+	if a.isDirectoryAccount(user.Login) {
+		return authErrors.ErrDirectoryAccount
+	}
 
 	// Email это login пользователя (используется при регистрации)
 	userEmail := login
 
-	// Кодируем логин в base64
-	encodedLogin := base64.StdEncoding.EncodeToString([]byte(login))
+	// Новый токен заменяет все ранее выданные, в базе хранится только его хеш
+	token, err := randtoken.New()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+	err = a.repo.CreatePasswordResetToken(ctx, &domain.PasswordResetToken{
+		TokenHash: randtoken.Hash(token),
+		UserId:    user.Id,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.config.Email.ResetTokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	// Формируем ссылку для сброса пароля
-	resetLink := fmt.Sprintf("%s?token=%s", a.config.Email.FrontendResetURL, encodedLogin)
+	resetLink := fmt.Sprintf("%s?token=%s", a.config.Email.FrontendResetURL, url.QueryEscape(token))
 
 	// Отправляем запрос на email-сервис
-	emailPayload := map[string]interface{}{
+	err = a.sendEmail(ctx, "/send-reset-email", map[string]interface{}{
 		"to":        userEmail,
 		"resetLink": resetLink,
 		"login":     login,
-	}
-
-	jsonData, err := json.Marshal(emailPayload)
+	})
 	if err != nil {
-		return fmt.Errorf("%s: ошибка создания JSON: %w", op, err)
-	}
-
-	resp, err := http.Post(
-		a.config.Email.ServiceURL+"/send-reset-email",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		slog.Error("failed to send email", "err", err)
-		return fmt.Errorf("%s: ошибка отправки email: %w", op, err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s: email-сервис вернул статус %d", op, resp.StatusCode)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	slog.Info("password reset email sent", "login", login, "email", userEmail)
 	return nil
 }
 
-func (a *Auth) ResetPassword(ctx context.Context, login, newPassword string) error {
+// ResetPassword меняет пароль по токену из письма. Токен одноразовый: он удаляется
+// при первой успешной смене пароля.
+// После смены пароля все сессии пользователя завершаются
+func (a *Auth) ResetPassword(ctx context.Context, token, newPassword string) error {
 	const op = "Auth.ResetPassword"
 
+	// Токен гасится только после проверки пароля: слабый пароль не должен сжигать ссылку из письма
+	tokenHash := randtoken.Hash(token)
+	resetToken, err := a.repo.GetPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if resetToken == nil || resetToken.IsExpired() {
+		return authErrors.ErrInvalidResetToken
+	}
+
 	// Проверяем существование пользователя
-	user, err := a.repo.GetUserByLogin(ctx, login)
+	user, err := a.repo.GetUserWithId(ctx, resetToken.UserId)
 	if err != nil {
 		slog.Error("failed to get user", "err", err)
 		return fmt.Errorf("%s: %w", op, err)
@@ -233,17 +578,35 @@ func (a *Auth) ResetPassword(ctx context.Context, login, newPassword string) err
 	if user.IsArchived {
 		return authErrors.ErrUserArchived
 	}
+	if a.isDirectoryAccount(user.Login) {
+		return authErrors.ErrDirectoryAccount
+	}
+
+	passwordHash, err := a.hashNewPassword(ctx, user, newPassword)
+	if err != nil {
+		return err
+	}
 
-	// Хешируем новый пароль используя ту же логику что и при создании
-	newUser := domain.NewUser(login, newPassword, nil, nil)
+	// Ссылку могли использовать параллельно - пароль меняет только тот, кто погасил токен
+	resetToken, err = a.repo.ConsumePasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if resetToken == nil {
+		return authErrors.ErrInvalidResetToken
+	}
 
-	// Обновляем пароль (PasswordHash это []byte, нужно string)
-	err = a.repo.UpdatePassword(ctx, login, string(newUser.PasswordHash))
+	err = a.repo.UpdatePassword(ctx, user.Login, passwordHash)
 	if err != nil {
 		slog.Error("failed to update password", "err", err)
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	slog.Info("password reset successful", "login", login)
+	// Сбросивший пароль должен выкинуть из аккаунта того, кто мог знать старый
+	if err := a.LogoutAll(ctx, user.Id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("password reset successful", "login", user.Login)
 	return nil
 }
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	jwtErrors "github.com/phenirain/sso/internal/errors/jwt"
+	"github.com/phenirain/sso/internal/lib/denylist"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+	"github.com/phenirain/sso/pkg/echomiddleware"
+)
+
+func newRefreshTestAuth(t *testing.T) (*Auth, *memoryRepository, *memoryDenylist, int64) {
+	t.Helper()
+	denylist := &memoryDenylist{}
+	a, repo := newTestAuth(t, withDenylist(denylist))
+	return a, repo, denylist, repo.addUser(t, "user@example.com", "user-password-1", 1)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	a, _, _, userId := newRefreshTestAuth(t)
+
+	login, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := a.Refresh(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	if _, err := a.Refresh(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("refresh with rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	a, repo, denylist, userId := newRefreshTestAuth(t)
+
+	login, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := a.Refresh(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// другая сессия того же пользователя не должна пострадать
+	other, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// повтор уже использованного токена - признак кражи
+	if _, err := a.Refresh(ctx, login.RefreshToken); !errors.Is(err, jwtErrors.ErrRefreshTokenReused) {
+		t.Fatalf("replay: got %v, want ErrRefreshTokenReused", err)
+	}
+	// вместе с ним отозвано все семейство, в том числе последний выданный токен
+	if _, err := a.Refresh(ctx, rotated.RefreshToken); err == nil {
+		t.Fatal("latest token of the revoked family is still accepted")
+	}
+
+	_, _, rotatedId, err := a.jwt.ParseRefreshToken(rotated.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repo.GetRefreshToken(ctx, rotatedId)
+	if !stored.Revoked {
+		t.Fatal("latest token of the family is not revoked")
+	}
+	if !denylist.isRevoked(stored.AccessTokenId) {
+		t.Fatal("access token of the revoked family is not denied")
+	}
+	if session := repo.sessions[stored.Family]; session == nil || !session.Revoked {
+		t.Fatal("session of the revoked family is still active")
+	}
+
+	if _, err := a.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("other session was revoked: %v", err)
+	}
+}
+
+func TestRefreshRejectsAccessToken(t *testing.T) {
+	ctx := context.Background()
+	a, _, _, userId := newRefreshTestAuth(t)
+
+	login, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(ctx, login.AccessToken); err == nil {
+		t.Fatal("access token accepted as refresh token")
+	}
+}
+
+// serveBearer пропускает запрос с access токеном через JwtValidation и возвращает статус ответа
+func serveBearer(revocation echomiddleware.Revocation, accessToken string) int {
+	e := echo.New()
+	handler := echomiddleware.JwtValidation(newTestJwt(), revocation)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/client/orders", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec.Code
+}
+
+func accessTokenId(t *testing.T, a *Auth, accessToken string) string {
+	t.Helper()
+	_, _, tokenId, err := a.jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenId
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	revoked := denylist.New()
+	a, repo := newTestAuth(t, withDenylist(revoked))
+	userId := repo.addUser(t, "user@example.com", "user-password-1", 1)
+
+	session, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serveBearer(revoked, session.AccessToken); got != http.StatusOK {
+		t.Fatalf("access token before logout: status %d", got)
+	}
+
+	if err := a.Logout(ctx, userId, session.RefreshToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if !revoked.IsRevoked(ctx, accessTokenId(t, a, session.AccessToken)) {
+		t.Fatal("access token of the session is not denied")
+	}
+	if got := serveBearer(revoked, session.AccessToken); got != http.StatusUnauthorized {
+		t.Fatalf("access token after logout: status %d, want %d", got, http.StatusUnauthorized)
+	}
+	if _, err := a.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Fatal("refresh token of the session is still accepted")
+	}
+
+	// другие сессии продолжают работать
+	if got := serveBearer(revoked, other.AccessToken); got != http.StatusOK {
+		t.Fatalf("access token of another session: status %d", got)
+	}
+	if _, err := a.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("refresh of another session: %v", err)
+	}
+}
+
+func TestLogoutRejectsForeignSession(t *testing.T) {
+	ctx := context.Background()
+	a, repo, revoked, userId := newRefreshTestAuth(t)
+	otherId := repo.addUser(t, "other@example.com", "other-password-1", 1)
+
+	session, err := a.IssueTokens(ctx, otherId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Logout(ctx, userId, session.RefreshToken); !errors.Is(err, jwtErrors.ErrInvalidToken) {
+		t.Fatalf("logout of another user's session: err = %v, want ErrInvalidToken", err)
+	}
+	if revoked.isRevoked(accessTokenId(t, a, session.AccessToken)) {
+		t.Fatal("access token of another user was denied")
+	}
+	if _, err := a.Refresh(ctx, session.RefreshToken); err != nil {
+		t.Fatalf("session of another user was revoked: %v", err)
+	}
+}
+
+func TestLogoutAllRevokesEverySession(t *testing.T) {
+	ctx := context.Background()
+	revoked := denylist.New()
+	a, repo := newTestAuth(t, withDenylist(revoked))
+	userId := repo.addUser(t, "user@example.com", "user-password-1", 1)
+	otherId := repo.addUser(t, "other@example.com", "other-password-1", 1)
+
+	first, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// после продления прежний refresh токен отозван, но выданный с ним access токен еще жив
+	rotated, err := a.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.IssueTokens(ctx, userId, 1, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := a.IssueTokens(ctx, otherId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.LogoutAll(ctx, userId); err != nil {
+		t.Fatalf("logout all: %v", err)
+	}
+	for _, tokens := range []*auth.AuthResponse{first, rotated, second} {
+		if got := serveBearer(revoked, tokens.AccessToken); got != http.StatusUnauthorized {
+			t.Fatalf("access token after logout all: status %d, want %d", got, http.StatusUnauthorized)
+		}
+	}
+	for _, tokens := range []*auth.AuthResponse{rotated, second} {
+		if _, err := a.Refresh(ctx, tokens.RefreshToken); err == nil {
+			t.Fatal("refresh token is still accepted after logout all")
+		}
+	}
+	for family, session := range repo.sessions {
+		if session.UserId == userId && !session.Revoked {
+			t.Fatalf("session %s is still active", family)
+		}
+	}
+
+	if got := serveBearer(revoked, foreign.AccessToken); got != http.StatusOK {
+		t.Fatalf("access token of another user: status %d", got)
+	}
+}
+
+const (
+	resetTestLogin       = "buyer@example.com"
+	resetTestPassword    = "buyer-password-1"
+	resetTestNewPassword = "buyer-password-2"
+)
+
+func newResetTestAuth(t *testing.T, opts ...testAuthOption) (*Auth, *memoryRepository, *emailService, int64) {
+	t.Helper()
+	service, serviceURL := newEmailService(t)
+	opts = append(opts, withConfig(func(cfg *config.Config) {
+		cfg.Email.ServiceURL = serviceURL
+		cfg.Email.FrontendResetURL = "https://shop.example.com/reset"
+		cfg.Email.ResetTokenTTL = time.Hour
+	}))
+	a, repo := newTestAuth(t, opts...)
+	return a, repo, service, repo.addUser(t, resetTestLogin, resetTestPassword, 1)
+}
+
+func sendResetEmail(t *testing.T, a *Auth, emails *emailService) string {
+	t.Helper()
+	if err := a.SendPasswordResetEmail(context.Background(), resetTestLogin); err != nil {
+		t.Fatalf("send reset email: %v", err)
+	}
+	return emails.lastToken(t, "/send-reset-email", resetTestLogin)
+}
+
+func logInWith(a *Auth, password string) error {
+	_, err := a.Auth(context.Background(), auth.AuthRequest{Login: resetTestLogin, Password: password}, false)
+	return err
+}
+
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	a, repo, emails, _ := newResetTestAuth(t)
+	token := sendResetEmail(t, a, emails)
+	// в базе хранится только хеш токена
+	if _, ok := repo.resetTokens[token]; ok {
+		t.Fatal("reset token is stored in plain text")
+	}
+
+	if err := a.ResetPassword(ctx, token, resetTestNewPassword); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := logInWith(a, resetTestNewPassword); err != nil {
+		t.Fatalf("log in with new password: %v", err)
+	}
+	if err := a.ResetPassword(ctx, token, "buyer-password-3"); !errors.Is(err, authErrors.ErrInvalidResetToken) {
+		t.Fatalf("replayed token: err = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestResetPasswordRejectsInvalidToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, a *Auth, repo *memoryRepository, emails *emailService) string
+	}{
+		{"unknown token", func(*testing.T, *Auth, *memoryRepository, *emailService) string {
+			return "unknown-token"
+		}},
+		{"expired token", func(t *testing.T, a *Auth, repo *memoryRepository, emails *emailService) string {
+			token := sendResetEmail(t, a, emails)
+			repo.resetTokens[randtoken.Hash(token)].ExpiresAt = time.Now().Add(-time.Second)
+			return token
+		}},
+		// действует только ссылка из последнего письма
+		{"superseded token", func(t *testing.T, a *Auth, _ *memoryRepository, emails *emailService) string {
+			token := sendResetEmail(t, a, emails)
+			sendResetEmail(t, a, emails)
+			return token
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, emails, _ := newResetTestAuth(t)
+			token := tt.prepare(t, a, repo, emails)
+
+			if err := a.ResetPassword(context.Background(), token, resetTestNewPassword); !errors.Is(err, authErrors.ErrInvalidResetToken) {
+				t.Fatalf("err = %v, want ErrInvalidResetToken", err)
+			}
+			if err := logInWith(a, resetTestPassword); err != nil {
+				t.Fatalf("password was changed: %v", err)
+			}
+		})
+	}
+}
+
+func TestResetPasswordNewerTokenWorks(t *testing.T) {
+	a, _, emails, _ := newResetTestAuth(t)
+	sendResetEmail(t, a, emails)
+	token := sendResetEmail(t, a, emails)
+
+	if err := a.ResetPassword(context.Background(), token, resetTestNewPassword); err != nil {
+		t.Fatalf("reset with the newest token: %v", err)
+	}
+}
+
+// слабый пароль не сжигает ссылку из письма
+func TestResetPasswordWeakPasswordKeepsToken(t *testing.T) {
+	ctx := context.Background()
+	a, _, emails, _ := newResetTestAuth(t)
+	token := sendResetEmail(t, a, emails)
+
+	var policyErr *passwordpolicy.Error
+	if err := a.ResetPassword(ctx, token, "short"); !errors.As(err, &policyErr) {
+		t.Fatalf("weak password: err = %v, want policy error", err)
+	}
+	if err := a.ResetPassword(ctx, token, resetTestNewPassword); err != nil {
+		t.Fatalf("reset after a weak password: %v", err)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	revoked := denylist.New()
+	a, repo, emails, userId := newResetTestAuth(t, withDenylist(revoked))
+
+	sessions := make([]*auth.AuthResponse, 2)
+	for i := range sessions {
+		tokens, err := a.IssueTokens(ctx, userId, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[i] = tokens
+	}
+
+	if err := a.ResetPassword(ctx, sendResetEmail(t, a, emails), resetTestNewPassword); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	for _, tokens := range sessions {
+		if _, err := a.Refresh(ctx, tokens.RefreshToken); err == nil {
+			t.Fatal("session opened before the reset is still active")
+		}
+		if got := serveBearer(revoked, tokens.AccessToken); got != http.StatusUnauthorized {
+			t.Fatalf("access token after reset: status %d, want %d", got, http.StatusUnauthorized)
+		}
+	}
+	for family, session := range repo.sessions {
+		if !session.Revoked {
+			t.Fatalf("session %s is still active", family)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/ldap"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+// directoryLogin проверяет пароль сотрудника в корпоративном каталоге его домена, создает
+// или обновляет пользователя с ролью из групп каталога и выдает токены как /auth/logIn
+func (a *Auth) directoryLogin(ctx context.Context, authenticator Authenticator, request auth.AuthRequest, ip string) (*auth.AuthResponse, error) {
+	const op = "Auth.directoryLogin"
+
+	roleId, err := authenticator.Authenticate(ctx, request.Login, request.Password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) || errors.Is(err, ldap.ErrUserNotFound) {
+			return nil, a.loginFailed(ctx, request.Login, ip)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// пароль верен, но ни одна группа сотрудника не дает доступа
+	if roleId == 0 {
+		slog.Warn("directory user has no mapped role", "login", request.Login)
+		return nil, authErrors.ErrDirectoryAccessDenied
+	}
+
+	user, err := a.repo.GetUserByLogin(ctx, request.Login)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil {
+		user, err = a.createDirectoryUser(ctx, request.Login, roleId)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if user.IsArchived {
+			return nil, authErrors.ErrUserArchived
+		}
+		if err := a.syncDirectoryRole(ctx, user, roleId); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.TotpEnabled {
+		return a.mfaChallenge(ctx, user)
+	}
+	if err := a.guard.Succeed(ctx, request.Login); err != nil {
+		slog.Error("failed to reset login failures", "err", err)
+	}
+	return a.completeLogin(ctx, user)
+}
+
+// createDirectoryUser создает сотрудника при первом входе. Пароль случайный и не используется:
+// сотрудник всегда входит с паролем каталога. Email подтвержден каталогом
+func (a *Auth) createDirectoryUser(ctx context.Context, login string, roleId int64) (*domain.User, error) {
+	password, err := randtoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации пароля: %w", err)
+	}
+	user, err := domain.NewUser(login, password, a.hasher, &roleId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка хеширования пароля: %w", err)
+	}
+
+	user.Id, err = a.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
+	}
+	if err := a.repo.SetEmailVerified(ctx, user.Id); err != nil {
+		return nil, fmt.Errorf("ошибка подтверждения email: %w", err)
+	}
+	user.EmailVerified = true
+
+	slog.Info("directory user provisioned", "userId", user.Id, "role", roleId)
+	return user, nil
+}
+
+// syncDirectoryRole приводит роль пользователя к роли из каталога. Сессии, открытые с прежней
+// ролью, завершаются: при обновлении токенов роль берется из базы
+func (a *Auth) syncDirectoryRole(ctx context.Context, user *domain.User, roleId int64) error {
+	if !user.EmailVerified {
+		if err := a.repo.SetEmailVerified(ctx, user.Id); err != nil {
+			return fmt.Errorf("ошибка подтверждения email: %w", err)
+		}
+		user.EmailVerified = true
+	}
+	if user.RoleId == roleId {
+		return nil
+	}
+
+	if err := a.repo.UpdateUserRole(ctx, user.Id, roleId); err != nil {
+		return fmt.Errorf("ошибка обновления роли пользователя: %w", err)
+	}
+	if err := a.LogoutAll(ctx, user.Id); err != nil {
+		return err
+	}
+	slog.Info("directory user role changed", "userId", user.Id, "from", user.RoleId, "to", roleId)
+	user.RoleId = roleId
+	return nil
+}
+
+// authenticatorFor возвращает каталог, к домену которого относится логин
+func (a *Auth) authenticatorFor(login string) (Authenticator, bool) {
+	at := strings.LastIndexByte(login, '@')
+	if at < 0 {
+		return nil, false
+	}
+	authenticator, ok := a.authenticators[strings.ToLower(login[at+1:])]
+	return authenticator, ok
+}
+
+// isDirectoryAccount - паролем учетной записи управляет каталог, поэтому локальные способы
+// входа и смены пароля для нее закрыты
+func (a *Auth) isDirectoryAccount(login string) bool {
+	_, ok := a.authenticatorFor(login)
+	return ok
+}
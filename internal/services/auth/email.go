@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+// VerifyEmail подтверждает email по токену из письма. Повторный переход по ссылке не ошибка
+func (a *Auth) VerifyEmail(ctx context.Context, token string) error {
+	const op = "Auth.VerifyEmail"
+
+	userId, email, err := a.jwt.ParseEmailVerificationToken(token)
+	if err != nil {
+		return authErrors.ErrInvalidVerifyToken
+	}
+
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// ссылка выдана на прежний логин
+	if user == nil || user.Login != email {
+		return authErrors.ErrInvalidVerifyToken
+	}
+	if user.IsArchived {
+		return authErrors.ErrUserArchived
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	if err := a.repo.SetEmailVerified(ctx, user.Id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("email verified", "userId", user.Id)
+	return nil
+}
+
+// ResendVerificationEmail повторно отправляет письмо подтверждения
+func (a *Auth) ResendVerificationEmail(ctx context.Context, login string) error {
+	const op = "Auth.ResendVerificationEmail"
+
+	user, err := a.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil {
+		return authErrors.ErrUserNotFound
+	}
+	if user.IsArchived {
+		return authErrors.ErrUserArchived
+	}
+	if user.EmailVerified {
+		return authErrors.ErrEmailAlreadyVerified
+	}
+
+	if err := a.sendVerificationEmail(ctx, user.Id, user.Login); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (a *Auth) sendVerificationEmail(ctx context.Context, userId int64, login string) error {
+	token, err := a.jwt.NewEmailVerificationToken(userId, login)
+	if err != nil {
+		return fmt.Errorf("ошибка создания токена подтверждения: %w", err)
+	}
+
+	verifyLink := fmt.Sprintf("%s?token=%s", a.config.Email.FrontendVerifyURL, url.QueryEscape(token))
+	err = a.sendEmail(ctx, "/send-verification-email", map[string]interface{}{
+		"to":         login,
+		"verifyLink": verifyLink,
+		"login":      login,
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("verification email sent", "userId", userId)
+	return nil
+}
+
+// sendEmail отправляет письмо через email-сервис. path - метод сервиса, соответствующий шаблону письма
+func (a *Auth) sendEmail(ctx context.Context, path string, payload map[string]interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка создания JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.Email.ServiceURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Error("failed to send email", "path", path, "err", err)
+		return fmt.Errorf("ошибка отправки email: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("email-сервис вернул статус %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+const verifyTestLogin = "buyer@example.com"
+
+// emailLinks - методы email-сервиса и поле со ссылкой в их письмах, как в email-service/server.js.
+// В уведомлениях ссылки нет
+var emailLinks = map[string]string{
+	"/send-reset-email":            "resetLink",
+	"/send-verification-email":     "verifyLink",
+	"/send-magic-link-email":       "magicLink",
+	"/send-password-changed-email": "",
+}
+
+type sentEmail struct {
+	path    string
+	payload map[string]string
+}
+
+// emailService - email-сервис на httptest, запоминает отправленные письма
+type emailService struct {
+	mu     sync.Mutex
+	emails []sentEmail
+}
+
+func newEmailService(t *testing.T) (*emailService, string) {
+	t.Helper()
+	service := &emailService{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := emailLinks[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		service.mu.Lock()
+		service.emails = append(service.emails, sentEmail{path: r.URL.Path, payload: payload})
+		service.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return service, server.URL
+}
+
+// lastToken возвращает токен из ссылки последнего письма path, отправленного на login
+func (s *emailService) lastToken(t *testing.T, path, login string) string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.emails) - 1; i >= 0; i-- {
+		if s.emails[i].path != path || s.emails[i].payload["to"] != login {
+			continue
+		}
+		link, err := url.Parse(s.emails[i].payload[emailLinks[path]])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return link.Query().Get("token")
+	}
+	t.Fatalf("no %s email sent to %s", path, login)
+	return ""
+}
+
+// sent возвращает число писем path, отправленных на login
+func (s *emailService) sent(path, login string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, email := range s.emails {
+		if email.path == path && email.payload["to"] == login {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *emailService) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.emails)
+}
+
+func newEmailTestAuth(t *testing.T, requireVerification bool) (*Auth, *memoryRepository, *emailService) {
+	t.Helper()
+	service, serviceURL := newEmailService(t)
+	a, repo := newTestAuth(t, withConfig(func(cfg *config.Config) {
+		cfg.Email.ServiceURL = serviceURL
+		cfg.Email.FrontendVerifyURL = "https://shop.example.com/verify"
+		cfg.Email.RequireVerification = requireVerification
+	}))
+	return a, repo, service
+}
+
+func signUp(a *Auth) (*auth.AuthResponse, error) {
+	return a.Auth(context.Background(), auth.AuthRequest{Login: verifyTestLogin, Password: "buyer-password-1"}, true)
+}
+
+func logIn(a *Auth) (*auth.AuthResponse, error) {
+	return a.Auth(context.Background(), auth.AuthRequest{Login: verifyTestLogin, Password: "buyer-password-1"}, false)
+}
+
+func TestSignUpRequiresEmailVerification(t *testing.T) {
+	a, _, emails := newEmailTestAuth(t, true)
+
+	response, err := signUp(a)
+	if err != nil {
+		t.Fatalf("sign up: %v", err)
+	}
+	if !response.EmailVerificationRequired || response.AccessToken != "" || response.RefreshToken != "" {
+		t.Fatalf("sign up returned %+v, want verification required without tokens", response)
+	}
+	if _, err := logIn(a); !errors.Is(err, authErrors.ErrEmailNotVerified) {
+		t.Fatalf("log in before verification: err = %v, want ErrEmailNotVerified", err)
+	}
+
+	token := emails.lastToken(t, "/send-verification-email", verifyTestLogin)
+	if err := a.VerifyEmail(context.Background(), token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	// повторный переход по ссылке не ошибка
+	if err := a.VerifyEmail(context.Background(), token); err != nil {
+		t.Fatalf("verify again: %v", err)
+	}
+
+	response, err = logIn(a)
+	if err != nil {
+		t.Fatalf("log in after verification: %v", err)
+	}
+	if response.AccessToken == "" {
+		t.Fatal("tokens were not issued after verification")
+	}
+}
+
+func TestSignUpIssuesTokensWhenVerificationOptional(t *testing.T) {
+	a, _, emails := newEmailTestAuth(t, false)
+
+	response, err := signUp(a)
+	if err != nil {
+		t.Fatalf("sign up: %v", err)
+	}
+	if response.EmailVerificationRequired || response.AccessToken == "" {
+		t.Fatalf("sign up returned %+v, want tokens", response)
+	}
+	if emails.count() != 1 {
+		t.Fatalf("%d verification emails sent, want 1", emails.count())
+	}
+}
+
+// сессия, начатая до включения require_verification, не продлевается без подтверждения
+func TestRefreshRejectsUnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	a, repo, _ := newEmailTestAuth(t, false)
+	userId := repo.addUser(t, verifyTestLogin, "buyer-password-1", 1)
+
+	tokens, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.config.Email.RequireVerification = true
+	if _, err := a.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, authErrors.ErrEmailNotVerified) {
+		t.Fatalf("refresh: err = %v, want ErrEmailNotVerified", err)
+	}
+
+	repo.users[userId].EmailVerified = true
+	tokens, err = a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(ctx, tokens.RefreshToken); err != nil {
+		t.Fatalf("refresh of verified user: %v", err)
+	}
+}
+
+func TestVerifyEmailRejectsInvalidToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(repo *memoryRepository, userId int64)
+		// token подменяет токен из письма
+		token   string
+		wantErr error
+	}{
+		{"malformed token", nil, "not-a-token", authErrors.ErrInvalidVerifyToken},
+		// ссылка выдана на прежний логин
+		{"login changed", func(repo *memoryRepository, userId int64) {
+			repo.users[userId].Login = "other@example.com"
+		}, "", authErrors.ErrInvalidVerifyToken},
+		{"user deleted", func(repo *memoryRepository, userId int64) {
+			delete(repo.users, userId)
+		}, "", authErrors.ErrInvalidVerifyToken},
+		{"user archived", func(repo *memoryRepository, userId int64) {
+			repo.users[userId].IsArchived = true
+		}, "", authErrors.ErrUserArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, emails := newEmailTestAuth(t, true)
+			if _, err := signUp(a); err != nil {
+				t.Fatal(err)
+			}
+			user, _ := repo.GetUserByLogin(context.Background(), verifyTestLogin)
+			userId := user.Id
+			token := emails.lastToken(t, "/send-verification-email", verifyTestLogin)
+			if tt.token != "" {
+				token = tt.token
+			}
+			if tt.prepare != nil {
+				tt.prepare(repo, userId)
+			}
+
+			if err := a.VerifyEmail(context.Background(), token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if user, ok := repo.users[userId]; ok && user.EmailVerified {
+				t.Fatal("email was verified")
+			}
+		})
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		login     string
+		prepare   func(repo *memoryRepository, userId int64)
+		wantErr   error
+		wantEmail bool
+	}{
+		{"unverified user", verifyTestLogin, nil, nil, true},
+		{"unknown user", "unknown@example.com", nil, authErrors.ErrUserNotFound, false},
+		{"already verified", verifyTestLogin, func(repo *memoryRepository, userId int64) {
+			repo.users[userId].EmailVerified = true
+		}, authErrors.ErrEmailAlreadyVerified, false},
+		{"archived user", verifyTestLogin, func(repo *memoryRepository, userId int64) {
+			repo.users[userId].IsArchived = true
+		}, authErrors.ErrUserArchived, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, emails := newEmailTestAuth(t, true)
+			userId := repo.addUser(t, verifyTestLogin, "buyer-password-1", 1)
+			if tt.prepare != nil {
+				tt.prepare(repo, userId)
+			}
+
+			if err := a.ResendVerificationEmail(context.Background(), tt.login); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if sent := emails.count() == 1; sent != tt.wantEmail {
+				t.Fatalf("email sent = %v, want %v", sent, tt.wantEmail)
+			}
+			if !tt.wantEmail {
+				return
+			}
+			if err := a.VerifyEmail(context.Background(), emails.lastToken(t, "/send-verification-email", verifyTestLogin)); err != nil {
+				t.Fatalf("verify with resent link: %v", err)
+			}
+			if !repo.users[userId].EmailVerified {
+				t.Fatal("email was not verified")
+			}
+		})
+	}
+}
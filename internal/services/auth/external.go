@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/oidcclient"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+// StartExternalLogin начинает вход через внешнего провайдера: запоминает state, nonce и
+// code_verifier и возвращает адрес страницы входа провайдера. StateBinding ответа сохраняется
+// в браузере и передается в FinishExternalLogin
+func (a *Auth) StartExternalLogin(ctx context.Context, provider string) (*auth.ExternalLoginStartResponse, error) {
+	const op = "Auth.StartExternalLogin"
+
+	p, ok := a.external[provider]
+	if !ok {
+		return nil, authErrors.ErrUnknownExternalProvider
+	}
+
+	state, err := randtoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	nonce, err := randtoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	codeVerifier, err := randtoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	err = a.repo.CreateExternalAuthState(ctx, &domain.ExternalAuthState{
+		StateHash:    randtoken.Hash(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(a.config.ExternalAuth.StateTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authorizationURL, err := p.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &auth.ExternalLoginStartResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+		StateBinding:     randtoken.Hash(state),
+	}, nil
+}
+
+// FinishExternalLogin обменивает код провайдера на пару токенов. Учетная запись провайдера
+// привязывается к пользователю с тем же подтвержденным email, а если такого нет - создается новый покупатель.
+// stateBinding - StateBinding из StartExternalLogin, сохраненный в браузере. Без него чужие code и state,
+// подсунутые пользователю, выполнили бы вход в аккаунт атакующего (login CSRF)
+func (a *Auth) FinishExternalLogin(ctx context.Context, provider, code, state, stateBinding string) (*auth.AuthResponse, error) {
+	const op = "Auth.FinishExternalLogin"
+
+	p, ok := a.external[provider]
+	if !ok {
+		return nil, authErrors.ErrUnknownExternalProvider
+	}
+	// state начатого в другом браузере не гасится: его владелец еще может завершить вход
+	if subtle.ConstantTimeCompare([]byte(randtoken.Hash(state)), []byte(stateBinding)) != 1 {
+		slog.Warn("external login state is not bound to this browser", "provider", provider)
+		return nil, authErrors.ErrInvalidExternalState
+	}
+
+	stored, err := a.repo.ConsumeExternalAuthState(ctx, randtoken.Hash(state))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if stored == nil || stored.IsExpired() || stored.Provider != provider {
+		return nil, authErrors.ErrInvalidExternalState
+	}
+
+	identity, err := p.Exchange(ctx, code, stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		slog.Warn("external login failed", "provider", provider, "err", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.externalUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return a.mfaChallenge(ctx, user)
+	}
+
+	slog.Info("external login", "userId", user.Id, "provider", provider)
+	return a.completeLogin(ctx, user)
+}
+
+// externalUser находит пользователя по учетной записи провайдера, привязывает ее по email
+// или создает нового пользователя
+func (a *Auth) externalUser(ctx context.Context, provider string, identity *oidcclient.Identity) (*domain.User, error) {
+	now := time.Now()
+	link := &domain.ExternalIdentity{
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+
+	linked, err := a.repo.GetExternalIdentity(ctx, provider, identity.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения внешней учетной записи: %w", err)
+	}
+	if linked != nil {
+		user, err := a.getActiveUser(ctx, linked.UserId)
+		if err != nil {
+			return nil, err
+		}
+		link.UserId = user.Id
+		if err := a.repo.SaveExternalIdentity(ctx, link); err != nil {
+			return nil, fmt.Errorf("ошибка сохранения внешней учетной записи: %w", err)
+		}
+		return user, nil
+	}
+
+	// без подтвержденного провайдером email нельзя ни привязать чужой аккаунт, ни занять адрес новым
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, authErrors.ErrExternalEmailNotVerified
+	}
+	if a.isDirectoryAccount(identity.Email) {
+		return nil, authErrors.ErrExternalLoginForbidden
+	}
+
+	user, err := a.repo.GetUserByLogin(ctx, identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+	if user == nil {
+		user, err = a.createExternalUser(ctx, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		// адрес подтвердил провайдер
+		if err := a.repo.SetEmailVerified(ctx, user.Id); err != nil {
+			return nil, fmt.Errorf("ошибка подтверждения email: %w", err)
+		}
+		user.EmailVerified = true
+	} else {
+		if user.IsArchived {
+			return nil, authErrors.ErrUserArchived
+		}
+		// взлом учетной записи у провайдера не должен открывать доступ к администрированию
+		if user.IsStaff() {
+			return nil, authErrors.ErrExternalLoginForbidden
+		}
+		// неподтвержденный аккаунт мог завести кто угодно, указав чужой адрес. Привязка сделала бы
+		// владельца email соседом того, кто знает пароль и мог настроить passkey или второй фактор
+		if !user.EmailVerified {
+			return nil, authErrors.ErrExternalAccountNotVerified
+		}
+	}
+
+	link.UserId = user.Id
+	if err := a.repo.SaveExternalIdentity(ctx, link); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения внешней учетной записи: %w", err)
+	}
+	slog.Info("external identity linked", "userId", user.Id, "provider", provider)
+	return user, nil
+}
+
+// createExternalUser регистрирует покупателя так же, как /auth/signUp. Пароль случайный и
+// никому не известен - задать свой пользователь может через восстановление пароля
+func (a *Auth) createExternalUser(ctx context.Context, email string) (*domain.User, error) {
+	password, err := randtoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации пароля: %w", err)
+	}
+	user, err := domain.NewUser(email, password, a.hasher, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка хеширования пароля: %w", err)
+	}
+
+	user.Id, err = a.createClient(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("user registered via external provider", "userId", user.Id)
+	return user, nil
+}
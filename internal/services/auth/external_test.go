@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/oidcclient"
+)
+
+const (
+	testProvider      = "google"
+	externalTestLogin = "buyer@example.com"
+)
+
+// fakeProvider - ExternalProvider, который отдает заданного пользователя, если code_verifier
+// соответствует code_challenge из AuthCodeURL
+type fakeProvider struct {
+	identity      oidcclient.Identity
+	codeChallenge string
+}
+
+func (p *fakeProvider) AuthCodeURL(_ context.Context, state, nonce, codeChallenge string) (string, error) {
+	p.codeChallenge = codeChallenge
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode(), nil
+}
+
+func (p *fakeProvider) Exchange(_ context.Context, _, codeVerifier, _ string) (*oidcclient.Identity, error) {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != p.codeChallenge {
+		return nil, oidcclient.ErrExchangeFailed
+	}
+	identity := p.identity
+	return &identity, nil
+}
+
+func newExternalTestAuth(t *testing.T) (*Auth, *memoryRepository, *fakeProvider, *fakeClientService) {
+	t.Helper()
+	provider := &fakeProvider{identity: oidcclient.Identity{Subject: "subject-1", Email: externalTestLogin, EmailVerified: true}}
+	clients := &fakeClientService{}
+	a, repo := newTestAuth(t, withExternalProvider(testProvider, provider), withClientService(clients))
+	return a, repo, provider, clients
+}
+
+// createLocalUser регистрирует пользователя с паролем, как через /auth/signUp
+func createLocalUser(t *testing.T, repo *memoryRepository, login string, roleId int64, emailVerified bool) int64 {
+	t.Helper()
+	userId := repo.addUser(t, login, "local-password-1", roleId)
+	repo.users[userId].EmailVerified = emailVerified
+	return userId
+}
+
+func externalLogin(t *testing.T, a *Auth) (userId int64, err error) {
+	t.Helper()
+	ctx := context.Background()
+	start, err := a.StartExternalLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	response, err := a.FinishExternalLogin(ctx, testProvider, "code", start.State, start.StateBinding)
+	if err != nil {
+		return 0, err
+	}
+	userId, _, _, err = a.jwt.ParseAccessToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	return userId, nil
+}
+
+func TestExternalLoginLinkingRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, repo *memoryRepository, provider *fakeProvider) (userId int64)
+		wantErr  error
+		wantLink bool
+	}{
+		{"verified local account is linked", func(t *testing.T, repo *memoryRepository, _ *fakeProvider) int64 {
+			return createLocalUser(t, repo, externalTestLogin, 1, true)
+		}, nil, true},
+		{"unverified local account is refused", func(t *testing.T, repo *memoryRepository, _ *fakeProvider) int64 {
+			createLocalUser(t, repo, externalTestLogin, 1, false)
+			return 0
+		}, authErrors.ErrExternalAccountNotVerified, false},
+		{"staff account is refused", func(t *testing.T, repo *memoryRepository, _ *fakeProvider) int64 {
+			createLocalUser(t, repo, externalTestLogin, 2, true)
+			return 0
+		}, authErrors.ErrExternalLoginForbidden, false},
+		{"email not verified by provider", func(t *testing.T, repo *memoryRepository, provider *fakeProvider) int64 {
+			createLocalUser(t, repo, externalTestLogin, 1, true)
+			provider.identity.EmailVerified = false
+			return 0
+		}, authErrors.ErrExternalEmailNotVerified, false},
+		{"provider without email", func(t *testing.T, _ *memoryRepository, provider *fakeProvider) int64 {
+			provider.identity.Email = ""
+			return 0
+		}, authErrors.ErrExternalEmailNotVerified, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, provider, _ := newExternalTestAuth(t)
+			wantUserId := tt.prepare(t, repo, provider)
+
+			userId, err := externalLogin(t, a)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && userId != wantUserId {
+				t.Fatalf("logged in as %d, want %d", userId, wantUserId)
+			}
+
+			linked, _ := repo.GetExternalIdentity(context.Background(), testProvider, provider.identity.Subject)
+			if (linked != nil) != tt.wantLink {
+				t.Fatalf("identity linked = %v, want %v", linked != nil, tt.wantLink)
+			}
+		})
+	}
+}
+
+// Привязанная учетная запись входит в свой аккаунт, даже если у провайдера сменился email
+func TestExternalLoginUsesLinkedIdentity(t *testing.T) {
+	a, repo, provider, _ := newExternalTestAuth(t)
+	ownerId := createLocalUser(t, repo, externalTestLogin, 1, true)
+	createLocalUser(t, repo, "other@example.com", 1, true)
+
+	if _, err := externalLogin(t, a); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	provider.identity.Email = "other@example.com"
+
+	userId, err := externalLogin(t, a)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if userId != ownerId {
+		t.Fatalf("logged in as %d, want linked user %d", userId, ownerId)
+	}
+}
+
+func TestExternalLoginCreatesVerifiedCustomer(t *testing.T) {
+	a, repo, _, clients := newExternalTestAuth(t)
+
+	userId, err := externalLogin(t, a)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	user, _ := repo.GetUserWithId(context.Background(), userId)
+	if user == nil || user.Login != externalTestLogin || !user.EmailVerified || user.IsStaff() {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if len(clients.registered) != 1 || clients.registered[0] != userId {
+		t.Fatalf("client service registrations %v, want [%d]", clients.registered, userId)
+	}
+}
+
+func TestFinishExternalLoginStateIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	a, repo, _, _ := newExternalTestAuth(t)
+	createLocalUser(t, repo, externalTestLogin, 1, true)
+
+	start, err := a.StartExternalLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.FinishExternalLogin(ctx, testProvider, "code", start.State, start.StateBinding); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := a.FinishExternalLogin(ctx, testProvider, "code", start.State, start.StateBinding); !errors.Is(err, authErrors.ErrInvalidExternalState) {
+		t.Fatalf("reused state: err = %v, want ErrInvalidExternalState", err)
+	}
+}
+
+// code и state, начатые атакующим, не завершают вход в браузере жертвы без cookie привязки
+func TestFinishExternalLoginRequiresStateBinding(t *testing.T) {
+	ctx := context.Background()
+	a, _, _, _ := newExternalTestAuth(t)
+	// fakeProvider помнит code_challenge последнего входа - атакующий начинает вход вторым
+	victim, err := a.StartExternalLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacker, err := a.StartExternalLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attacker.StateBinding == "" || attacker.StateBinding == attacker.State {
+		t.Fatalf("state binding %q must be a hash of the state", attacker.StateBinding)
+	}
+
+	for _, binding := range []string{"", victim.StateBinding, attacker.State} {
+		if _, err := a.FinishExternalLogin(ctx, testProvider, "code", attacker.State, binding); !errors.Is(err, authErrors.ErrInvalidExternalState) {
+			t.Fatalf("binding %q: got %v, want ErrInvalidExternalState", binding, err)
+		}
+	}
+	// отклоненная попытка не гасит state: браузер, начавший вход, его завершает
+	if _, err := a.FinishExternalLogin(ctx, testProvider, "code", attacker.State, attacker.StateBinding); err != nil {
+		t.Fatalf("finish in the browser that started the login: %v", err)
+	}
+}
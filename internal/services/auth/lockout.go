@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+)
+
+// UnlockUser досрочно снимает блокировку входа с пользователя
+func (a *Auth) UnlockUser(ctx context.Context, userId int64) error {
+	const op = "Auth.UnlockUser"
+
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil {
+		return authErrors.ErrUserNotFound
+	}
+
+	if err := a.guard.Unlock(ctx, user.Login); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("login unlocked", "userId", user.Id)
+	return nil
+}
+
+func (a *Auth) checkLockout(ctx context.Context, login, ip string) error {
+	lockedFor, err := a.guard.Check(ctx, login, ip)
+	if err != nil {
+		// недоступность счетчиков не должна закрывать вход
+		slog.Error("failed to check login lockout", "err", err)
+		return nil
+	}
+	if lockedFor > 0 {
+		minutes := int(math.Ceil(lockedFor.Minutes()))
+		return fmt.Errorf("%w, повторите через %d мин", authErrors.ErrAccountLocked, minutes)
+	}
+	return nil
+}
+
+// loginFailed учитывает неудачную попытку входа и возвращает ошибку неверного логина или пароля
+func (a *Auth) loginFailed(ctx context.Context, login, ip string) error {
+	a.attemptFailed(ctx, login, ip)
+	return authErrors.ErrInvalidUserCredentials
+}
+
+// attemptFailed учитывает неудачную попытку (пароль, код второго фактора) и придерживает ответ
+// на растущую задержку, чтобы перебор шел медленнее еще до блокировки
+func (a *Auth) attemptFailed(ctx context.Context, login, ip string) {
+	delay, err := a.guard.Fail(ctx, login, ip)
+	if err != nil {
+		slog.Error("failed to record login failure", "err", err)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/loginguard"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+const (
+	lockoutTestLogin    = "buyer@example.com"
+	lockoutTestPassword = "buyer-password-1"
+)
+
+func logInFrom(a *Auth, ip, login, password string) error {
+	ctx := context.WithValue(context.Background(), contextkeys.ClientIPCtxKey, ip)
+	_, err := a.Auth(ctx, auth.AuthRequest{Login: login, Password: password}, false)
+	return err
+}
+
+func TestAuthLocksAccountAfterFailures(t *testing.T) {
+	// лимит newTestGuard - 5 неудач на логин
+	a, repo := newTestAuth(t)
+	userId := repo.addUser(t, lockoutTestLogin, lockoutTestPassword, 1)
+
+	for i := range 5 {
+		if err := logInFrom(a, "10.0.0.1", lockoutTestLogin, "wrong-password"); !errors.Is(err, authErrors.ErrInvalidUserCredentials) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidUserCredentials", i+1, err)
+		}
+	}
+	// верный пароль не проходит ни с того же, ни с другого IP
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if err := logInFrom(a, ip, lockoutTestLogin, lockoutTestPassword); !errors.Is(err, authErrors.ErrAccountLocked) {
+			t.Fatalf("correct password from %s: err = %v, want ErrAccountLocked", ip, err)
+		}
+	}
+
+	if err := a.UnlockUser(context.Background(), userId); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := logInFrom(a, "10.0.0.1", lockoutTestLogin, lockoutTestPassword); err != nil {
+		t.Fatalf("log in after unlock: %v", err)
+	}
+}
+
+// перебор паролей к разным логинам с одного IP блокирует этот IP
+func TestAuthLocksIPAfterFailures(t *testing.T) {
+	guard := loginguard.New(loginguard.NewMemoryStore(time.Hour), loginguard.Policy{
+		Window:           time.Hour,
+		LockoutDuration:  time.Hour,
+		MaxLoginFailures: 5,
+		MaxIPFailures:    3,
+	})
+	a, repo := newTestAuth(t, withGuard(guard))
+	repo.addUser(t, lockoutTestLogin, lockoutTestPassword, 1)
+
+	// неизвестные логины считаются так же, как неверные пароли
+	for _, login := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := logInFrom(a, "10.0.0.1", login, "wrong-password"); !errors.Is(err, authErrors.ErrInvalidUserCredentials) {
+			t.Fatalf("%s: err = %v, want ErrInvalidUserCredentials", login, err)
+		}
+	}
+	if err := logInFrom(a, "10.0.0.1", lockoutTestLogin, lockoutTestPassword); !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("from the locked IP: err = %v, want ErrAccountLocked", err)
+	}
+	if err := logInFrom(a, "10.0.0.2", lockoutTestLogin, lockoutTestPassword); err != nil {
+		t.Fatalf("from another IP: %v", err)
+	}
+}
+
+// успешный вход обнуляет счетчик логина: неудачи до него не складываются с последующими
+func TestAuthSuccessResetsLoginFailures(t *testing.T) {
+	a, repo := newTestAuth(t)
+	repo.addUser(t, lockoutTestLogin, lockoutTestPassword, 1)
+
+	for _, password := range []string{"wrong-password", "wrong-password", "wrong-password", "wrong-password", lockoutTestPassword,
+		"wrong-password", "wrong-password", "wrong-password", "wrong-password"} {
+		logInFrom(a, "10.0.0.1", lockoutTestLogin, password)
+	}
+	if err := logInFrom(a, "10.0.0.1", lockoutTestLogin, lockoutTestPassword); err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+// SendMagicLink отправляет на email пользователя одноразовую ссылку для входа без пароля
+func (a *Auth) SendMagicLink(ctx context.Context, login string) error {
+	const op = "Auth.SendMagicLink"
+
+	user, err := a.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil {
+		return authErrors.ErrUserNotFound
+	}
+	if user.IsArchived {
+		return authErrors.ErrUserArchived
+	}
+	if a.isDirectoryAccount(user.Login) {
+		return authErrors.ErrDirectoryAccount
+	}
+
+	token, err := randtoken.New()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+	created, err := a.repo.CreateMagicLinkToken(ctx, &domain.MagicLinkToken{
+		TokenHash: randtoken.Hash(token),
+		UserId:    user.Id,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.config.Email.MagicLinkTTL),
+	}, now.Add(-a.config.Email.MagicLinkWindow), a.config.Email.MagicLinkLimit)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !created {
+		slog.Warn("magic link rate limit exceeded", "userId", user.Id)
+		return authErrors.ErrTooManyMagicLinks
+	}
+
+	magicLink := fmt.Sprintf("%s?token=%s", a.config.Email.FrontendMagicLinkURL, url.QueryEscape(token))
+	err = a.sendEmail(ctx, "/send-magic-link-email", map[string]interface{}{
+		"to":        user.Login,
+		"magicLink": magicLink,
+		"login":     user.Login,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("magic link sent", "userId", user.Id)
+	return nil
+}
+
+// LoginWithMagicLink обменивает токен из письма на пару токенов. Ссылка заменяет только пароль:
+// при включенной двухфакторной аутентификации вход продолжается через /auth/2fa/verify.
+// Переход по ссылке из письма заодно подтверждает email
+func (a *Auth) LoginWithMagicLink(ctx context.Context, token string) (*auth.AuthResponse, error) {
+	const op = "Auth.LoginWithMagicLink"
+
+	magicLink, err := a.repo.ConsumeMagicLinkToken(ctx, randtoken.Hash(token))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if magicLink == nil || magicLink.IsExpired() {
+		return nil, authErrors.ErrInvalidMagicLink
+	}
+
+	user, err := a.getActiveUser(ctx, magicLink.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.EmailVerified {
+		if err := a.repo.SetEmailVerified(ctx, user.Id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		slog.Info("email verified", "userId", user.Id)
+	}
+	if user.TotpEnabled {
+		return a.mfaChallenge(ctx, user)
+	}
+
+	slog.Info("magic link login", "userId", user.Id)
+	return a.completeLogin(ctx, user)
+}
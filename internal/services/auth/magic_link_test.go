@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/config"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+const magicLinkTestLogin = "buyer@example.com"
+
+func newMagicLinkTestAuth(t *testing.T, limit int) (*Auth, *memoryRepository, *emailService, int64) {
+	t.Helper()
+	service, serviceURL := newEmailService(t)
+	a, repo := newTestAuth(t, withConfig(func(cfg *config.Config) {
+		cfg.Email.ServiceURL = serviceURL
+		cfg.Email.FrontendMagicLinkURL = "https://shop.example.com/magic-link"
+		cfg.Email.MagicLinkTTL = time.Minute * 15
+		cfg.Email.MagicLinkLimit = limit
+		cfg.Email.MagicLinkWindow = time.Hour
+	}))
+	return a, repo, service, repo.addUser(t, magicLinkTestLogin, "buyer-password-1", 1)
+}
+
+func sendMagicLink(t *testing.T, a *Auth, emails *emailService) string {
+	t.Helper()
+	if err := a.SendMagicLink(context.Background(), magicLinkTestLogin); err != nil {
+		t.Fatalf("send magic link: %v", err)
+	}
+	return emails.lastToken(t, "/send-magic-link-email", magicLinkTestLogin)
+}
+
+func TestMagicLinkIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	a, repo, emails, userId := newMagicLinkTestAuth(t, 5)
+	token := sendMagicLink(t, a, emails)
+
+	response, err := a.LoginWithMagicLink(ctx, token)
+	if err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if response.AccessToken == "" || response.RefreshToken == "" {
+		t.Fatalf("log in returned %+v, want tokens", response)
+	}
+	// переход по ссылке из письма подтверждает email
+	if !repo.users[userId].EmailVerified {
+		t.Fatal("email was not verified")
+	}
+
+	if _, err := a.LoginWithMagicLink(ctx, token); !errors.Is(err, authErrors.ErrInvalidMagicLink) {
+		t.Fatalf("replayed link: got %v, want ErrInvalidMagicLink", err)
+	}
+}
+
+func TestMagicLinkRejectsInvalidToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(repo *memoryRepository, token string) string
+		wantErr error
+	}{
+		{"unknown token", func(_ *memoryRepository, _ string) string {
+			return "unknown-token"
+		}, authErrors.ErrInvalidMagicLink},
+		{"expired token", func(repo *memoryRepository, token string) string {
+			repo.magicLinks[randtoken.Hash(token)].ExpiresAt = time.Now().Add(-time.Second)
+			return token
+		}, authErrors.ErrInvalidMagicLink},
+		{"archived user", func(repo *memoryRepository, token string) string {
+			repo.users[repo.magicLinks[randtoken.Hash(token)].UserId].IsArchived = true
+			return token
+		}, authErrors.ErrUserArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo, emails, _ := newMagicLinkTestAuth(t, 5)
+			token := tt.prepare(repo, sendMagicLink(t, a, emails))
+
+			if _, err := a.LoginWithMagicLink(context.Background(), token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendMagicLinkLimitsRequestsPerLogin(t *testing.T) {
+	ctx := context.Background()
+	a, _, emails, _ := newMagicLinkTestAuth(t, 2)
+
+	for range 2 {
+		sendMagicLink(t, a, emails)
+	}
+	if err := a.SendMagicLink(ctx, magicLinkTestLogin); !errors.Is(err, authErrors.ErrTooManyMagicLinks) {
+		t.Fatalf("request over limit: got %v, want ErrTooManyMagicLinks", err)
+	}
+	if emails.count() != 2 {
+		t.Fatalf("%d emails sent, want 2", emails.count())
+	}
+
+	// использованные ссылки тоже считаются, иначе лимит обходится входом по каждой
+	if _, err := a.LoginWithMagicLink(ctx, emails.lastToken(t, "/send-magic-link-email", magicLinkTestLogin)); err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if err := a.SendMagicLink(ctx, magicLinkTestLogin); !errors.Is(err, authErrors.ErrTooManyMagicLinks) {
+		t.Fatalf("request after login: got %v, want ErrTooManyMagicLinks", err)
+	}
+}
+
+func TestSendMagicLinkUnknownUser(t *testing.T) {
+	a, _, emails, _ := newMagicLinkTestAuth(t, 5)
+
+	if err := a.SendMagicLink(context.Background(), "unknown@example.com"); !errors.Is(err, authErrors.ErrUserNotFound) {
+		t.Fatalf("got %v, want ErrUserNotFound", err)
+	}
+	if emails.count() != 0 {
+		t.Fatalf("%d emails sent, want 0", emails.count())
+	}
+}
+
+// ссылка заменяет только пароль, второй фактор по-прежнему нужен
+func TestMagicLinkRequiresSecondFactor(t *testing.T) {
+	a, repo, emails, userId := newMagicLinkTestAuth(t, 5)
+	repo.users[userId].TotpEnabled = true
+
+	response, err := a.LoginWithMagicLink(context.Background(), sendMagicLink(t, a, emails))
+	if err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if !response.MFARequired || response.MFAToken == "" || response.AccessToken != "" {
+		t.Fatalf("log in returned %+v, want second factor without tokens", response)
+	}
+}
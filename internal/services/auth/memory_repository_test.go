@@ -0,0 +1,646 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/loginguard"
+	"github.com/phenirain/sso/internal/lib/passwordhash"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
+	"github.com/phenirain/sso/internal/lib/secretbox"
+	api "gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api"
+	pb "gitlab.com/mpt4164636/fourthcoursefirstprojectgroup/proto/generated/api/client"
+	"google.golang.org/grpc"
+)
+
+const (
+	testRPID   = "sso.example.com"
+	testOrigin = "https://sso.example.com"
+)
+
+// testAuthOption меняет зависимости или конфиг сервиса, который собирает newTestAuth
+type testAuthOption func(deps *Deps, cfg *config.Config)
+
+func withConfig(change func(cfg *config.Config)) testAuthOption {
+	return func(_ *Deps, cfg *config.Config) {
+		change(cfg)
+	}
+}
+
+func withGuard(guard LoginGuard) testAuthOption {
+	return func(deps *Deps, _ *config.Config) {
+		deps.Guard = guard
+	}
+}
+
+func withPolicy(policy PasswordPolicy) testAuthOption {
+	return func(deps *Deps, _ *config.Config) {
+		deps.Policy = policy
+	}
+}
+
+func withDenylist(denylist Denylist) testAuthOption {
+	return func(deps *Deps, _ *config.Config) {
+		deps.Denylist = denylist
+	}
+}
+
+func withClientService(clientService pb.ClientServiceClient) testAuthOption {
+	return func(deps *Deps, _ *config.Config) {
+		deps.ClientService = clientService
+	}
+}
+
+func withExternalProvider(name string, provider ExternalProvider) testAuthOption {
+	return func(deps *Deps, _ *config.Config) {
+		deps.External[name] = provider
+	}
+}
+
+// newTestAuth собирает сервис на зависимостях в памяти. Ни одна зависимость не nil, так что
+// тест не упадет на той, которую не настраивал; опции подменяют нужные
+func newTestAuth(t *testing.T, opts ...testAuthOption) (*Auth, *memoryRepository) {
+	t.Helper()
+	repo := newMemoryRepository()
+	deps := Deps{
+		Repo:           repo,
+		Jwt:            newTestJwt(),
+		Denylist:       &memoryDenylist{},
+		Cipher:         newTestCipher(t),
+		Guard:          newTestGuard(5),
+		Policy:         &passwordpolicy.Policy{MinLength: 8, MaxLength: 64},
+		Hasher:         newTestHasher(t),
+		External:       map[string]ExternalProvider{},
+		Authenticators: map[string]Authenticator{},
+		ClientService:  &fakeClientService{},
+	}
+	cfg := &config.Config{
+		MFA:          config.MFAConfig{Issuer: "SSO", ChallengeTTL: time.Minute * 5, MaxAttempts: 5},
+		ExternalAuth: config.ExternalAuthConfig{StateTTL: time.Minute * 10},
+		WebAuthn: config.WebAuthnConfig{
+			RPID:         testRPID,
+			RPName:       "SSO",
+			Origins:      []string{testOrigin},
+			ChallengeTTL: time.Minute,
+		},
+	}
+	for _, opt := range opts {
+		opt(&deps, cfg)
+	}
+	return New(deps, cfg), repo
+}
+
+// addUser сохраняет пользователя с паролем password и ролью roleId
+func (r *memoryRepository) addUser(t *testing.T, login, password string, roleId int64) int64 {
+	t.Helper()
+	user, err := domain.NewUser(login, password, newTestHasher(t), &roleId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId, err := r.CreateUser(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userId
+}
+
+// memoryRepository - Repository в памяти для тестов сервиса. Методы, которые тесты не
+// используют, не реализованы: вызов упадет на nil встроенного интерфейса
+type memoryRepository struct {
+	Repository
+
+	mu            sync.Mutex
+	users         map[int64]*domain.User
+	refreshTokens map[string]*domain.RefreshToken
+	sessions      map[string]*domain.Session
+	challenges    map[string]*domain.LoginChallenge
+	recoveryCodes []domain.RecoveryCode
+	webauthn      map[string]*domain.WebAuthnChallenge
+	passkeys      map[string]*domain.Passkey
+	externalState map[string]*domain.ExternalAuthState
+	identities    map[string]*domain.ExternalIdentity
+	magicLinks    map[string]*domain.MagicLinkToken
+	resetTokens   map[string]*domain.PasswordResetToken
+
+	beforePasskeyUpdate func(id string)
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		users:         map[int64]*domain.User{},
+		refreshTokens: map[string]*domain.RefreshToken{},
+		sessions:      map[string]*domain.Session{},
+		challenges:    map[string]*domain.LoginChallenge{},
+		webauthn:      map[string]*domain.WebAuthnChallenge{},
+		passkeys:      map[string]*domain.Passkey{},
+		externalState: map[string]*domain.ExternalAuthState{},
+		identities:    map[string]*domain.ExternalIdentity{},
+		magicLinks:    map[string]*domain.MagicLinkToken{},
+		resetTokens:   map[string]*domain.PasswordResetToken{},
+	}
+}
+
+func (r *memoryRepository) GetUserByLogin(_ context.Context, login string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Login == login {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRepository) GetUserWithId(_ context.Context, uid int64) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[uid]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryRepository) CreateUser(_ context.Context, user *domain.User) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *user
+	copied.Id = int64(len(r.users) + 1)
+	if copied.PasswordChangedAt.IsZero() {
+		copied.PasswordChangedAt = time.Now()
+	}
+	r.users[copied.Id] = &copied
+	return copied.Id, nil
+}
+
+func (r *memoryRepository) UpdatePassword(_ context.Context, login, newPasswordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Login == login {
+			user.PasswordHash = []byte(newPasswordHash)
+			user.PasswordChangedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *token
+	r.refreshTokens[token.Id] = &copied
+	return nil
+}
+
+func (r *memoryRepository) GetRefreshToken(_ context.Context, id string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.refreshTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *memoryRepository) RevokeRefreshToken(_ context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.refreshTokens[id]
+	if !ok || token.Revoked {
+		return false, nil
+	}
+	token.Revoked = true
+	return true, nil
+}
+
+func (r *memoryRepository) RevokeRefreshTokenFamily(_ context.Context, family string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.refreshTokens {
+		if token.Family == family {
+			token.Revoked = true
+		}
+	}
+	if session, ok := r.sessions[family]; ok {
+		session.Revoked = true
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetRefreshTokensByFamily(_ context.Context, family string) ([]domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tokens []domain.RefreshToken
+	for _, token := range r.refreshTokens {
+		if token.Family == family {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+// GetRefreshTokensByUser, как и запрос в базу, возвращает и отозванные токены: access токены,
+// выданные в паре с уже замененными refresh токенами, еще живы
+func (r *memoryRepository) GetRefreshTokensByUser(_ context.Context, userId int64) ([]domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tokens []domain.RefreshToken
+	for _, token := range r.refreshTokens {
+		if token.UserId == userId {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryRepository) RevokeUserRefreshTokens(_ context.Context, userId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.refreshTokens {
+		if token.UserId == userId {
+			token.Revoked = true
+		}
+	}
+	for _, session := range r.sessions {
+		if session.UserId == userId {
+			session.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) SaveSession(_ context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.sessions[session.Id]; ok {
+		stored.LastSeenAt = session.LastSeenAt
+		stored.ExpiresAt = session.ExpiresAt
+		return nil
+	}
+	copied := *session
+	r.sessions[session.Id] = &copied
+	return nil
+}
+
+func (r *memoryRepository) GetSession(_ context.Context, id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *memoryRepository) GetActiveSessions(_ context.Context, userId int64) ([]domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := []domain.Session{}
+	for _, session := range r.sessions {
+		if session.UserId == userId && session.IsActive() {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (r *memoryRepository) CreateLoginChallenge(_ context.Context, challenge *domain.LoginChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *challenge
+	r.challenges[challenge.Id] = &copied
+	return nil
+}
+
+func (r *memoryRepository) UseLoginChallenge(_ context.Context, id, purpose string, maxAttempts int) (*domain.LoginChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	challenge, ok := r.challenges[id]
+	if !ok || challenge.Purpose != purpose || challenge.Attempts >= maxAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil
+	}
+	challenge.Attempts++
+	copied := *challenge
+	return &copied, nil
+}
+
+func (r *memoryRepository) DeleteLoginChallenge(_ context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.challenges[id]
+	delete(r.challenges, id)
+	return ok, nil
+}
+
+func (r *memoryRepository) UseTotpStep(_ context.Context, userId, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[userId]
+	if !ok || step <= user.TotpLastStep {
+		return false, nil
+	}
+	user.TotpLastStep = step
+	return true, nil
+}
+
+func (r *memoryRepository) GetUnusedRecoveryCodes(_ context.Context, userId int64) ([]domain.RecoveryCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var codes []domain.RecoveryCode
+	for _, code := range r.recoveryCodes {
+		if code.UserId == userId && code.UsedAt == nil {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *memoryRepository) UseRecoveryCode(_ context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.recoveryCodes {
+		if r.recoveryCodes[i].Id == id && r.recoveryCodes[i].UsedAt == nil {
+			now := time.Now()
+			r.recoveryCodes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) CreateWebAuthnChallenge(_ context.Context, challenge *domain.WebAuthnChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *challenge
+	r.webauthn[challenge.ChallengeHash] = &copied
+	return nil
+}
+
+func (r *memoryRepository) ConsumeWebAuthnChallenge(_ context.Context, challengeHash string) (*domain.WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	challenge, ok := r.webauthn[challengeHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.webauthn, challengeHash)
+	return challenge, nil
+}
+
+func (r *memoryRepository) CreatePasskey(_ context.Context, passkey *domain.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *passkey
+	r.passkeys[passkey.Id] = &copied
+	return nil
+}
+
+func (r *memoryRepository) GetPasskey(_ context.Context, id string) (*domain.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	passkey, ok := r.passkeys[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *passkey
+	return &copied, nil
+}
+
+func (r *memoryRepository) GetUserPasskeys(_ context.Context, userId int64) ([]domain.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	passkeys := []domain.Passkey{}
+	for _, passkey := range r.passkeys {
+		if passkey.UserId == userId {
+			passkeys = append(passkeys, *passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+// UpdatePasskeyUsage, как и запрос в базу, сохраняет счетчик, только если он вырос.
+// beforePasskeyUpdate позволяет тесту вклиниться между проверкой счетчика в сервисе и записью
+func (r *memoryRepository) UpdatePasskeyUsage(_ context.Context, id string, signCount int64) (bool, error) {
+	if r.beforePasskeyUpdate != nil {
+		r.beforePasskeyUpdate(id)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	passkey, ok := r.passkeys[id]
+	if !ok || !(passkey.SignCount < signCount || passkey.SignCount == 0 && signCount == 0) {
+		return false, nil
+	}
+	now := time.Now()
+	passkey.SignCount, passkey.LastUsedAt = signCount, &now
+	return true, nil
+}
+
+func (r *memoryRepository) SetEmailVerified(_ context.Context, userId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[userId]; ok {
+		user.EmailVerified = true
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateExternalAuthState(_ context.Context, state *domain.ExternalAuthState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *state
+	r.externalState[state.StateHash] = &copied
+	return nil
+}
+
+func (r *memoryRepository) ConsumeExternalAuthState(_ context.Context, stateHash string) (*domain.ExternalAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.externalState[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.externalState, stateHash)
+	return state, nil
+}
+
+func (r *memoryRepository) GetExternalIdentity(_ context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity, ok := r.identities[provider+"/"+subject]
+	if !ok {
+		return nil, nil
+	}
+	copied := *identity
+	return &copied, nil
+}
+
+func (r *memoryRepository) SaveExternalIdentity(_ context.Context, identity *domain.ExternalIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *identity
+	r.identities[identity.Provider+"/"+identity.Subject] = &copied
+	return nil
+}
+
+func (r *memoryRepository) CreateMagicLinkToken(_ context.Context, token *domain.MagicLinkToken, since time.Time, limit int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, stored := range r.magicLinks {
+		if stored.UserId == token.UserId && !stored.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	if count >= limit {
+		return false, nil
+	}
+	copied := *token
+	r.magicLinks[token.TokenHash] = &copied
+	return true, nil
+}
+
+func (r *memoryRepository) ConsumeMagicLinkToken(_ context.Context, tokenHash string) (*domain.MagicLinkToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.magicLinks[tokenHash]
+	if !ok || token.UsedAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	copied := *token
+	return &copied, nil
+}
+
+func (r *memoryRepository) CreatePasswordResetToken(_ context.Context, token *domain.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, stored := range r.resetTokens {
+		if stored.UserId == token.UserId {
+			delete(r.resetTokens, hash)
+		}
+	}
+	copied := *token
+	r.resetTokens[token.TokenHash] = &copied
+	return nil
+}
+
+func (r *memoryRepository) GetPasswordResetToken(_ context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.resetTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *memoryRepository) ConsumePasswordResetToken(_ context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.resetTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.resetTokens, tokenHash)
+	return token, nil
+}
+
+// memoryDenylist запоминает отозванные access токены
+type memoryDenylist struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func (d *memoryDenylist) Revoke(_ context.Context, tokenId string, _ time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.revoked == nil {
+		d.revoked = map[string]bool{}
+	}
+	d.revoked[tokenId] = true
+	return nil
+}
+
+func (d *memoryDenylist) isRevoked(tokenId string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.revoked[tokenId]
+}
+
+func newTestJwt() *jwt.JwtLib {
+	return jwt.NewJwtLib(jwt.Options{
+		Issuer:               "sso-test",
+		Audience:             "sso-test",
+		AccessDuration:       time.Minute * 15,
+		RefreshDuration:      time.Hour,
+		VerificationDuration: time.Hour,
+		MFADuration:          time.Minute * 5,
+		Leeway:               time.Second * 5,
+	}, jwt.NewKeyring(jwt.NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+}
+
+func newTestCipher(t *testing.T) *secretbox.Box {
+	t.Helper()
+	key := sha256.Sum256([]byte("sso-test"))
+	box, err := secretbox.New(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+// newTestHasher - bcrypt с минимальной стоимостью, чтобы тесты не тратили время на хеширование
+func newTestHasher(t *testing.T) *passwordhash.Hasher {
+	t.Helper()
+	hasher, err := passwordhash.New(passwordhash.Config{
+		Algorithm:  passwordhash.AlgorithmBcrypt,
+		BcryptCost: 4,
+		Argon2id:   passwordhash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+// newTestGuard блокирует логин после maxLoginFailures неудач без задержек между попытками
+func newTestGuard(maxLoginFailures int) *loginguard.Guard {
+	return loginguard.New(loginguard.NewMemoryStore(time.Hour), loginguard.Policy{
+		Window:           time.Hour,
+		LockoutDuration:  time.Hour,
+		MaxLoginFailures: maxLoginFailures,
+		MaxIPFailures:    1000,
+	})
+}
+
+// fakeClientService запоминает зарегистрированных покупателей
+type fakeClientService struct {
+	pb.ClientServiceClient
+
+	mu         sync.Mutex
+	registered []int64
+}
+
+func (s *fakeClientService) RegisterClient(_ context.Context, in *api.ClientRequest, _ ...grpc.CallOption) (*api.ClientResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registered = append(s.registered, *in.UserId)
+	return &api.ClientResponse{}, nil
+}
@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/totp"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// SetupTOTP генерирует новый секрет TOTP. Двухфакторная аутентификация включится
+// только после ConfirmTOTP с кодом из приложения
+func (a *Auth) SetupTOTP(ctx context.Context, userId int64) (*auth.TOTPSetupResponse, error) {
+	const op = "Auth.SetupTOTP"
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return nil, authErrors.ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sealed, err := a.cipher.Seal([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.repo.SetTotpSecret(ctx, user.Id, sealed); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &auth.TOTPSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(a.config.MFA.Issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает двухфакторную аутентификацию, если код соответствует выданному секрету,
+// и выдает коды восстановления
+func (a *Auth) ConfirmTOTP(ctx context.Context, userId int64, code string) (*auth.RecoveryCodesResponse, error) {
+	const op = "Auth.ConfirmTOTP"
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return nil, authErrors.ErrTOTPAlreadyEnabled
+	}
+
+	step, err := a.validateTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.repo.EnableTotp(ctx, user.Id, step); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("totp enabled", "userId", user.Id)
+	return a.issueRecoveryCodes(ctx, user.Id)
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления, прежние перестают действовать
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userId int64) (*auth.RecoveryCodesResponse, error) {
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, authErrors.ErrTOTPNotSetUp
+	}
+	return a.issueRecoveryCodes(ctx, user.Id)
+}
+
+// GetRecoveryCodesStatus возвращает число неиспользованных кодов восстановления
+func (a *Auth) GetRecoveryCodesStatus(ctx context.Context, userId int64) (*auth.RecoveryCodesStatusResponse, error) {
+	const op = "Auth.GetRecoveryCodesStatus"
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := a.repo.GetUnusedRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &auth.RecoveryCodesStatusResponse{
+		Enabled:   user.TotpEnabled,
+		Remaining: len(codes),
+	}, nil
+}
+
+// VerifyMFA завершает вход: обменивает токен ожидания второго фактора и код TOTP
+// или код восстановления на пару токенов. Неверные коды считаются в loginguard по логину
+// пользователя, а по одному токену можно сделать не больше mfa.max_attempts попыток
+func (a *Auth) VerifyMFA(ctx context.Context, mfaToken, code string) (*auth.AuthResponse, error) {
+	const op = "Auth.VerifyMFA"
+
+	userId, _, tokenId, err := a.jwt.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, authErrors.ErrInvalidMFAToken
+	}
+	challenge, err := a.repo.UseLoginChallenge(ctx, tokenId, domain.LoginChallengeMFA, a.config.MFA.MaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if challenge == nil || challenge.UserId != userId {
+		return nil, authErrors.ErrInvalidMFAToken
+	}
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := a.checkLockout(ctx, user.Login, ip); err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, authErrors.ErrTOTPNotSetUp
+	}
+
+	remaining := -1
+	if isTOTPCode(code) {
+		err = a.useTOTPCode(ctx, user, code)
+	} else {
+		remaining, err = a.useRecoveryCode(ctx, user, code)
+	}
+	if err != nil {
+		if errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			a.attemptFailed(ctx, user.Login, ip)
+		}
+		return nil, err
+	}
+
+	// второй фактор пройден: токен ожидания больше не действует
+	deleted, err := a.repo.DeleteLoginChallenge(ctx, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !deleted {
+		return nil, authErrors.ErrInvalidMFAToken
+	}
+	if err := a.guard.Succeed(ctx, user.Login); err != nil {
+		slog.Error("failed to reset login failures", "err", err)
+	}
+
+	response, err := a.completeLogin(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if remaining >= 0 {
+		response.RecoveryCodesLeft = &remaining
+	}
+	return response, nil
+}
+
+// useTOTPCode проверяет код из приложения и запоминает его шаг, чтобы код нельзя было повторить
+func (a *Auth) useTOTPCode(ctx context.Context, user *domain.User, code string) error {
+	const op = "Auth.useTOTPCode"
+
+	step, err := a.validateTOTP(user, code)
+	if err != nil {
+		return err
+	}
+	fresh, err := a.repo.UseTotpStep(ctx, user.Id, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !fresh {
+		slog.Warn("totp code reused", "userId", user.Id)
+		return authErrors.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// useRecoveryCode гасит код восстановления и возвращает, сколько кодов осталось
+func (a *Auth) useRecoveryCode(ctx context.Context, user *domain.User, code string) (int, error) {
+	const op = "Auth.useRecoveryCode"
+
+	// bcrypt дорогой: строку не того формата отбрасываем сразу
+	if !domain.IsRecoveryCodeFormat(code) {
+		return 0, authErrors.ErrInvalidTOTPCode
+	}
+	codes, err := a.repo.GetUnusedRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	match := findRecoveryCode(codes, code)
+	if match == nil {
+		return 0, authErrors.ErrInvalidTOTPCode
+	}
+	used, err := a.repo.UseRecoveryCode(ctx, match.Id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if !used {
+		return 0, authErrors.ErrInvalidTOTPCode
+	}
+
+	remaining := len(codes) - 1
+	slog.Warn("recovery code used", "userId", user.Id, "remaining", remaining)
+	return remaining, nil
+}
+
+// findRecoveryCode ищет код среди неиспользованных. bcrypt считается только для кандидатов
+// с тем же префиксом - обычно это один код
+func findRecoveryCode(codes []domain.RecoveryCode, code string) *domain.RecoveryCode {
+	lookup := domain.RecoveryCodeLookup(code)
+	for i := range codes {
+		if codes[i].IsCandidate(lookup) && codes[i].Matches(code) {
+			return &codes[i]
+		}
+	}
+	return nil
+}
+
+func (a *Auth) issueRecoveryCodes(ctx context.Context, userId int64) (*auth.RecoveryCodesResponse, error) {
+	codes, records, err := domain.NewRecoveryCodes(userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации кодов восстановления: %w", err)
+	}
+	if err := a.repo.ReplaceRecoveryCodes(ctx, userId, records); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения кодов восстановления: %w", err)
+	}
+
+	slog.Info("recovery codes issued", "userId", userId)
+	return &auth.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// mfaChallenge выдает токен ожидания второго фактора. Его jti хранится в базе: после успешного
+// кода запись удаляется, и тот же токен больше не примет ни одного кода
+func (a *Auth) mfaChallenge(ctx context.Context, user *domain.User) (*auth.AuthResponse, error) {
+	tokenId, err := a.createLoginChallenge(ctx, user.Id, domain.LoginChallengeMFA)
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.jwt.NewMFAToken(user.Id, user.RoleId, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации токена второго фактора: %w", err)
+	}
+	return &auth.AuthResponse{
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+// createLoginChallenge сохраняет незавершенный вход и возвращает jti для его токена.
+// Запись живет столько же, сколько токен
+func (a *Auth) createLoginChallenge(ctx context.Context, userId int64, purpose string) (string, error) {
+	now := time.Now()
+	challenge := &domain.LoginChallenge{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.config.MFA.ChallengeTTL),
+	}
+	if err := a.repo.CreateLoginChallenge(ctx, challenge); err != nil {
+		return "", fmt.Errorf("ошибка сохранения незавершенного входа: %w", err)
+	}
+	return challenge.Id, nil
+}
+
+// validateTOTP расшифровывает секрет пользователя и проверяет код, возвращая его шаг
+func (a *Auth) validateTOTP(user *domain.User, code string) (int64, error) {
+	if len(user.TotpSecret) == 0 {
+		return 0, authErrors.ErrTOTPNotSetUp
+	}
+
+	secret, err := a.cipher.Open(user.TotpSecret)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка расшифровки секрета TOTP: %w", err)
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now())
+	if !ok || step <= user.TotpLastStep {
+		return 0, authErrors.ErrInvalidTOTPCode
+	}
+	return step, nil
+}
+
+func (a *Auth) getActiveUser(ctx context.Context, userId int64) (*domain.User, error) {
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	if user.IsArchived {
+		return nil, authErrors.ErrUserArchived
+	}
+	return user, nil
+}
+
+// isTOTPCode отличает код из приложения (только цифры) от кода восстановления
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
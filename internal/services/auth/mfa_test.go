@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/totp"
+)
+
+const (
+	mfaTestLogin    = "user@example.com"
+	mfaTestPassword = "correct horse battery staple"
+)
+
+type mfaTestEnv struct {
+	auth          *Auth
+	repo          *memoryRepository
+	secret        string
+	recoveryCodes []string
+}
+
+// newMFATestAuth создает пользователя с включенным TOTP. maxLoginFailures - лимит loginguard
+// на логин, maxAttempts - лимит попыток по одному токену ожидания второго фактора
+func newMFATestAuth(t *testing.T, maxLoginFailures, maxAttempts int) *mfaTestEnv {
+	t.Helper()
+	a, repo := newTestAuth(t,
+		withGuard(newTestGuard(maxLoginFailures)),
+		withConfig(func(cfg *config.Config) {
+			cfg.MFA.MaxAttempts = maxAttempts
+		}),
+	)
+	userId := repo.addUser(t, mfaTestLogin, mfaTestPassword, 1)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := a.cipher.Seal([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.users[userId].TotpSecret, repo.users[userId].TotpEnabled = sealed, true
+
+	codes, records, err := domain.NewRecoveryCodes(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range records {
+		records[i].Id = int64(i + 1)
+	}
+	repo.recoveryCodes = records
+	return &mfaTestEnv{auth: a, repo: repo, secret: secret, recoveryCodes: codes}
+}
+
+func (e *mfaTestEnv) logIn(t *testing.T) string {
+	t.Helper()
+	response, err := e.auth.Auth(context.Background(), auth.AuthRequest{Login: mfaTestLogin, Password: mfaTestPassword}, false)
+	if err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if !response.MFARequired || response.MFAToken == "" {
+		t.Fatal("second factor was not requested")
+	}
+	return response.MFAToken
+}
+
+func (e *mfaTestEnv) code(t *testing.T) string {
+	t.Helper()
+	code, err := totp.Code(e.secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// wrongCode - код из приложения, который не совпадает с текущим
+func (e *mfaTestEnv) wrongCode(t *testing.T) string {
+	t.Helper()
+	if e.code(t) == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestVerifyMFALocksOutAfterFailures(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestAuth(t, 3, 10)
+	token := env.logIn(t)
+
+	// неверные коды из приложения и неверные коды восстановления считаются одинаково
+	wrong := []string{env.wrongCode(t), "aaaaa-aaaaa", env.wrongCode(t)}
+	for _, code := range wrong {
+		if _, err := env.auth.VerifyMFA(ctx, token, code); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code %q: got %v, want ErrInvalidTOTPCode", code, err)
+		}
+	}
+
+	if _, err := env.auth.VerifyMFA(ctx, token, env.code(t)); !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("correct code after lockout: got %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestVerifyMFAPasswordDoesNotResetFailures(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestAuth(t, 3, 10)
+
+	token := env.logIn(t)
+	for range 2 {
+		if _, err := env.auth.VerifyMFA(ctx, token, env.wrongCode(t)); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code: got %v", err)
+		}
+	}
+
+	// верный пароль не обнуляет счетчик: иначе перебор кодов шел бы через повторный вход
+	token = env.logIn(t)
+	if _, err := env.auth.VerifyMFA(ctx, token, env.wrongCode(t)); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+		t.Fatalf("wrong code: got %v", err)
+	}
+	if _, err := env.auth.VerifyMFA(ctx, token, env.code(t)); !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("correct code after lockout: got %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestVerifyMFATokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestAuth(t, 5, 5)
+	token := env.logIn(t)
+
+	response, err := env.auth.VerifyMFA(ctx, token, env.recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if response.AccessToken == "" {
+		t.Fatal("tokens were not issued")
+	}
+	if response.RecoveryCodesLeft == nil || *response.RecoveryCodesLeft != domain.RecoveryCodesCount-1 {
+		t.Fatalf("recovery codes left: %v", response.RecoveryCodesLeft)
+	}
+
+	if _, err := env.auth.VerifyMFA(ctx, token, env.recoveryCodes[1]); !errors.Is(err, authErrors.ErrInvalidMFAToken) {
+		t.Fatalf("reused token: got %v, want ErrInvalidMFAToken", err)
+	}
+}
+
+func TestVerifyMFALimitsAttemptsPerToken(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestAuth(t, 100, 2)
+	token := env.logIn(t)
+
+	for range 2 {
+		if _, err := env.auth.VerifyMFA(ctx, token, env.wrongCode(t)); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code: got %v", err)
+		}
+	}
+	if _, err := env.auth.VerifyMFA(ctx, token, env.code(t)); !errors.Is(err, authErrors.ErrInvalidMFAToken) {
+		t.Fatalf("token after attempt limit: got %v, want ErrInvalidMFAToken", err)
+	}
+
+	// с новым токеном верный код проходит
+	if _, err := env.auth.VerifyMFA(ctx, env.logIn(t), env.code(t)); err != nil {
+		t.Fatalf("verify with new token: %v", err)
+	}
+}
+
+func TestVerifyMFAResetsFailuresOnSuccess(t *testing.T) {
+	ctx := context.Background()
+	env := newMFATestAuth(t, 3, 10)
+
+	token := env.logIn(t)
+	for range 2 {
+		if _, err := env.auth.VerifyMFA(ctx, token, env.wrongCode(t)); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code: got %v", err)
+		}
+	}
+	if _, err := env.auth.VerifyMFA(ctx, token, env.recoveryCodes[0]); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// после пройденного второго фактора счетчик начинается заново
+	token = env.logIn(t)
+	for range 2 {
+		if _, err := env.auth.VerifyMFA(ctx, token, env.wrongCode(t)); !errors.Is(err, authErrors.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code after reset: got %v", err)
+		}
+	}
+}
+
+func TestFindRecoveryCodeComparesOnlyCandidates(t *testing.T) {
+	codes, records, err := domain.NewRecoveryCodes(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range records {
+		records[i].Id = int64(i + 1)
+	}
+	last := len(records) - 1
+	// остальные записи совпали бы с кодом по bcrypt, но префикс у них другой - сравниваться они не должны
+	for i := range last {
+		records[i].CodeHash = records[last].CodeHash
+		records[i].Lookup = "zzzz"
+	}
+
+	match := findRecoveryCode(records, codes[last])
+	if match == nil || match.Id != records[last].Id {
+		t.Fatalf("found %v, want record %d", match, records[last].Id)
+	}
+
+	// у кодов, выданных до появления префикса, он пустой - они проверяются как раньше
+	records[last].Lookup = ""
+	if match := findRecoveryCode(records[last:], strings.ToUpper(codes[last])); match == nil {
+		t.Fatal("legacy code without lookup was not matched")
+	}
+}
+
+func TestRecoveryCodeFormat(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"abcde-fghij", true},
+		{"ABCDE FGHIJ", true},
+		{"abcdefghij", true},
+		{"abcde-fghi", false},
+		{"abcde-fghijk", false},
+		// 0, 1, l и o не входят в алфавит кодов
+		{"abcde-fgh0j", false},
+		{"abcde-fghlj", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := domain.IsRecoveryCodeFormat(tt.code); got != tt.want {
+			t.Errorf("IsRecoveryCodeFormat(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
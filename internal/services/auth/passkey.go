@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+	"github.com/phenirain/sso/internal/lib/webauthn"
+)
+
+// BeginPasskeyRegistration выдает параметры navigator.credentials.create() для авторизованного пользователя
+func (a *Auth) BeginPasskeyRegistration(ctx context.Context, userId int64) (*auth.PasskeyCreationOptions, error) {
+	const op = "Auth.BeginPasskeyRegistration"
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := a.repo.GetUserPasskeys(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	challenge, err := a.newWebAuthnChallenge(ctx, &user.Id, webauthn.CeremonyCreate)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	params := make([]auth.PasskeyCredentialParameter, 0, len(webauthn.SupportedAlgorithms))
+	for _, alg := range webauthn.SupportedAlgorithms {
+		params = append(params, auth.PasskeyCredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return &auth.PasskeyCreationOptions{
+		Challenge: challenge,
+		RP: auth.PasskeyRelyingParty{
+			ID:   a.config.WebAuthn.RPID,
+			Name: a.config.WebAuthn.RPName,
+		},
+		User: auth.PasskeyUser{
+			ID:          userHandle(user.Id),
+			Name:        user.Login,
+			DisplayName: user.Login,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            a.config.WebAuthn.ChallengeTTL.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: credentialDescriptors(passkeys),
+		AuthenticatorSelection: auth.PasskeyAuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+	}, nil
+}
+
+// FinishPasskeyRegistration проверяет ответ аутентификатора и сохраняет новый passkey
+func (a *Auth) FinishPasskeyRegistration(ctx context.Context, userId int64, req auth.PasskeyRegistrationRequest) (*auth.PasskeyResponse, error) {
+	const op = "Auth.FinishPasskeyRegistration"
+
+	rp := a.relyingParty()
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.Response.ClientDataJSON)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	clientData, err := rp.ParseClientData(clientDataJSON, webauthn.CeremonyCreate)
+	if err != nil {
+		slog.Warn("passkey registration rejected", "userId", userId, "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	challenge, err := a.consumeWebAuthnChallenge(ctx, clientData.Challenge, webauthn.CeremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserId == nil || *challenge.UserId != userId {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	attestationObject, err := webauthn.DecodeBase64URL(req.Response.AttestationObject)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	credential, err := rp.VerifyRegistration(attestationObject)
+	if err != nil {
+		slog.Warn("passkey registration rejected", "userId", userId, "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	if !credential.UserVerified {
+		return nil, authErrors.ErrPasskeyUserNotVerified
+	}
+
+	passkey := &domain.Passkey{
+		Id:         webauthn.EncodeBase64URL(credential.ID),
+		UserId:     userId,
+		PublicKey:  credential.PublicKey,
+		SignCount:  int64(credential.SignCount),
+		Transports: req.Response.Transports,
+		Name:       req.Name,
+		CreatedAt:  time.Now(),
+	}
+	if passkey.Transports == nil {
+		passkey.Transports = []string{}
+	}
+
+	existing, err := a.repo.GetPasskey(ctx, passkey.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if existing != nil {
+		return nil, authErrors.ErrPasskeyAlreadyRegistered
+	}
+	if err := a.repo.CreatePasskey(ctx, passkey); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("passkey registered", "userId", userId, "passkeyId", passkey.Id, "alg", credential.Algorithm)
+	return toPasskeyResponse(*passkey), nil
+}
+
+// BeginPasskeyLogin выдает параметры navigator.credentials.get(). Если логин указан и известен,
+// браузеру перечисляются passkey этого пользователя, иначе он предложит любой passkey сайта.
+// Неизвестный логин не раскрывается
+func (a *Auth) BeginPasskeyLogin(ctx context.Context, login string) (*auth.PasskeyRequestOptions, error) {
+	const op = "Auth.BeginPasskeyLogin"
+
+	var userId *int64
+	passkeys := []domain.Passkey{}
+	if login != "" {
+		user, err := a.repo.GetUserByLogin(ctx, login)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if user != nil && !user.IsArchived {
+			userId = &user.Id
+			passkeys, err = a.repo.GetUserPasskeys(ctx, user.Id)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	challenge, err := a.newWebAuthnChallenge(ctx, userId, webauthn.CeremonyGet)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &auth.PasskeyRequestOptions{
+		Challenge:        challenge,
+		RPID:             a.config.WebAuthn.RPID,
+		Timeout:          a.config.WebAuthn.ChallengeTTL.Milliseconds(),
+		UserVerification: "required",
+		AllowCredentials: credentialDescriptors(passkeys),
+	}, nil
+}
+
+// FinishPasskeyLogin проверяет подпись аутентификатора и выдает пару токенов, как /auth/logIn
+func (a *Auth) FinishPasskeyLogin(ctx context.Context, req auth.PasskeyLoginRequest) (*auth.AuthResponse, error) {
+	const op = "Auth.FinishPasskeyLogin"
+
+	rp := a.relyingParty()
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.Response.ClientDataJSON)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	clientData, err := rp.ParseClientData(clientDataJSON, webauthn.CeremonyGet)
+	if err != nil {
+		slog.Warn("passkey login rejected", "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+
+	challenge, err := a.consumeWebAuthnChallenge(ctx, clientData.Challenge, webauthn.CeremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialId, err := webauthn.DecodeBase64URL(req.RawID)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	passkey, err := a.repo.GetPasskey(ctx, webauthn.EncodeBase64URL(credentialId))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if passkey == nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	// challenge выдан под конкретного пользователя - passkey должен быть его
+	if challenge.UserId != nil && *challenge.UserId != passkey.UserId {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	if req.Response.UserHandle != "" {
+		handle, err := webauthn.DecodeBase64URL(req.Response.UserHandle)
+		if err != nil || string(handle) != strconv.FormatInt(passkey.UserId, 10) {
+			return nil, authErrors.ErrInvalidPasskey
+		}
+	}
+
+	authData, err := webauthn.DecodeBase64URL(req.Response.AuthenticatorData)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	signature, err := webauthn.DecodeBase64URL(req.Response.Signature)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	assertion, err := rp.VerifyAssertion(passkey.PublicKey, authData, clientDataJSON, signature)
+	if err != nil {
+		slog.Warn("passkey login rejected", "passkeyId", passkey.Id, "err", err)
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	// вход по passkey заменяет и пароль, и TOTP, поэтому без проверки PIN-кода или биометрии
+	// (флаг UV) его не принимаем: иначе украденного устройства хватило бы для входа
+	if !assertion.UserVerified {
+		slog.Warn("passkey login without user verification", "passkeyId", passkey.Id)
+		return nil, authErrors.ErrPasskeyUserNotVerified
+	}
+
+	// счетчик должен расти; если не вырос - ключ мог быть клонирован.
+	// Аутентификаторы без счетчика всегда присылают 0
+	signCount := int64(assertion.SignCount)
+	if (signCount != 0 || passkey.SignCount != 0) && signCount <= passkey.SignCount {
+		slog.Warn("passkey sign counter did not increase", "passkeyId", passkey.Id, "stored", passkey.SignCount, "received", signCount)
+		return nil, authErrors.ErrPasskeyCloned
+	}
+
+	user, err := a.getActiveUser(ctx, passkey.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if a.config.Email.RequireVerification && !user.EmailVerified {
+		return nil, authErrors.ErrEmailNotVerified
+	}
+	// иначе сотрудник, отключенный в каталоге, продолжал бы входить по passkey
+	if a.isDirectoryAccount(user.Login) {
+		return nil, authErrors.ErrDirectoryAccount
+	}
+
+	// счетчик сохраняется условно: одновременный вход с тем же счетчиком успел раньше
+	updated, err := a.repo.UpdatePasskeyUsage(ctx, passkey.Id, signCount)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !updated {
+		slog.Warn("passkey sign counter was already used", "passkeyId", passkey.Id, "received", signCount)
+		return nil, authErrors.ErrPasskeyCloned
+	}
+
+	slog.Info("passkey login", "userId", user.Id, "passkeyId", passkey.Id)
+	return a.completeLogin(ctx, user)
+}
+
+func (a *Auth) GetPasskeys(ctx context.Context, userId int64) ([]auth.PasskeyResponse, error) {
+	const op = "Auth.GetPasskeys"
+
+	passkeys, err := a.repo.GetUserPasskeys(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]auth.PasskeyResponse, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		result = append(result, *toPasskeyResponse(passkey))
+	}
+	return result, nil
+}
+
+func (a *Auth) DeletePasskey(ctx context.Context, userId int64, passkeyId string) error {
+	const op = "Auth.DeletePasskey"
+
+	deleted, err := a.repo.DeletePasskey(ctx, userId, passkeyId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !deleted {
+		return authErrors.ErrPasskeyNotFound
+	}
+
+	slog.Info("passkey deleted", "userId", userId, "passkeyId", passkeyId)
+	return nil
+}
+
+func (a *Auth) relyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{
+		ID:      a.config.WebAuthn.RPID,
+		Origins: a.config.WebAuthn.Origins,
+	}
+}
+
+// newWebAuthnChallenge сохраняет хеш нового challenge и возвращает его в base64url
+func (a *Auth) newWebAuthnChallenge(ctx context.Context, userId *int64, ceremony string) (string, error) {
+	challenge, err := randtoken.New()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = a.repo.CreateWebAuthnChallenge(ctx, &domain.WebAuthnChallenge{
+		ChallengeHash: randtoken.Hash(challenge),
+		UserId:        userId,
+		Ceremony:      ceremony,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(a.config.WebAuthn.ChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+func (a *Auth) consumeWebAuthnChallenge(ctx context.Context, challenge, ceremony string) (*domain.WebAuthnChallenge, error) {
+	stored, err := a.repo.ConsumeWebAuthnChallenge(ctx, randtoken.Hash(challenge))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения challenge: %w", err)
+	}
+	if stored == nil || stored.IsExpired() || stored.Ceremony != ceremony {
+		return nil, authErrors.ErrInvalidPasskey
+	}
+	return stored, nil
+}
+
+// userHandle - идентификатор пользователя внутри passkey. Логин туда не кладется,
+// аутентификатор может показывать handle другим сайтам
+func userHandle(userId int64) string {
+	return webauthn.EncodeBase64URL([]byte(strconv.FormatInt(userId, 10)))
+}
+
+func credentialDescriptors(passkeys []domain.Passkey) []auth.PasskeyCredentialDescriptor {
+	descriptors := make([]auth.PasskeyCredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		descriptors = append(descriptors, auth.PasskeyCredentialDescriptor{
+			Type:       "public-key",
+			ID:         passkey.Id,
+			Transports: passkey.Transports,
+		})
+	}
+	return descriptors
+}
+
+func toPasskeyResponse(passkey domain.Passkey) *auth.PasskeyResponse {
+	return &auth.PasskeyResponse{
+		ID:         passkey.Id,
+		Name:       passkey.Name,
+		Transports: passkey.Transports,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/webauthn"
+	"github.com/phenirain/sso/internal/lib/webauthn/webauthntest"
+)
+
+func newPasskeyTestAuth(t *testing.T) (*Auth, int64, *webauthntest.Authenticator) {
+	t.Helper()
+	a, repo := newTestAuth(t)
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, repo.addUser(t, "user@example.com", "user-password-1", 1), authenticator
+}
+
+func registerPasskey(t *testing.T, a *Auth, userId int64, authenticator *webauthntest.Authenticator, origin string) (*auth.PasskeyResponse, error) {
+	t.Helper()
+	options, err := a.BeginPasskeyRegistration(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req auth.PasskeyRegistrationRequest
+	req.RawID = webauthntest.EncodeBase64URL(authenticator.CredentialID)
+	req.Response.ClientDataJSON = webauthntest.EncodeBase64URL(webauthntest.ClientDataJSON(webauthn.CeremonyCreate, options.Challenge, origin))
+	req.Response.AttestationObject = webauthntest.EncodeBase64URL(authenticator.Register())
+	return a.FinishPasskeyRegistration(context.Background(), userId, req)
+}
+
+func logInWithPasskey(t *testing.T, a *Auth, authenticator *webauthntest.Authenticator, origin string) (*auth.AuthResponse, error) {
+	t.Helper()
+	options, err := a.BeginPasskeyLogin(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientData := webauthntest.ClientDataJSON(webauthn.CeremonyGet, options.Challenge, origin)
+	authData, signature, err := authenticator.Assert(clientData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req auth.PasskeyLoginRequest
+	req.RawID = webauthntest.EncodeBase64URL(authenticator.CredentialID)
+	req.Response.ClientDataJSON = webauthntest.EncodeBase64URL(clientData)
+	req.Response.AuthenticatorData = webauthntest.EncodeBase64URL(authData)
+	req.Response.Signature = webauthntest.EncodeBase64URL(signature)
+	return a.FinishPasskeyLogin(context.Background(), req)
+}
+
+func TestPasskeyRegisterAndLogIn(t *testing.T) {
+	a, userId, authenticator := newPasskeyTestAuth(t)
+
+	passkey, err := registerPasskey(t, a, userId, authenticator, testOrigin)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if passkey.ID != webauthntest.EncodeBase64URL(authenticator.CredentialID) {
+		t.Fatalf("passkey id %s does not match credential id", passkey.ID)
+	}
+
+	for range 2 {
+		response, err := logInWithPasskey(t, a, authenticator, testOrigin)
+		if err != nil {
+			t.Fatalf("log in: %v", err)
+		}
+		if response.AccessToken == "" {
+			t.Fatal("tokens were not issued")
+		}
+	}
+
+	// тот же ключ нельзя зарегистрировать второй раз
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); !errors.Is(err, authErrors.ErrPasskeyAlreadyRegistered) {
+		t.Fatalf("duplicate registration: got %v, want ErrPasskeyAlreadyRegistered", err)
+	}
+}
+
+func TestPasskeyLogInRejectsSignCountRegression(t *testing.T) {
+	a, userId, authenticator := newPasskeyTestAuth(t)
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+
+	// клон ключа подписывает со счетчиком, который уже был принят
+	authenticator.SignCount = 1
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); !errors.Is(err, authErrors.ErrPasskeyCloned) {
+		t.Fatalf("regressed counter: got %v, want ErrPasskeyCloned", err)
+	}
+}
+
+// два одновременных входа клона с одним счетчиком: оба проходят проверку в сервисе,
+// но сохранить счетчик успевает только первый
+func TestPasskeyLogInRejectsConcurrentSameCounter(t *testing.T) {
+	a, repo := newTestAuth(t)
+	userId := repo.addUser(t, "user@example.com", "user-password-1", 1)
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.beforePasskeyUpdate = func(id string) {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		// параллельный вход уже сохранил следующий счетчик
+		repo.passkeys[id].SignCount = int64(authenticator.SignCount)
+	}
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); !errors.Is(err, authErrors.ErrPasskeyCloned) {
+		t.Fatalf("concurrent login with the same counter: got %v, want ErrPasskeyCloned", err)
+	}
+}
+
+func TestPasskeyRejectsWrongOriginAndRPID(t *testing.T) {
+	a, userId, authenticator := newPasskeyTestAuth(t)
+
+	if _, err := registerPasskey(t, a, userId, authenticator, "https://evil.example.com"); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Fatalf("registration from other origin: got %v, want ErrInvalidPasskey", err)
+	}
+	authenticator.RPID = "evil.example.com"
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Fatalf("registration for other rpId: got %v, want ErrInvalidPasskey", err)
+	}
+
+	authenticator.RPID = testRPID
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := logInWithPasskey(t, a, authenticator, "https://evil.example.com"); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Fatalf("log in from other origin: got %v, want ErrInvalidPasskey", err)
+	}
+	authenticator.RPID = "evil.example.com"
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); !errors.Is(err, authErrors.ErrInvalidPasskey) {
+		t.Fatalf("log in for other rpId: got %v, want ErrInvalidPasskey", err)
+	}
+}
+
+func TestPasskeyRequiresUserVerification(t *testing.T) {
+	a, userId, authenticator := newPasskeyTestAuth(t)
+
+	options, err := a.BeginPasskeyLogin(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.UserVerification != "required" {
+		t.Fatalf("login userVerification = %q, want required", options.UserVerification)
+	}
+
+	authenticator.SkipUserVerification = true
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); !errors.Is(err, authErrors.ErrPasskeyUserNotVerified) {
+		t.Fatalf("registration without UV: got %v, want ErrPasskeyUserNotVerified", err)
+	}
+
+	authenticator.SkipUserVerification = false
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+	authenticator.SkipUserVerification = true
+	if _, err := logInWithPasskey(t, a, authenticator, testOrigin); !errors.Is(err, authErrors.ErrPasskeyUserNotVerified) {
+		t.Fatalf("log in without UV: got %v, want ErrPasskeyUserNotVerified", err)
+	}
+}
+
+// passkey с проверкой пользователя - это уже два фактора, код TOTP после него не спрашивается
+func TestPasskeyLogInWithTOTPEnabled(t *testing.T) {
+	a, repo := newTestAuth(t)
+	userId := repo.addUser(t, "user@example.com", "user-password-1", 1)
+	repo.users[userId].TotpEnabled = true
+	authenticator, err := webauthntest.NewAuthenticator(testRPID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registerPasskey(t, a, userId, authenticator, testOrigin); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := logInWithPasskey(t, a, authenticator, testOrigin)
+	if err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if response.MFARequired || response.AccessToken == "" {
+		t.Fatalf("got %+v, want tokens without MFA challenge", response)
+	}
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// ChangeExpiredPassword завершает вход пользователя с устаревшим паролем:
+// меняет пароль по токену из ответа /auth/logIn и выдает пару токенов.
+// Токен одноразовый, а пароль к этому моменту должен быть все еще устаревшим
+func (a *Auth) ChangeExpiredPassword(ctx context.Context, passwordChangeToken, newPassword string) (*auth.AuthResponse, error) {
+	const op = "Auth.ChangeExpiredPassword"
+
+	userId, _, tokenId, err := a.jwt.ParsePasswordChangeToken(passwordChangeToken)
+	if err != nil {
+		return nil, authErrors.ErrInvalidPasswordChangeToken
+	}
+	// попытку засчитываем сразу: новый пароль может не пройти требования, но не бесконечно
+	challenge, err := a.repo.UseLoginChallenge(ctx, tokenId, domain.LoginChallengePasswordChange, a.config.MFA.MaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if challenge == nil || challenge.UserId != userId {
+		return nil, authErrors.ErrInvalidPasswordChangeToken
+	}
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	// пароль уже сменили другим способом (сброс, смена в профиле) - токен больше ничего не дает
+	if !a.isPasswordExpired(user) {
+		return nil, authErrors.ErrInvalidPasswordChangeToken
+	}
+
+	passwordHash, err := a.hashNewPassword(ctx, user, newPassword)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := a.repo.DeleteLoginChallenge(ctx, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !deleted {
+		return nil, authErrors.ErrInvalidPasswordChangeToken
+	}
+	if err := a.repo.UpdatePassword(ctx, user.Login, passwordHash); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// как и при сбросе: старый пароль мог знать кто-то еще
+	if err := a.LogoutAll(ctx, user.Id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("expired password changed", "userId", user.Id)
+	return a.getAuthResponse(ctx, user.Id, user.RoleId, "", "")
+}
+
+// ChangePassword меняет пароль авторизованного пользователя после проверки старого.
+// accessTokenId - токен текущего запроса: при RevokeOtherSessions его сессия остается активной.
+// Неверный старый пароль считается неудачной попыткой входа, иначе с украденным access токеном
+// его можно было бы подбирать в обход блокировок
+func (a *Auth) ChangePassword(ctx context.Context, userId int64, accessTokenId string, req auth.ChangePasswordRequest) error {
+	const op = "Auth.ChangePassword"
+
+	user, err := a.getActiveUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if a.isDirectoryAccount(user.Login) {
+		return authErrors.ErrDirectoryAccount
+	}
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if err := a.checkLockout(ctx, user.Login, ip); err != nil {
+		return err
+	}
+
+	// требования и история проверяются до смены, но их ошибку видит только тот, кто знает старый пароль:
+	// иначе совпадение нового пароля с текущим подсказало бы текущий в обход блокировок
+	if err := a.checkNewPassword(ctx, user, req.NewPassword); err != nil {
+		if !user.CheckPassword(a.hasher, req.OldPassword) {
+			a.attemptFailed(ctx, user.Login, ip)
+			return domain.ErrInvalidOldPassword
+		}
+		return err
+	}
+	if err := user.UpdatePassword(a.hasher, req.OldPassword, req.NewPassword); err != nil {
+		if errors.Is(err, domain.ErrInvalidOldPassword) {
+			a.attemptFailed(ctx, user.Login, ip)
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// счетчик неудач обнуляет только полностью успешная смена
+	if err := a.guard.Succeed(ctx, user.Login); err != nil {
+		slog.Error("failed to reset login failures", "err", err)
+	}
+
+	if err := a.repo.UpdatePassword(ctx, user.Login, string(user.PasswordHash)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	slog.Info("password changed", "userId", user.Id)
+
+	if req.RevokeOtherSessions {
+		if err := a.revokeOtherSessions(ctx, user.Id, accessTokenId); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// пароль уже сменен - недоступность email-сервиса не повод возвращать ошибку
+	err = a.sendEmail(ctx, "/send-password-changed-email", map[string]interface{}{
+		"to":    user.Login,
+		"login": user.Login,
+	})
+	if err != nil {
+		slog.Error("failed to send password changed email", "userId", user.Id, "err", err)
+	}
+	return nil
+}
+
+// completeLogin выдает токены пользователю, прошедшему все проверки входа,
+// или требует сменить пароль, если его срок действия истек
+func (a *Auth) completeLogin(ctx context.Context, user *domain.User) (*auth.AuthResponse, error) {
+	if a.isPasswordExpired(user) {
+		return a.passwordExpiredResponse(ctx, user)
+	}
+	return a.getAuthResponse(ctx, user.Id, user.RoleId, "", "")
+}
+
+// passwordExpiredResponse вместо токенов выдает токен смены устаревшего пароля
+func (a *Auth) passwordExpiredResponse(ctx context.Context, user *domain.User) (*auth.AuthResponse, error) {
+	tokenId, err := a.createLoginChallenge(ctx, user.Id, domain.LoginChallengePasswordChange)
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.jwt.NewPasswordChangeToken(user.Id, user.RoleId, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации токена смены пароля: %w", err)
+	}
+	slog.Info("password expired", "userId", user.Id)
+	return &auth.AuthResponse{
+		PasswordExpired:     true,
+		PasswordChangeToken: token,
+	}, nil
+}
+
+// hashNewPassword проверяет новый пароль на требования и историю и возвращает его хеш
+func (a *Auth) hashNewPassword(ctx context.Context, user *domain.User, password string) (string, error) {
+	if err := a.checkNewPassword(ctx, user, password); err != nil {
+		return "", err
+	}
+
+	passwordHash, err := a.hasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("ошибка хеширования пароля: %w", err)
+	}
+	return string(passwordHash), nil
+}
+
+// checkNewPassword проверяет новый пароль на требования и историю
+func (a *Auth) checkNewPassword(ctx context.Context, user *domain.User, password string) error {
+	if err := a.policy.Check(password, user.Login); err != nil {
+		return err
+	}
+	return a.checkPasswordReuse(ctx, user, password)
+}
+
+// checkPasswordReuse запрещает текущий пароль и history_size последних
+func (a *Auth) checkPasswordReuse(ctx context.Context, user *domain.User, password string) error {
+	historySize := a.config.PasswordPolicy.HistorySize
+	if historySize <= 0 {
+		return nil
+	}
+	if user.CheckPassword(a.hasher, password) {
+		return authErrors.ErrPasswordReused
+	}
+
+	history, err := a.repo.GetPasswordHistory(ctx, user.Id, historySize)
+	if err != nil {
+		return fmt.Errorf("ошибка получения истории паролей: %w", err)
+	}
+	for _, entry := range history {
+		if entry.Matches(a.hasher, password) {
+			return authErrors.ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// rehashPassword пересчитывает хеш пароля, посчитанный алгоритмом или параметрами слабее текущих.
+// Пароль уже проверен, поэтому ошибка только логируется - вход от нее не зависит
+func (a *Auth) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	if !a.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := a.hasher.Hash(password)
+	if err != nil {
+		slog.Error("failed to rehash password", "userId", user.Id, "err", err)
+		return
+	}
+	if err := a.repo.RehashPassword(ctx, user.Id, string(user.PasswordHash), string(passwordHash)); err != nil {
+		slog.Error("failed to rehash password", "userId", user.Id, "err", err)
+		return
+	}
+	user.PasswordHash = passwordHash
+	slog.Info("password rehashed", "userId", user.Id)
+}
+
+// isPasswordExpired - менеджеры и администраторы обязаны менять пароль раз в staff_max_age.
+// Срок пароля учетных записей каталога контролирует сам каталог
+func (a *Auth) isPasswordExpired(user *domain.User) bool {
+	maxAge := a.config.PasswordPolicy.StaffMaxAge
+	return maxAge > 0 && user.IsStaff() && !a.isDirectoryAccount(user.Login) && time.Since(user.PasswordChangedAt) > maxAge
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/config"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/passwordpolicy"
+)
+
+const (
+	staffTestLogin    = "manager@example.com"
+	staffTestPassword = "old-password-1"
+	staffNewPassword  = "new-password-2"
+)
+
+// newExpiredPasswordTestAuth создает сотрудника, чей пароль старше staff_max_age
+func newExpiredPasswordTestAuth(t *testing.T, maxAttempts int) (*Auth, *memoryRepository, int64) {
+	t.Helper()
+	a, repo := newTestAuth(t,
+		withPolicy(&passwordpolicy.Policy{MinLength: 12}),
+		withConfig(func(cfg *config.Config) {
+			cfg.MFA.MaxAttempts = maxAttempts
+			cfg.PasswordPolicy.StaffMaxAge = 24 * time.Hour
+		}),
+	)
+	userId := repo.addUser(t, staffTestLogin, staffTestPassword, 2)
+	repo.users[userId].PasswordChangedAt = time.Now().Add(-48 * time.Hour)
+	return a, repo, userId
+}
+
+func expiredPasswordToken(t *testing.T, a *Auth) string {
+	t.Helper()
+	response, err := a.Auth(context.Background(), auth.AuthRequest{Login: staffTestLogin, Password: staffTestPassword}, false)
+	if err != nil {
+		t.Fatalf("log in: %v", err)
+	}
+	if !response.PasswordExpired || response.PasswordChangeToken == "" {
+		t.Fatal("password change was not requested")
+	}
+	return response.PasswordChangeToken
+}
+
+func TestChangeExpiredPasswordTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	a, _, _ := newExpiredPasswordTestAuth(t, 5)
+	token := expiredPasswordToken(t, a)
+
+	response, err := a.ChangeExpiredPassword(ctx, token, staffNewPassword)
+	if err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if response.AccessToken == "" {
+		t.Fatal("tokens were not issued")
+	}
+
+	if _, err := a.ChangeExpiredPassword(ctx, token, "another-password-3"); !errors.Is(err, authErrors.ErrInvalidPasswordChangeToken) {
+		t.Fatalf("replayed token: got %v, want ErrInvalidPasswordChangeToken", err)
+	}
+}
+
+func TestChangeExpiredPasswordRequiresExpiredPassword(t *testing.T) {
+	ctx := context.Background()
+	a, repo, _ := newExpiredPasswordTestAuth(t, 5)
+	token := expiredPasswordToken(t, a)
+
+	// пока токен ждал, пароль сменили сбросом
+	if err := repo.UpdatePassword(ctx, staffTestLogin, "reset-hash"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ChangeExpiredPassword(ctx, token, staffNewPassword); !errors.Is(err, authErrors.ErrInvalidPasswordChangeToken) {
+		t.Fatalf("password is no longer expired: got %v, want ErrInvalidPasswordChangeToken", err)
+	}
+}
+
+func TestChangeExpiredPasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+
+	// сессия, открытая до смены пароля, например тем, кто знал старый пароль
+	old, err := a.IssueTokens(ctx, userId, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := a.ChangeExpiredPassword(ctx, expiredPasswordToken(t, a), staffNewPassword)
+	if err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if _, err := a.Refresh(ctx, old.RefreshToken); err == nil {
+		t.Fatal("session opened before the change is still active")
+	}
+	if _, err := a.Refresh(ctx, response.RefreshToken); err != nil {
+		t.Fatalf("session issued by the change: %v", err)
+	}
+}
+
+func TestChangeExpiredPasswordLimitsAttempts(t *testing.T) {
+	ctx := context.Background()
+	a, _, _ := newExpiredPasswordTestAuth(t, 2)
+	token := expiredPasswordToken(t, a)
+
+	for range 2 {
+		var policyErr *passwordpolicy.Error
+		if _, err := a.ChangeExpiredPassword(ctx, token, "short"); !errors.As(err, &policyErr) {
+			t.Fatalf("weak password: got %v, want policy error", err)
+		}
+	}
+	if _, err := a.ChangeExpiredPassword(ctx, token, staffNewPassword); !errors.Is(err, authErrors.ErrInvalidPasswordChangeToken) {
+		t.Fatalf("token after attempt limit: got %v, want ErrInvalidPasswordChangeToken", err)
+	}
+}
+
+// сессия, открытая до истечения срока пароля, не продлевается без его смены
+func TestRefreshRequiresExpiredPasswordChange(t *testing.T) {
+	ctx := context.Background()
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+	tokens, err := a.IssueTokens(ctx, userId, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := a.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if !response.PasswordExpired || response.PasswordChangeToken == "" || response.AccessToken != "" || response.RefreshToken != "" {
+		t.Fatalf("refresh returned %+v, want password change instead of tokens", response)
+	}
+	if _, err := a.Refresh(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("refresh token is still valid after the password change was requested")
+	}
+
+	changed, err := a.ChangeExpiredPassword(ctx, response.PasswordChangeToken, staffNewPassword)
+	if err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if _, err := a.Refresh(ctx, changed.RefreshToken); err != nil {
+		t.Fatalf("refresh after the change: %v", err)
+	}
+}
+
+func changePassword(a *Auth, userId int64, oldPassword string) error {
+	return a.ChangePassword(context.Background(), userId, "", auth.ChangePasswordRequest{
+		OldPassword: oldPassword,
+		NewPassword: staffNewPassword,
+	})
+}
+
+func TestChangePasswordCountsWrongOldPassword(t *testing.T) {
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+
+	// лимит newTestGuard - 5 неудач на логин
+	for range 5 {
+		if err := changePassword(a, userId, "wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+			t.Fatalf("wrong old password: got %v, want ErrInvalidOldPassword", err)
+		}
+	}
+
+	if err := changePassword(a, userId, staffTestPassword); !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("correct old password after lockout: got %v, want ErrAccountLocked", err)
+	}
+	// блокировка общая со входом по паролю
+	_, err := a.Auth(context.Background(), auth.AuthRequest{Login: staffTestLogin, Password: staffTestPassword}, false)
+	if !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("log in after lockout: got %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestChangePasswordResetsFailuresOnSuccess(t *testing.T) {
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+
+	for range 4 {
+		if err := changePassword(a, userId, "wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+			t.Fatalf("wrong old password: got %v", err)
+		}
+	}
+	if err := changePassword(a, userId, staffTestPassword); err != nil {
+		t.Fatalf("change: %v", err)
+	}
+
+	for range 4 {
+		if err := changePassword(a, userId, "wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+			t.Fatalf("wrong old password after reset: got %v", err)
+		}
+	}
+}
+
+// новый пароль не прошел требования - это не успешная смена, счетчик неудач остается
+func TestChangePasswordKeepsFailuresOnRejectedNewPassword(t *testing.T) {
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+
+	for range 4 {
+		if err := changePassword(a, userId, "wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+			t.Fatalf("wrong old password: got %v", err)
+		}
+	}
+	err := a.ChangePassword(context.Background(), userId, "", auth.ChangePasswordRequest{
+		OldPassword: staffTestPassword,
+		NewPassword: "short",
+	})
+	var policyErr *passwordpolicy.Error
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("weak new password: got %v, want policy error", err)
+	}
+
+	if err := changePassword(a, userId, "wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+		t.Fatalf("wrong old password: got %v", err)
+	}
+	if err := changePassword(a, userId, staffTestPassword); !errors.Is(err, authErrors.ErrAccountLocked) {
+		t.Fatalf("correct old password after lockout: got %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestChangePasswordSendsNotice(t *testing.T) {
+	emails, serviceURL := newEmailService(t)
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+	a.config.Email.ServiceURL = serviceURL
+
+	if err := changePassword(a, userId, staffTestPassword); err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if sent := emails.sent("/send-password-changed-email", staffTestLogin); sent != 1 {
+		t.Fatalf("%d password changed emails sent, want 1", sent)
+	}
+}
+
+// совпадение нового пароля с текущим сообщается только при верном старом пароле
+func TestChangePasswordHidesReuseWithoutOldPassword(t *testing.T) {
+	a, _, userId := newExpiredPasswordTestAuth(t, 5)
+	a.config.PasswordPolicy.HistorySize = 1
+	reuse := func(oldPassword string) error {
+		return a.ChangePassword(context.Background(), userId, "", auth.ChangePasswordRequest{
+			OldPassword: oldPassword,
+			NewPassword: staffTestPassword,
+		})
+	}
+
+	if err := reuse("wrong-password"); !errors.Is(err, domain.ErrInvalidOldPassword) {
+		t.Fatalf("wrong old password: got %v, want ErrInvalidOldPassword", err)
+	}
+	if err := reuse(staffTestPassword); !errors.Is(err, authErrors.ErrPasswordReused) {
+		t.Fatalf("correct old password: got %v, want ErrPasswordReused", err)
+	}
+}
+
+func TestChangePasswordUpdatesHash(t *testing.T) {
+	ctx := context.Background()
+	a, repo, userId := newExpiredPasswordTestAuth(t, 5)
+
+	if err := changePassword(a, userId, staffTestPassword); err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if !repo.users[userId].CheckPassword(a.hasher, staffNewPassword) {
+		t.Fatal("new password was not saved")
+	}
+	if _, err := a.Auth(ctx, auth.AuthRequest{Login: staffTestLogin, Password: staffTestPassword}, false); !errors.Is(err, authErrors.ErrInvalidUserCredentials) {
+		t.Fatalf("log in with the old password: got %v, want ErrInvalidUserCredentials", err)
+	}
+}
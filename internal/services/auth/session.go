@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/useragent"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+// maxUserAgentLength - длиннее User-Agent не хранится, для списка сессий хватает начала
+const maxUserAgentLength = 512
+
+// GetSessions возвращает активные сессии пользователя. accessTokenId - токен текущего
+// запроса, его сессия помечается как текущая; администратор передает пустую строку
+func (a *Auth) GetSessions(ctx context.Context, userId int64, accessTokenId string) ([]auth.SessionResponse, error) {
+	const op = "Auth.GetSessions"
+
+	sessions, err := a.repo.GetActiveSessions(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	current, err := a.currentSessionId(ctx, userId, accessTokenId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]auth.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, auth.SessionResponse{
+			ID:         session.Id,
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			ClientID:   session.ClientId,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.Id == current,
+		})
+	}
+	return result, nil
+}
+
+// RevokeSession завершает сессию sessionId пользователя userId вместе с ее access токенами
+func (a *Auth) RevokeSession(ctx context.Context, userId int64, sessionId string) error {
+	const op = "Auth.RevokeSession"
+
+	if _, err := uuid.Parse(sessionId); err != nil {
+		return authErrors.ErrSessionNotFound
+	}
+	session, err := a.repo.GetSession(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// чужая сессия неотличима от несуществующей
+	if session == nil || session.UserId != userId || !session.IsActive() {
+		return authErrors.ErrSessionNotFound
+	}
+
+	if err := a.revokeFamily(ctx, session.Id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("session revoked", "userId", userId, "sessionId", session.Id)
+	return nil
+}
+
+// revokeOtherSessions завершает все сессии пользователя, кроме той,
+// в которой выпущен access токен accessTokenId
+func (a *Auth) revokeOtherSessions(ctx context.Context, userId int64, accessTokenId string) error {
+	sessions, err := a.repo.GetActiveSessions(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("ошибка получения сессий: %w", err)
+	}
+	current, err := a.currentSessionId(ctx, userId, accessTokenId)
+	if err != nil {
+		return err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.Id == current {
+			continue
+		}
+		if err := a.revokeFamily(ctx, session.Id); err != nil {
+			return err
+		}
+		revoked++
+	}
+
+	slog.Info("other sessions revoked", "userId", userId, "count", revoked)
+	return nil
+}
+
+// currentSessionId находит сессию, в которой выпущен access токен accessTokenId.
+// Пустая строка - токен не найден или не передан
+func (a *Auth) currentSessionId(ctx context.Context, userId int64, accessTokenId string) (string, error) {
+	if accessTokenId == "" {
+		return "", nil
+	}
+
+	tokens, err := a.repo.GetRefreshTokensByUser(ctx, userId)
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения refresh токенов: %w", err)
+	}
+	for _, token := range tokens {
+		if token.AccessTokenId == accessTokenId {
+			return token.Family, nil
+		}
+	}
+	return "", nil
+}
+
+// saveSession создает сессию при входе или отмечает активность при ротации refresh токена.
+// Устройство и адрес берутся из запроса, в котором выпущен токен
+func (a *Auth) saveSession(ctx context.Context, token *domain.RefreshToken) error {
+	userAgent, _ := ctx.Value(contextkeys.UserAgentCtxKey).(string)
+	ip, _ := ctx.Value(contextkeys.ClientIPCtxKey).(string)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	// обрезка могла разрезать символ, а в заголовке бывают и просто невалидные байты
+	userAgent = strings.ToValidUTF8(userAgent, "")
+
+	session := domain.NewSession(token, userAgent, ip, useragent.Device(userAgent))
+	if err := a.repo.SaveSession(ctx, session); err != nil {
+		return fmt.Errorf("ошибка сохранения сессии: %w", err)
+	}
+	return nil
+}
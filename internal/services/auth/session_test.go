@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phenirain/sso/internal/dto/auth"
+	authErrors "github.com/phenirain/sso/internal/errors/auth"
+	"github.com/phenirain/sso/internal/lib/denylist"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+const (
+	desktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+	mobileUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+)
+
+// logInOn открывает сессию пользователя с устройства userAgent
+func logInOn(t *testing.T, a *Auth, userId int64, userAgent string) *auth.AuthResponse {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), contextkeys.UserAgentCtxKey, userAgent)
+	ctx = context.WithValue(ctx, contextkeys.ClientIPCtxKey, "10.0.0.1")
+	tokens, err := a.IssueTokens(ctx, userId, 1, "")
+	if err != nil {
+		t.Fatalf("issue tokens: %v", err)
+	}
+	return tokens
+}
+
+// sessionOf возвращает id сессии, в которой выпущен accessToken
+func sessionOf(t *testing.T, a *Auth, userId int64, accessToken string) string {
+	t.Helper()
+	id, err := a.currentSessionId(context.Background(), userId, accessTokenId(t, a, accessToken))
+	if err != nil || id == "" {
+		t.Fatalf("no session for the access token: %v", err)
+	}
+	return id
+}
+
+func TestGetSessionsMarksCurrent(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestAuth(t)
+	userId := repo.addUser(t, "buyer@example.com", "buyer-password-1", 1)
+	otherId := repo.addUser(t, "other@example.com", "other-password-1", 1)
+	desktop := logInOn(t, a, userId, desktopUserAgent)
+	mobile := logInOn(t, a, userId, mobileUserAgent)
+	logInOn(t, a, otherId, desktopUserAgent)
+
+	sessions, err := a.GetSessions(ctx, userId, accessTokenId(t, a, mobile.AccessToken))
+	if err != nil {
+		t.Fatalf("get sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2 of the caller", len(sessions))
+	}
+	for _, session := range sessions {
+		wantCurrent := session.UserAgent == mobileUserAgent
+		if session.Current != wantCurrent {
+			t.Fatalf("session %s (%s): current %v, want %v", session.ID, session.Device, session.Current, wantCurrent)
+		}
+		if session.IP != "10.0.0.1" || session.Device == "" {
+			t.Fatalf("session %+v has no device or IP", session)
+		}
+	}
+
+	// после ротации сессия остается текущей для нового access токена
+	refreshed, err := a.Refresh(ctx, desktop.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	sessions, err = a.GetSessions(ctx, userId, accessTokenId(t, a, refreshed.AccessToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range sessions {
+		if wantCurrent := session.UserAgent == desktopUserAgent; session.Current != wantCurrent {
+			t.Fatalf("after refresh session %s: current %v, want %v", session.Device, session.Current, wantCurrent)
+		}
+	}
+
+	// администратор смотрит чужие сессии без текущей
+	sessions, err = a.GetSessions(ctx, userId, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range sessions {
+		if session.Current {
+			t.Fatalf("session %s is current without an access token", session.ID)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	revoked := denylist.New()
+	a, repo := newTestAuth(t, withDenylist(revoked))
+	userId := repo.addUser(t, "buyer@example.com", "buyer-password-1", 1)
+	desktop := logInOn(t, a, userId, desktopUserAgent)
+	mobile := logInOn(t, a, userId, mobileUserAgent)
+
+	if err := a.RevokeSession(ctx, userId, sessionOf(t, a, userId, mobile.AccessToken)); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	sessions, err := a.GetSessions(ctx, userId, accessTokenId(t, a, desktop.AccessToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].UserAgent != desktopUserAgent || !sessions[0].Current {
+		t.Fatalf("sessions after revoke: %+v, want only the current desktop session", sessions)
+	}
+	if _, err := a.Refresh(ctx, mobile.RefreshToken); err == nil {
+		t.Fatal("refresh token of the revoked session still works")
+	}
+	if got := serveBearer(revoked, mobile.AccessToken); got != http.StatusUnauthorized {
+		t.Fatalf("access token of the revoked session: status %d, want %d", got, http.StatusUnauthorized)
+	}
+
+	// остальные сессии не затронуты
+	if _, err := a.Refresh(ctx, desktop.RefreshToken); err != nil {
+		t.Fatalf("refresh of the other session: %v", err)
+	}
+	if got := serveBearer(revoked, desktop.AccessToken); got != http.StatusOK {
+		t.Fatalf("access token of the other session: status %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestRevokeSessionNotFound(t *testing.T) {
+	tests := []struct {
+		name    string
+		session func(t *testing.T, a *Auth, userId, otherId int64) string
+	}{
+		{"malformed id", func(*testing.T, *Auth, int64, int64) string {
+			return "not-a-uuid"
+		}},
+		{"unknown session", func(*testing.T, *Auth, int64, int64) string {
+			return uuid.NewString()
+		}},
+		// чужая сессия неотличима от несуществующей
+		{"another user's session", func(t *testing.T, a *Auth, _, otherId int64) string {
+			return sessionOf(t, a, otherId, logInOn(t, a, otherId, desktopUserAgent).AccessToken)
+		}},
+		{"already revoked session", func(t *testing.T, a *Auth, userId, _ int64) string {
+			session := sessionOf(t, a, userId, logInOn(t, a, userId, mobileUserAgent).AccessToken)
+			if err := a.RevokeSession(context.Background(), userId, session); err != nil {
+				t.Fatal(err)
+			}
+			return session
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, repo := newTestAuth(t)
+			userId := repo.addUser(t, "buyer@example.com", "buyer-password-1", 1)
+			otherId := repo.addUser(t, "other@example.com", "other-password-1", 1)
+			session := tt.session(t, a, userId, otherId)
+
+			if err := a.RevokeSession(context.Background(), userId, session); !errors.Is(err, authErrors.ErrSessionNotFound) {
+				t.Fatalf("err = %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+}
+
+// отзыв чужой сессии не завершает ее
+func TestRevokeSessionKeepsForeignSession(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestAuth(t)
+	userId := repo.addUser(t, "buyer@example.com", "buyer-password-1", 1)
+	otherId := repo.addUser(t, "other@example.com", "other-password-1", 1)
+	other := logInOn(t, a, otherId, desktopUserAgent)
+
+	if err := a.RevokeSession(ctx, userId, sessionOf(t, a, otherId, other.AccessToken)); !errors.Is(err, authErrors.ErrSessionNotFound) {
+		t.Fatalf("err = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := a.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("refresh of the other user's session: %v", err)
+	}
+}
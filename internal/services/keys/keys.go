@@ -0,0 +1,215 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+type Repository interface {
+	GetSigningKeys(ctx context.Context) ([]domain.SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *domain.SigningKey) error
+	RetireSigningKeys(ctx context.Context, exceptId string) error
+	DeleteSigningKey(ctx context.Context, id string) error
+	SealSigningKey(ctx context.Context, id string, sealedKey []byte) error
+}
+
+// Cipher шифрует приватные ключи перед сохранением в базу
+type Cipher interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// Keys управляет ротацией ключей подписи. Состояние хранится в базе,
+// Reload переносит его в связку ключей, которой пользуется JwtLib.
+type Keys struct {
+	repo    Repository
+	keyring *jwt.Keyring
+	cipher  Cipher
+	// mu делает Reload целиком последовательным: иначе Reload, прочитавший базу до ротации,
+	// мог бы заменить связку ключей уже после Reload из Rotate и вернуть старый текущий ключ
+	mu sync.Mutex
+	// bootstrap - ключ из конфигурации, используется пока в базе нет ключей
+	// и остается ключом проверки, пока не истекут выпущенные им токены
+	bootstrap *jwt.SigningKey
+	// bootstrapRetired - токены bootstrap ключа истекли, в связку он больше не попадает
+	bootstrapRetired bool
+	algorithm        string
+	// maxTokenLifetime - время жизни самого долгоживущего токена с учетом допустимого расхождения часов
+	maxTokenLifetime time.Duration
+}
+
+func New(repo Repository, keyring *jwt.Keyring, cipher Cipher, bootstrap *jwt.SigningKey, algorithm string, maxTokenLifetime, leeway time.Duration) *Keys {
+	return &Keys{
+		repo:             repo,
+		keyring:          keyring,
+		cipher:           cipher,
+		bootstrap:        bootstrap,
+		algorithm:        algorithm,
+		maxTokenLifetime: maxTokenLifetime + leeway,
+	}
+}
+
+// Reload перечитывает ключи из базы и удаляет те, чьи токены уже истекли
+func (k *Keys) Reload(ctx context.Context) error {
+	const op = "Keys.Reload"
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stored, err := k.repo.GetSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var current *jwt.SigningKey
+	var previous []*jwt.SigningKey
+	for _, s := range stored {
+		if s.IsExpired(k.maxTokenLifetime) {
+			if err := k.repo.DeleteSigningKey(ctx, s.Id); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			slog.Info("expired signing key removed", "kid", s.Id)
+			continue
+		}
+
+		key, err := k.unmarshal(ctx, &s)
+		if err != nil {
+			return fmt.Errorf("%s: key %s: %w", op, s.Id, err)
+		}
+		// ключи отсортированы от новых к старым - первый действующий становится текущим
+		if current == nil && s.RetiredAt == nil {
+			current = key
+			continue
+		}
+		previous = append(previous, key)
+	}
+
+	switch {
+	case current == nil:
+		current = k.bootstrap
+	case k.isBootstrapActive(stored):
+		previous = append(previous, k.bootstrap)
+	}
+	k.keyring.Replace(current, previous...)
+	return nil
+}
+
+// isBootstrapActive - bootstrap ключ был текущим до первой ротации, то есть до создания самого
+// старого ключа в базе. После этого его токены живут не дольше maxTokenLifetime.
+// Вызывается под k.mu
+func (k *Keys) isBootstrapActive(stored []domain.SigningKey) bool {
+	if k.bootstrapRetired {
+		return false
+	}
+	oldest := stored[len(stored)-1]
+	if time.Now().After(oldest.CreatedAt.Add(k.maxTokenLifetime)) {
+		k.bootstrapRetired = true
+		slog.Info("bootstrap signing key retired", "kid", k.bootstrap.Id)
+		return false
+	}
+	return true
+}
+
+// unmarshal расшифровывает приватный ключ. Ключи, сохраненные до появления шифрования,
+// шифруются и перезаписываются
+func (k *Keys) unmarshal(ctx context.Context, stored *domain.SigningKey) (*jwt.SigningKey, error) {
+	if stored.Sealed {
+		privateKey, err := k.cipher.Open(stored.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("расшифровка ключа: %w", err)
+		}
+		return jwt.UnmarshalSigningKey(stored.Algorithm, stored.Id, privateKey)
+	}
+
+	key, err := jwt.UnmarshalSigningKey(stored.Algorithm, stored.Id, stored.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := k.cipher.Seal(stored.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("шифрование ключа: %w", err)
+	}
+	if err := k.repo.SealSigningKey(ctx, stored.Id, sealed); err != nil {
+		return nil, err
+	}
+	slog.Info("signing key sealed", "kid", stored.Id)
+	return key, nil
+}
+
+// Rotate создает новый ключ, делает его текущим и выводит из оборота остальные.
+// Выведенные ключи продолжают проверять подписи, пока не истекут все их токены
+func (k *Keys) Rotate(ctx context.Context) (*domain.SigningKey, error) {
+	const op = "Keys.Rotate"
+
+	key, err := jwt.GenerateSigningKey(k.algorithm, uuid.NewString())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sealed, err := k.cipher.Seal(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored := &domain.SigningKey{
+		Id:         key.Id,
+		Algorithm:  k.algorithm,
+		PrivateKey: sealed,
+		Sealed:     true,
+		CreatedAt:  time.Now(),
+	}
+	if err := k.repo.CreateSigningKey(ctx, stored); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := k.repo.RetireSigningKeys(ctx, stored.Id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := k.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("signing key rotated", "kid", stored.Id, "algorithm", stored.Algorithm)
+	return stored, nil
+}
+
+// GetSigningKeys возвращает ключи из базы без приватной части
+func (k *Keys) GetSigningKeys(ctx context.Context) ([]domain.SigningKey, error) {
+	const op = "Keys.GetSigningKeys"
+
+	stored, err := k.repo.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range stored {
+		stored[i].PrivateKey = nil
+	}
+	return stored, nil
+}
+
+// Start периодически перечитывает ключи, чтобы ротация на одном экземпляре
+// подхватывалась остальными без перезапуска
+func (k *Keys) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				slog.Error("failed to reload signing keys", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/secretbox"
+)
+
+type memoryRepository struct {
+	mu   sync.Mutex
+	keys []domain.SigningKey
+	// afterGet вызывается после чтения ключей - так тест задерживает Reload с прочитанным снимком
+	afterGet func()
+}
+
+func (r *memoryRepository) GetSigningKeys(context.Context) ([]domain.SigningKey, error) {
+	r.mu.Lock()
+	// от новых к старым, как в базе
+	result := make([]domain.SigningKey, len(r.keys))
+	for i, key := range r.keys {
+		result[len(r.keys)-1-i] = key
+	}
+	afterGet := r.afterGet
+	r.mu.Unlock()
+
+	if afterGet != nil {
+		afterGet()
+	}
+	return result, nil
+}
+
+func (r *memoryRepository) CreateSigningKey(_ context.Context, key *domain.SigningKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func (r *memoryRepository) RetireSigningKeys(_ context.Context, exceptId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i := range r.keys {
+		if r.keys[i].Id != exceptId && r.keys[i].RetiredAt == nil {
+			r.keys[i].RetiredAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) DeleteSigningKey(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.keys {
+		if r.keys[i].Id == id {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) SealSigningKey(_ context.Context, id string, sealedKey []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.keys {
+		if r.keys[i].Id == id {
+			r.keys[i].PrivateKey, r.keys[i].Sealed = sealedKey, true
+		}
+	}
+	return nil
+}
+
+func newTestKeys(t *testing.T, repo *memoryRepository) (*Keys, *jwt.Keyring, *jwt.SigningKey) {
+	t.Helper()
+	secret := sha256.Sum256([]byte("secret"))
+	box, err := secretbox.New(secret[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrap := jwt.NewHMACKey("bootstrap", []byte("secret"))
+	keyring := jwt.NewKeyring(bootstrap)
+	return New(repo, keyring, box, bootstrap, jwt.AlgorithmEdDSA, time.Hour, time.Minute), keyring, bootstrap
+}
+
+func hasKey(keyring *jwt.Keyring, id string) bool {
+	_, ok := keyring.Lookup(id)
+	return ok
+}
+
+func TestRotateSealsPrivateKey(t *testing.T) {
+	repo := &memoryRepository{}
+	k, keyring, _ := newTestKeys(t, repo)
+
+	rotated, err := k.Rotate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.keys[0].Sealed {
+		t.Fatal("rotated key is stored unsealed")
+	}
+	if keyring.Current().Id != rotated.Id {
+		t.Fatalf("current key is %s, want %s", keyring.Current().Id, rotated.Id)
+	}
+}
+
+func TestReloadSealsLegacyKey(t *testing.T) {
+	key, err := jwt.GenerateSigningKey(jwt.AlgorithmEdDSA, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := key.MarshalPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memoryRepository{keys: []domain.SigningKey{{
+		Id: "legacy", Algorithm: jwt.AlgorithmEdDSA, PrivateKey: plaintext, CreatedAt: time.Now(),
+	}}}
+	k, keyring, _ := newTestKeys(t, repo)
+
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.keys[0].Sealed || bytes.Equal(repo.keys[0].PrivateKey, plaintext) {
+		t.Fatal("legacy key was not sealed")
+	}
+	if keyring.Current().Id != "legacy" {
+		t.Fatal("legacy key is not current")
+	}
+	// после перезаписи ключ читается уже зашифрованным
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadRetiresBootstrapKey(t *testing.T) {
+	repo := &memoryRepository{}
+	k, keyring, bootstrap := newTestKeys(t, repo)
+
+	if _, err := k.Rotate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// токены bootstrap ключа еще могут быть живы
+	if !hasKey(keyring, bootstrap.Id) {
+		t.Fatal("bootstrap key removed right after rotation")
+	}
+
+	// первая ротация была раньше, чем живет самый долгий токен с учетом leeway
+	repo.keys[0].CreatedAt = time.Now().Add(-time.Hour - 2*time.Minute)
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if hasKey(keyring, bootstrap.Id) {
+		t.Fatal("bootstrap key is still accepted after its tokens expired")
+	}
+}
+
+func TestReloadKeepsBootstrapWithoutRotation(t *testing.T) {
+	k, keyring, bootstrap := newTestKeys(t, &memoryRepository{})
+
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if keyring.Current().Id != bootstrap.Id {
+		t.Fatal("bootstrap key is not current without stored keys")
+	}
+}
+
+// Reload, прочитавший базу до ротации, не должен вернуть в связку прежний текущий ключ
+func TestRotateAndReloadConcurrently(t *testing.T) {
+	ctx := context.Background()
+	k, keyring, _ := newTestKeys(t, &memoryRepository{})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := k.Reload(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 20 {
+		rotated, err := k.Rotate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current := keyring.Current().Id; current != rotated.Id {
+			t.Fatalf("current key is %s after rotation to %s", current, rotated.Id)
+		}
+	}
+}
+
+// Reload, прочитавший базу до ротации и задержавшийся, заменяет связку раньше Reload из Rotate
+func TestReloadDoesNotRestoreStaleSnapshot(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRepository{}
+	k, keyring, _ := newTestKeys(t, repo)
+
+	read := make(chan struct{})
+	release := make(chan struct{})
+	// задерживается только первое чтение, Reload из Rotate читает без задержки
+	var delayed atomic.Bool
+	repo.afterGet = func() {
+		if delayed.CompareAndSwap(false, true) {
+			close(read)
+			<-release
+		}
+	}
+	stale := make(chan error)
+	go func() {
+		stale <- k.Reload(ctx)
+	}()
+	<-read
+
+	rotated := make(chan *domain.SigningKey)
+	go func() {
+		key, err := k.Rotate(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		rotated <- key
+	}()
+
+	// Rotate должен дождаться задержанного Reload; если не дождался - тот перезапишет связку
+	var key *domain.SigningKey
+	select {
+	case key = <-rotated:
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stale; err != nil {
+		t.Fatal(err)
+	}
+	if key == nil {
+		key = <-rotated
+	}
+	if key == nil {
+		t.FailNow()
+	}
+	if current := keyring.Current().Id; current != key.Id {
+		t.Fatalf("current key is %s after rotation to %s", current, key.Id)
+	}
+}
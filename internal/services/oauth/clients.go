@@ -0,0 +1,171 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+var supportedGrantTypes = []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials}
+
+func (o *OAuth) GetClients(ctx context.Context) ([]oauth.ClientResponse, error) {
+	const op = "OAuth.GetClients"
+
+	clients, err := o.repo.GetClients(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]oauth.ClientResponse, 0, len(clients))
+	for _, client := range clients {
+		result = append(result, toClientResponse(&client, ""))
+	}
+	return result, nil
+}
+
+// SaveClient создает или обновляет клиента. Новому конфиденциальному клиенту выпускается секрет,
+// он возвращается в ответе один раз - в базе хранится только хеш
+func (o *OAuth) SaveClient(ctx context.Context, req oauth.ClientRequest) (*oauth.ClientResponse, error) {
+	const op = "OAuth.SaveClient"
+
+	if err := validateClientRequest(req); err != nil {
+		return nil, err
+	}
+
+	client, err := o.repo.GetClient(ctx, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if client == nil {
+		client = &domain.OAuthClient{
+			Id:           req.Id,
+			CreationTime: time.Now(),
+		}
+	} else {
+		t := time.Now()
+		client.UpdateTime = &t
+	}
+	client.Name = req.Name
+	// колонки TEXT[] NOT NULL: nil записался бы как NULL
+	client.RedirectURIs = orEmpty(req.RedirectURIs)
+	client.Scopes = orEmpty(req.Scopes)
+	client.GrantTypes = orEmpty(req.GrantTypes)
+	client.IsPublic = req.IsPublic
+
+	var secret string
+	if client.IsPublic {
+		client.SecretHash = nil
+	} else if len(client.SecretHash) == 0 {
+		if secret, err = setNewSecret(client); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := o.repo.SaveClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("oauth client saved", "clientId", client.Id)
+	response := toClientResponse(client, secret)
+	return &response, nil
+}
+
+// ResetClientSecret выпускает новый секрет конфиденциального клиента, старый перестает действовать
+func (o *OAuth) ResetClientSecret(ctx context.Context, clientId string) (*oauth.ClientResponse, error) {
+	const op = "OAuth.ResetClientSecret"
+
+	client, err := o.repo.GetClient(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if client == nil {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if client.IsPublic {
+		return nil, oauthErrors.ErrInvalidClientMetadata
+	}
+
+	secret, err := setNewSecret(client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := o.repo.SaveClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("oauth client secret reset", "clientId", client.Id)
+	response := toClientResponse(client, secret)
+	return &response, nil
+}
+
+func (o *OAuth) DeleteClient(ctx context.Context, clientId string) error {
+	const op = "OAuth.DeleteClient"
+
+	if err := o.repo.DeleteClient(ctx, clientId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	slog.Info("oauth client deleted", "clientId", clientId)
+	return nil
+}
+
+func validateClientRequest(req oauth.ClientRequest) error {
+	if req.Id == "" || req.Name == "" || len(req.GrantTypes) == 0 {
+		return oauthErrors.ErrInvalidClientMetadata
+	}
+	for _, grantType := range req.GrantTypes {
+		if !slices.Contains(supportedGrantTypes, grantType) {
+			return oauthErrors.ErrInvalidClientMetadata
+		}
+	}
+	if req.IsPublic && slices.Contains(req.GrantTypes, GrantTypeClientCredentials) {
+		return oauthErrors.ErrInvalidClientMetadata
+	}
+	if slices.Contains(req.GrantTypes, GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return oauthErrors.ErrInvalidRedirectURI
+	}
+	for _, uri := range req.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return oauthErrors.ErrInvalidRedirectURI
+		}
+	}
+	return nil
+}
+
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func setNewSecret(client *domain.OAuthClient) (string, error) {
+	secret, err := randtoken.New()
+	if err != nil {
+		return "", err
+	}
+	if err := client.SetSecret(secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func toClientResponse(client *domain.OAuthClient, secret string) oauth.ClientResponse {
+	return oauth.ClientResponse{
+		Id:           client.Id,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		GrantTypes:   client.GrantTypes,
+		IsPublic:     client.IsPublic,
+		ClientSecret: secret,
+	}
+}
@@ -0,0 +1,241 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+)
+
+// memoryRepository - реестр клиентов и коды авторизации в памяти
+type memoryRepository struct {
+	mu      sync.Mutex
+	clients map[string]domain.OAuthClient
+	codes   map[string]*domain.AuthorizationCode
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		clients: map[string]domain.OAuthClient{},
+		codes:   map[string]*domain.AuthorizationCode{},
+	}
+}
+
+func (r *memoryRepository) CreateAuthorizationCode(_ context.Context, code *domain.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *code
+	r.codes[code.CodeHash] = &copied
+	return nil
+}
+
+func (r *memoryRepository) ConsumeAuthorizationCode(_ context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.codes, codeHash)
+	return code, nil
+}
+
+func (r *memoryRepository) GetClient(_ context.Context, clientId string) (*domain.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[clientId]
+	if !ok {
+		return nil, nil
+	}
+	return &client, nil
+}
+
+func (r *memoryRepository) GetClients(context.Context) ([]domain.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]domain.OAuthClient, 0, len(r.clients))
+	for _, client := range r.clients {
+		result = append(result, client)
+	}
+	return result, nil
+}
+
+func (r *memoryRepository) SaveClient(_ context.Context, client *domain.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.Id] = *client
+	return nil
+}
+
+func (r *memoryRepository) DeleteClient(_ context.Context, clientId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, clientId)
+	return nil
+}
+
+func newClientsTestOAuth() (*OAuth, *memoryRepository) {
+	repo := newMemoryRepository()
+	return New(repo, nil, nil, nil, nil, time.Minute), repo
+}
+
+func webClientRequest() oauth.ClientRequest {
+	return oauth.ClientRequest{
+		Id:           "web",
+		Name:         "Web",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{ScopeOpenID},
+		GrantTypes:   []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+	}
+}
+
+func TestValidateClientRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(req *oauth.ClientRequest)
+		wantErr error
+	}{
+		{"valid", func(*oauth.ClientRequest) {}, nil},
+		{"service without redirect uri", func(req *oauth.ClientRequest) {
+			req.GrantTypes, req.RedirectURIs = []string{GrantTypeClientCredentials}, nil
+		}, nil},
+		{"public with authorization code", func(req *oauth.ClientRequest) { req.IsPublic = true }, nil},
+		{"without id", func(req *oauth.ClientRequest) { req.Id = "" }, oauthErrors.ErrInvalidClientMetadata},
+		{"without name", func(req *oauth.ClientRequest) { req.Name = "" }, oauthErrors.ErrInvalidClientMetadata},
+		{"without grant types", func(req *oauth.ClientRequest) { req.GrantTypes = nil }, oauthErrors.ErrInvalidClientMetadata},
+		{"unsupported grant type", func(req *oauth.ClientRequest) { req.GrantTypes = []string{"password"} }, oauthErrors.ErrInvalidClientMetadata},
+		// у публичного клиента нет секрета, которым он подтвердил бы client_credentials
+		{"public with client credentials", func(req *oauth.ClientRequest) {
+			req.IsPublic, req.GrantTypes = true, []string{GrantTypeClientCredentials}
+		}, oauthErrors.ErrInvalidClientMetadata},
+		{"authorization code without redirect uri", func(req *oauth.ClientRequest) { req.RedirectURIs = nil }, oauthErrors.ErrInvalidRedirectURI},
+		{"relative redirect uri", func(req *oauth.ClientRequest) { req.RedirectURIs = []string{"/callback"} }, oauthErrors.ErrInvalidRedirectURI},
+		{"redirect uri with fragment", func(req *oauth.ClientRequest) {
+			req.RedirectURIs = []string{"https://app.example.com/callback#token"}
+		}, oauthErrors.ErrInvalidRedirectURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := webClientRequest()
+			tt.change(&req)
+			if err := validateClientRequest(req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// колонки TEXT[] NOT NULL: пустые списки сохраняются пустыми, а не NULL
+func TestSaveClientStoresEmptyLists(t *testing.T) {
+	o, repo := newClientsTestOAuth()
+
+	req := oauth.ClientRequest{Id: "service", Name: "Service", GrantTypes: []string{GrantTypeClientCredentials}}
+	if _, err := o.SaveClient(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	client := repo.clients["service"]
+	if client.RedirectURIs == nil || client.Scopes == nil {
+		t.Fatalf("nil lists saved: redirect_uris %#v, scopes %#v", client.RedirectURIs, client.Scopes)
+	}
+}
+
+func TestSaveClientHashesSecret(t *testing.T) {
+	ctx := context.Background()
+	o, repo := newClientsTestOAuth()
+
+	created, err := o.SaveClient(ctx, webClientRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ClientSecret == "" {
+		t.Fatal("secret was not issued to a new confidential client")
+	}
+	hash := repo.clients["web"].SecretHash
+	if len(hash) == 0 || bytes.Contains(hash, []byte(created.ClientSecret)) {
+		t.Fatal("secret is not stored as a hash")
+	}
+	if _, err := o.authenticate(ctx, "web", created.ClientSecret); err != nil {
+		t.Fatalf("authenticate with issued secret: %v", err)
+	}
+	if _, err := o.authenticate(ctx, "web", "wrong-secret"); !errors.Is(err, oauthErrors.ErrInvalidClient) {
+		t.Fatalf("wrong secret: err = %v, want ErrInvalidClient", err)
+	}
+
+	// при обновлении секрет не меняется и не возвращается повторно
+	updated, err := o.SaveClient(ctx, webClientRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ClientSecret != "" || !bytes.Equal(repo.clients["web"].SecretHash, hash) {
+		t.Fatal("update changed or revealed the secret")
+	}
+
+	reset, err := o.ResetClientSecret(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.authenticate(ctx, "web", created.ClientSecret); !errors.Is(err, oauthErrors.ErrInvalidClient) {
+		t.Fatalf("old secret after reset: err = %v, want ErrInvalidClient", err)
+	}
+	if _, err := o.authenticate(ctx, "web", reset.ClientSecret); err != nil {
+		t.Fatalf("authenticate with reset secret: %v", err)
+	}
+}
+
+func TestSavePublicClientDropsSecret(t *testing.T) {
+	ctx := context.Background()
+	o, repo := newClientsTestOAuth()
+	if _, err := o.SaveClient(ctx, webClientRequest()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := webClientRequest()
+	req.IsPublic = true
+	response, err := o.SaveClient(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ClientSecret != "" || repo.clients["web"].SecretHash != nil {
+		t.Fatal("public client has a secret")
+	}
+	if _, err := o.ResetClientSecret(ctx, "web"); !errors.Is(err, oauthErrors.ErrInvalidClientMetadata) {
+		t.Fatalf("reset secret of public client: err = %v, want ErrInvalidClientMetadata", err)
+	}
+}
+
+// redirect_uri сравнивается с зарегистрированным только целиком
+func TestValidateClientMatchesRedirectURIExactly(t *testing.T) {
+	ctx := context.Background()
+	o, _ := newClientsTestOAuth()
+	if _, err := o.SaveClient(ctx, webClientRequest()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		clientId    string
+		redirectURI string
+		wantErr     error
+	}{
+		{"web", "https://app.example.com/callback", nil},
+		{"web", "https://app.example.com/callback/", oauthErrors.ErrInvalidRedirectURI},
+		{"web", "https://app.example.com/callback?next=/admin", oauthErrors.ErrInvalidRedirectURI},
+		{"web", "https://app.example.com/other", oauthErrors.ErrInvalidRedirectURI},
+		{"web", "http://app.example.com/callback", oauthErrors.ErrInvalidRedirectURI},
+		{"web", "https://app.example.com.evil.com/callback", oauthErrors.ErrInvalidRedirectURI},
+		{"web", "", oauthErrors.ErrInvalidRedirectURI},
+		{"unknown", "https://app.example.com/callback", oauthErrors.ErrInvalidClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clientId+" "+tt.redirectURI, func(t *testing.T) {
+			if _, err := o.ValidateClient(ctx, tt.clientId, tt.redirectURI); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
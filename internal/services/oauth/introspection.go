@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+// Introspect сообщает, действителен ли токен (RFC 7662). Проверять токены могут только
+// конфиденциальные клиенты. Недействительный, истекший или отозванный токен - не ошибка,
+// а ответ active=false без остальных полей
+func (o *OAuth) Introspect(ctx context.Context, req oauth.IntrospectRequest) (*oauth.IntrospectResponse, error) {
+	const op = "OAuth.Introspect"
+
+	client, err := o.authenticate(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+	if req.Token == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	info, err := o.jwt.Inspect(req.Token)
+	if err != nil {
+		return &oauth.IntrospectResponse{Active: false}, nil
+	}
+
+	active, err := o.isActive(ctx, info)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !active {
+		return &oauth.IntrospectResponse{Active: false}, nil
+	}
+
+	return &oauth.IntrospectResponse{
+		Active:    true,
+		Subject:   info.Subject,
+		Role:      info.RoleId,
+		Scope:     info.Scope,
+		ClientId:  info.ClientId,
+		TokenType: info.Type,
+		TokenId:   info.TokenId,
+		Audience:  info.Audience,
+		IssuedAt:  info.IssuedAt.Unix(),
+		ExpiresAt: info.ExpiresAt.Unix(),
+	}, nil
+}
+
+// Revoke отзывает access, сервисный или refresh токен (RFC 7009). Отзыв refresh токена завершает
+// всю сессию. Клиент может отозвать только выданный ему токен: чужой токен остается действительным,
+// но ответ тот же, что и для неизвестного или уже недействительного токена, - успешный
+func (o *OAuth) Revoke(ctx context.Context, req oauth.RevokeRequest) error {
+	const op = "OAuth.Revoke"
+
+	client, err := o.authenticate(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return oauthErrors.ErrInvalidRequest
+	}
+
+	info, err := o.jwt.Inspect(req.Token)
+	if err != nil {
+		return nil
+	}
+	if info.ClientId != client.Id {
+		slog.Warn("revocation of a token issued to another client ignored", "clientId", client.Id, "tokenId", info.TokenId)
+		return nil
+	}
+
+	switch info.Type {
+	case jwt.TokenTypeRefresh:
+		err = o.tokens.RevokeRefreshToken(ctx, info.TokenId)
+	default:
+		err = o.denylist.Revoke(ctx, info.TokenId, time.Until(info.ExpiresAt))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("token revoked", "clientId", client.Id, "type", info.Type, "tokenId", info.TokenId)
+	return nil
+}
+
+// isActive проверяет состояние отзыва: refresh токены - по серверной записи, остальные - по denylist
+func (o *OAuth) isActive(ctx context.Context, info *jwt.TokenInfo) (bool, error) {
+	if info.TokenId == "" {
+		return true, nil
+	}
+	if info.Type == jwt.TokenTypeRefresh {
+		return o.tokens.IsRefreshTokenActive(ctx, info.TokenId)
+	}
+	return !o.denylist.IsRevoked(ctx, info.TokenId), nil
+}
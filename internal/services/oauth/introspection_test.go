@@ -0,0 +1,180 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/jwt"
+)
+
+// issueTokens выдает клиенту web пару токенов через authorization code
+func (e *oauthTestEnv) issueTokens(t *testing.T) *oauth.TokenResponse {
+	t.Helper()
+	response, err := e.oauth.Token(context.Background(), codeTokenRequest(e.authorize(t)))
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	return response
+}
+
+func (e *oauthTestEnv) introspect(t *testing.T, token string) *oauth.IntrospectResponse {
+	t.Helper()
+	response, err := e.oauth.Introspect(context.Background(), oauth.IntrospectRequest{
+		Token:        token,
+		ClientId:     "web",
+		ClientSecret: testSecret,
+	})
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	return response
+}
+
+func (e *oauthTestEnv) revoke(t *testing.T, clientId, token string) {
+	t.Helper()
+	err := e.oauth.Revoke(context.Background(), oauth.RevokeRequest{
+		Token:        token,
+		ClientId:     clientId,
+		ClientSecret: testSecret,
+	})
+	if err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+}
+
+// foreignToken выпускает access токен ключом SSO, но с другими параметрами
+func foreignToken(t *testing.T, change func(opts *jwt.Options)) string {
+	t.Helper()
+	opts := testJwtOptions()
+	change(&opts)
+	accessToken, _, err := newTestJwt(opts).NewToken(testUserId, 1, "refresh-id", "access-id", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accessToken
+}
+
+func TestIntrospectActiveToken(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	tokens := env.issueTokens(t)
+
+	for _, token := range []string{tokens.AccessToken, tokens.RefreshToken} {
+		response := env.introspect(t, token)
+		if !response.Active || response.Subject != "7" || response.ClientId != "web" || response.TokenId == "" {
+			t.Fatalf("introspect returned %+v, want active token of user 7 issued to web", response)
+		}
+	}
+}
+
+func TestIntrospectInactiveToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token func(t *testing.T, env *oauthTestEnv) string
+	}{
+		{"malformed token", func(*testing.T, *oauthTestEnv) string {
+			return "not-a-token"
+		}},
+		{"expired token", func(t *testing.T, _ *oauthTestEnv) string {
+			return foreignToken(t, func(opts *jwt.Options) { opts.AccessDuration = -time.Minute })
+		}},
+		{"foreign audience", func(t *testing.T, _ *oauthTestEnv) string {
+			return foreignToken(t, func(opts *jwt.Options) { opts.Audience = "other-service" })
+		}},
+		{"foreign issuer", func(t *testing.T, _ *oauthTestEnv) string {
+			return foreignToken(t, func(opts *jwt.Options) { opts.Issuer = "other-sso" })
+		}},
+		{"revoked access token", func(t *testing.T, env *oauthTestEnv) string {
+			token := env.issueTokens(t).AccessToken
+			env.revoke(t, "web", token)
+			return token
+		}},
+		{"revoked refresh token", func(t *testing.T, env *oauthTestEnv) string {
+			token := env.issueTokens(t).RefreshToken
+			env.revoke(t, "web", token)
+			return token
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			response := env.introspect(t, tt.token(t, env))
+			// у недействительного токена не раскрываются остальные поля
+			if !reflect.DeepEqual(*response, oauth.IntrospectResponse{Active: false}) {
+				t.Fatalf("introspect returned %+v, want only active=false", response)
+			}
+		})
+	}
+}
+
+// отзыв refresh токена завершает сессию, но не трогает выданный вместе с ним access токен
+func TestRevokeRefreshToken(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	tokens := env.issueTokens(t)
+
+	env.revoke(t, "web", tokens.RefreshToken)
+	if env.introspect(t, tokens.RefreshToken).Active {
+		t.Fatal("revoked refresh token is active")
+	}
+	if !env.introspect(t, tokens.AccessToken).Active {
+		t.Fatal("access token was revoked with the refresh token")
+	}
+}
+
+// RFC 7009: чужой токен не отзывается, а ответ не выдает, что токен существует
+func TestRevokeForeignTokenIsNoop(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.addServiceClient(t, "manager")
+	tokens := env.issueTokens(t)
+
+	for _, token := range []string{tokens.AccessToken, tokens.RefreshToken} {
+		env.revoke(t, "service", token)
+		if !env.introspect(t, token).Active {
+			t.Fatal("token issued to web was revoked by another client")
+		}
+	}
+}
+
+func TestRevokeUnknownToken(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.revoke(t, "web", "not-a-token")
+}
+
+func TestIntrospectAndRevokeAuthenticateClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientId     string
+		clientSecret string
+		wantErr      error
+		// публичный клиент может отзывать токены, но не проверять их
+		wantRevokeErr error
+	}{
+		{"unknown client", "unknown", testSecret, oauthErrors.ErrInvalidClient, oauthErrors.ErrInvalidClient},
+		{"wrong secret", "web", "wrong-secret", oauthErrors.ErrInvalidClient, oauthErrors.ErrInvalidClient},
+		{"missing client", "", "", oauthErrors.ErrInvalidClient, oauthErrors.ErrInvalidClient},
+		{"public client", "spa", "", oauthErrors.ErrUnauthorizedClient, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newOAuthTestEnv(t)
+			token := env.issueTokens(t).AccessToken
+
+			_, err := env.oauth.Introspect(ctx, oauth.IntrospectRequest{Token: token, ClientId: tt.clientId, ClientSecret: tt.clientSecret})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("introspect: err = %v, want %v", err, tt.wantErr)
+			}
+			err = env.oauth.Revoke(ctx, oauth.RevokeRequest{Token: token, ClientId: tt.clientId, ClientSecret: tt.clientSecret})
+			if !errors.Is(err, tt.wantRevokeErr) {
+				t.Fatalf("revoke: err = %v, want %v", err, tt.wantRevokeErr)
+			}
+			if !env.introspect(t, token).Active {
+				t.Fatal("token was revoked")
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	ScopeOpenID = "openid"
+
+	codeChallengeMethodS256 = "S256"
+)
+
+type Repository interface {
+	CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+
+	GetClient(ctx context.Context, clientId string) (*domain.OAuthClient, error)
+	GetClients(ctx context.Context) ([]domain.OAuthClient, error)
+	SaveClient(ctx context.Context, client *domain.OAuthClient) error
+	DeleteClient(ctx context.Context, clientId string) error
+}
+
+type Users interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+type Jwt interface {
+	Inspect(tokenString string) (*jwt.TokenInfo, error)
+	NewIDToken(userId, role int64, email, clientId, nonce string) (string, error)
+	NewServiceToken(clientId string, scopes []string, tokenId string) (string, error)
+	AccessDuration() time.Duration
+	ServiceDuration() time.Duration
+}
+
+// TokenIssuer выпускает пару access/refresh токенов так же, как вход через /auth/logIn,
+// и управляет серверными записями refresh токенов
+type TokenIssuer interface {
+	IssueTokens(ctx context.Context, userId, role int64, clientId string) (*auth.AuthResponse, error)
+	RefreshForClient(ctx context.Context, refreshToken, clientId string) (*auth.AuthResponse, error)
+	RevokeRefreshToken(ctx context.Context, tokenId string) error
+	IsRefreshTokenActive(ctx context.Context, tokenId string) (bool, error)
+}
+
+// Denylist хранит идентификаторы отозванных access и сервисных токенов
+type Denylist interface {
+	Revoke(ctx context.Context, tokenId string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, tokenId string) bool
+}
+
+type OAuth struct {
+	repo     Repository
+	users    Users
+	jwt      Jwt
+	tokens   TokenIssuer
+	denylist Denylist
+	codeTTL  time.Duration
+}
+
+func New(repo Repository, users Users, jwt Jwt, tokens TokenIssuer, denylist Denylist, codeTTL time.Duration) *OAuth {
+	return &OAuth{
+		repo:     repo,
+		users:    users,
+		jwt:      jwt,
+		tokens:   tokens,
+		denylist: denylist,
+		codeTTL:  codeTTL,
+	}
+}
+
+// ValidateClient проверяет client_id и redirect_uri. При этих ошибках
+// нельзя перенаправлять пользователя на redirect_uri (RFC 6749, раздел 4.1.2.1)
+func (o *OAuth) ValidateClient(ctx context.Context, clientId, redirectURI string) (*domain.OAuthClient, error) {
+	client, err := o.repo.GetClient(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения клиента: %w", err)
+	}
+	if client == nil {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if !client.HasRedirectURI(redirectURI) {
+		return nil, oauthErrors.ErrInvalidRedirectURI
+	}
+	return client, nil
+}
+
+// ValidateAuthorizeRequest проверяет запрос авторизации целиком
+func (o *OAuth) ValidateAuthorizeRequest(ctx context.Context, req oauth.AuthorizeRequest) error {
+	client, err := o.ValidateClient(ctx, req.ClientId, req.RedirectURI)
+	if err != nil {
+		return err
+	}
+	if req.ResponseType != "code" {
+		return oauthErrors.ErrUnsupportedResponseType
+	}
+	if !client.AllowsGrantType(GrantTypeAuthorizationCode) {
+		return oauthErrors.ErrUnauthorizedClient
+	}
+	if !client.AllowsScopes(strings.Fields(req.Scope)) {
+		return oauthErrors.ErrInvalidScope
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeMethodS256 {
+		return oauthErrors.ErrPKCERequired
+	}
+	return nil
+}
+
+// Authorize выдает код авторизации аутентифицированному пользователю
+// и возвращает redirect_uri клиента с кодом и state
+func (o *OAuth) Authorize(ctx context.Context, userId int64, req oauth.AuthorizeRequest) (string, error) {
+	const op = "OAuth.Authorize"
+
+	if err := o.ValidateAuthorizeRequest(ctx, req); err != nil {
+		return "", err
+	}
+
+	code, err := randtoken.New()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	err = o.repo.CreateAuthorizationCode(ctx, &domain.AuthorizationCode{
+		CodeHash:            randtoken.Hash(code),
+		ClientId:            req.ClientId,
+		UserId:              userId,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(o.codeTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("authorization code issued", "clientId", req.ClientId, "userId", userId)
+	return RedirectWithParams(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}})
+}
+
+// Token обрабатывает запрос к token endpoint
+func (o *OAuth) Token(ctx context.Context, req oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return o.exchangeCode(ctx, req)
+	case GrantTypeRefreshToken:
+		return o.refresh(ctx, req)
+	case GrantTypeClientCredentials:
+		return o.clientCredentials(ctx, req)
+	default:
+		return nil, oauthErrors.ErrUnsupportedGrantType
+	}
+}
+
+func (o *OAuth) exchangeCode(ctx context.Context, req oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	const op = "OAuth.exchangeCode"
+
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	client, err := o.authenticateClient(ctx, req, GrantTypeAuthorizationCode)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := o.repo.ConsumeAuthorizationCode(ctx, randtoken.Hash(req.Code))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if code == nil || code.IsExpired() || code.ClientId != client.Id || code.RedirectURI != req.RedirectURI {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, req.CodeVerifier) {
+		return nil, oauthErrors.ErrInvalidCodeVerifier
+	}
+
+	user, err := o.users.GetUserWithId(ctx, code.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	tokens, err := o.tokens.IssueTokens(ctx, user.Id, user.RoleId, client.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	response := o.tokenResponse(tokens, code.Scope)
+
+	if slices.Contains(strings.Fields(code.Scope), ScopeOpenID) {
+		response.IDToken, err = o.jwt.NewIDToken(user.Id, user.RoleId, user.Login, client.Id, code.Nonce)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	slog.Info("authorization code exchanged", "clientId", client.Id, "userId", user.Id)
+	return response, nil
+}
+
+func (o *OAuth) refresh(ctx context.Context, req oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	client, err := o.authenticateClient(ctx, req, GrantTypeRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := o.tokens.RefreshForClient(ctx, req.RefreshToken, client.Id)
+	if err != nil {
+		slog.Warn("refresh token grant failed", "err", err)
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	// токенов нет: пароль нужно сменить на странице входа SSO, после чего клиент авторизуется заново
+	if tokens.PasswordExpired {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	return o.tokenResponse(tokens, ""), nil
+}
+
+// clientCredentials выдает токен сервиса (RFC 6749, раздел 4.4). Грант доступен только
+// конфиденциальным клиентам, запрошенные scope должны входить в разрешенные клиенту.
+// Без scope в запросе выдаются все разрешенные
+func (o *OAuth) clientCredentials(ctx context.Context, req oauth.TokenRequest) (*oauth.TokenResponse, error) {
+	const op = "OAuth.clientCredentials"
+
+	client, err := o.authenticateClient(ctx, req, GrantTypeClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, oauthErrors.ErrInvalidScope
+	}
+
+	accessToken, err := o.jwt.NewServiceToken(client.Id, scopes, uuid.NewString())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("service token issued", "clientId", client.Id, "scope", scopes)
+	return &oauth.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(o.jwt.ServiceDuration().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// authenticateClient проверяет клиента на token endpoint: конфиденциальный клиент
+// обязан предъявить секрет, публичный - полагается на PKCE
+func (o *OAuth) authenticateClient(ctx context.Context, req oauth.TokenRequest, grantType string) (*domain.OAuthClient, error) {
+	client, err := o.authenticate(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrantType(grantType) {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+	return client, nil
+}
+
+func (o *OAuth) authenticate(ctx context.Context, clientId, clientSecret string) (*domain.OAuthClient, error) {
+	if clientId == "" {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+
+	client, err := o.repo.GetClient(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения клиента: %w", err)
+	}
+	if client == nil {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if !client.IsPublic && !client.CheckSecret(clientSecret) {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (o *OAuth) tokenResponse(tokens *auth.AuthResponse, scope string) *oauth.TokenResponse {
+	return &oauth.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(o.jwt.AccessDuration().Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        scope,
+	}
+}
+
+// RedirectWithParams добавляет параметры к redirect_uri, сохраняя его собственные
+func RedirectWithParams(redirectURI string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", fmt.Errorf("некорректный redirect_uri: %w", err)
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// verifyCodeChallenge проверяет PKCE S256: BASE64URL(SHA256(code_verifier)) == code_challenge
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,458 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/domain"
+	"github.com/phenirain/sso/internal/dto/auth"
+	"github.com/phenirain/sso/internal/dto/oauth"
+	oauthErrors "github.com/phenirain/sso/internal/errors/oauth"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/internal/lib/randtoken"
+	"github.com/phenirain/sso/pkg/echomiddleware"
+)
+
+const (
+	testUserId      int64 = 7
+	testRedirectURI       = "https://app.example.com/callback"
+	testSecret            = "web-secret"
+	// пара из RFC 7636, приложение B
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// memoryUsers - пользователи в памяти
+type memoryUsers map[int64]*domain.User
+
+func (u memoryUsers) GetUserWithId(_ context.Context, uid int64) (*domain.User, error) {
+	user, ok := u[uid]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// memoryTokens выпускает пары токенов через jwt и хранит состояние refresh токенов.
+// Продление тестам не нужно: вызов упадет на nil встроенного интерфейса
+type memoryTokens struct {
+	TokenIssuer
+
+	jwt     *jwt.JwtLib
+	mu      sync.Mutex
+	refresh map[string]bool
+}
+
+func (m *memoryTokens) IssueTokens(_ context.Context, userId, role int64, clientId string) (*auth.AuthResponse, error) {
+	refreshTokenId := uuid.NewString()
+	accessToken, refreshToken, err := m.jwt.NewToken(userId, role, refreshTokenId, uuid.NewString(), clientId)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refresh[refreshTokenId] = true
+	return &auth.AuthResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (m *memoryTokens) RevokeRefreshToken(_ context.Context, tokenId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refresh[tokenId] = false
+	return nil
+}
+
+func (m *memoryTokens) IsRefreshTokenActive(_ context.Context, tokenId string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refresh[tokenId], nil
+}
+
+// memoryDenylist запоминает отозванные access и сервисные токены
+type memoryDenylist struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func (d *memoryDenylist) Revoke(_ context.Context, tokenId string, _ time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.revoked[tokenId] = true
+	return nil
+}
+
+func (d *memoryDenylist) IsRevoked(_ context.Context, tokenId string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.revoked[tokenId]
+}
+
+type oauthTestEnv struct {
+	oauth    *OAuth
+	repo     *memoryRepository
+	users    memoryUsers
+	jwt      *jwt.JwtLib
+	denylist *memoryDenylist
+}
+
+func newTestJwt(opts jwt.Options) *jwt.JwtLib {
+	return jwt.NewJwtLib(opts, jwt.NewKeyring(jwt.NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+}
+
+func testJwtOptions() jwt.Options {
+	return jwt.Options{
+		Issuer:          "sso-test",
+		Audience:        "sso-test",
+		AccessDuration:  time.Minute * 15,
+		RefreshDuration: time.Hour,
+		ServiceDuration: time.Minute * 5,
+	}
+}
+
+// newOAuthTestEnv регистрирует конфиденциального клиента web с секретом testSecret,
+// публичного клиента spa и пользователя testUserId
+func newOAuthTestEnv(t *testing.T) *oauthTestEnv {
+	t.Helper()
+	jwtLib := newTestJwt(testJwtOptions())
+	env := &oauthTestEnv{
+		repo:     newMemoryRepository(),
+		users:    memoryUsers{testUserId: {Id: testUserId, Login: "user@example.com", RoleId: 1}},
+		jwt:      jwtLib,
+		denylist: &memoryDenylist{revoked: map[string]bool{}},
+	}
+	env.oauth = New(env.repo, env.users, jwtLib, &memoryTokens{jwt: jwtLib, refresh: map[string]bool{}}, env.denylist, time.Minute)
+
+	web := domain.OAuthClient{
+		Id:           "web",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{ScopeOpenID, "email"},
+		GrantTypes:   []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+	}
+	if err := web.SetSecret(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	spa := web
+	spa.Id, spa.SecretHash, spa.IsPublic = "spa", nil, true
+	env.repo.clients[web.Id], env.repo.clients[spa.Id] = web, spa
+	return env
+}
+
+func authorizeRequest(clientId string) oauth.AuthorizeRequest {
+	return oauth.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientId:            clientId,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		State:               "state-1",
+		Nonce:               "nonce-1",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: codeChallengeMethodS256,
+	}
+}
+
+// authorize выдает код клиенту web и возвращает его из redirect_uri
+func (e *oauthTestEnv) authorize(t *testing.T) string {
+	t.Helper()
+	redirect, err := e.oauth.Authorize(context.Background(), testUserId, authorizeRequest("web"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("state") != "state-1" {
+		t.Fatalf("state was not returned: %s", redirect)
+	}
+	return u.Query().Get("code")
+}
+
+func codeTokenRequest(code string) oauth.TokenRequest {
+	return oauth.TokenRequest{
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		ClientId:     "web",
+		ClientSecret: testSecret,
+		CodeVerifier: testCodeVerifier,
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	response, err := env.oauth.Token(context.Background(), codeTokenRequest(env.authorize(t)))
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if response.AccessToken == "" || response.RefreshToken == "" || response.Scope != "openid email" {
+		t.Fatalf("exchange returned %+v", response)
+	}
+	info, err := env.jwt.Inspect(response.AccessToken)
+	if err != nil {
+		t.Fatalf("inspect access token: %v", err)
+	}
+	if info.ClientId != "web" || info.Subject != "7" {
+		t.Fatalf("access token issued to %q for %q, want web for 7", info.ClientId, info.Subject)
+	}
+	// scope openid - вместе с токенами выдается ID токен
+	if response.IDToken == "" {
+		t.Fatal("id token was not issued")
+	}
+}
+
+func TestExchangeCodeVerifiesPKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		wantErr  error
+	}{
+		{"matching verifier", testCodeVerifier, nil},
+		{"other verifier", "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXj", oauthErrors.ErrInvalidCodeVerifier},
+		// при методе plain такой code_verifier прошел бы проверку
+		{"challenge as verifier", testCodeChallenge, oauthErrors.ErrInvalidCodeVerifier},
+		{"short verifier", "short", oauthErrors.ErrInvalidCodeVerifier},
+		{"missing verifier", "", oauthErrors.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			req := codeTokenRequest(env.authorize(t))
+			req.CodeVerifier = tt.verifier
+
+			if _, err := env.oauth.Token(context.Background(), req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRequiresS256(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(req *oauth.AuthorizeRequest)
+	}{
+		{"plain method", func(req *oauth.AuthorizeRequest) { req.CodeChallengeMethod = "plain" }},
+		{"missing method", func(req *oauth.AuthorizeRequest) { req.CodeChallengeMethod = "" }},
+		{"missing challenge", func(req *oauth.AuthorizeRequest) { req.CodeChallenge = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			req := authorizeRequest("web")
+			tt.change(&req)
+
+			if _, err := env.oauth.Authorize(context.Background(), testUserId, req); !errors.Is(err, oauthErrors.ErrPKCERequired) {
+				t.Fatalf("err = %v, want ErrPKCERequired", err)
+			}
+			if len(env.repo.codes) != 0 {
+				t.Fatal("code was issued")
+			}
+		})
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	env := newOAuthTestEnv(t)
+	req := codeTokenRequest(env.authorize(t))
+
+	if _, err := env.oauth.Token(ctx, req); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := env.oauth.Token(ctx, req); !errors.Is(err, oauthErrors.ErrInvalidGrant) {
+		t.Fatalf("replayed code: err = %v, want ErrInvalidGrant", err)
+	}
+}
+
+func TestExchangeCodeRejectsInvalidGrant(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(env *oauthTestEnv, req *oauth.TokenRequest)
+		wantErr error
+	}{
+		{"other redirect uri", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.RedirectURI = "https://app.example.com/other"
+		}, oauthErrors.ErrInvalidGrant},
+		// код выдан web, а обменивает его spa
+		{"other client", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.ClientId, req.ClientSecret = "spa", ""
+		}, oauthErrors.ErrInvalidGrant},
+		{"expired code", func(env *oauthTestEnv, req *oauth.TokenRequest) {
+			env.repo.codes[randtoken.Hash(req.Code)].ExpiresAt = time.Now().Add(-time.Second)
+		}, oauthErrors.ErrInvalidGrant},
+		{"unknown code", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.Code = "unknown-code"
+		}, oauthErrors.ErrInvalidGrant},
+		{"wrong secret", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.ClientSecret = "wrong-secret"
+		}, oauthErrors.ErrInvalidClient},
+		{"archived user", func(env *oauthTestEnv, _ *oauth.TokenRequest) {
+			env.users[testUserId].IsArchived = true
+		}, oauthErrors.ErrInvalidGrant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			req := codeTokenRequest(env.authorize(t))
+			tt.change(env, &req)
+
+			if _, err := env.oauth.Token(context.Background(), req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// addServiceClient регистрирует конфиденциального клиента service с грантом client_credentials
+func (e *oauthTestEnv) addServiceClient(t *testing.T, scopes ...string) {
+	t.Helper()
+	client := domain.OAuthClient{
+		Id:         "service",
+		Scopes:     scopes,
+		GrantTypes: []string{GrantTypeClientCredentials},
+	}
+	if err := client.SetSecret(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	e.repo.clients[client.Id] = client
+}
+
+func serviceTokenRequest(scope string) oauth.TokenRequest {
+	return oauth.TokenRequest{
+		GrantType:    GrantTypeClientCredentials,
+		ClientId:     "service",
+		ClientSecret: testSecret,
+		Scope:        scope,
+	}
+}
+
+func TestClientCredentialsScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      string
+		wantScopes []string
+		wantErr    error
+	}{
+		{"all allowed scopes by default", "", []string{"manager", "reports"}, nil},
+		{"narrowed to requested", "reports", []string{"reports"}, nil},
+		{"scope not allowed", "reports admin", nil, oauthErrors.ErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			env.addServiceClient(t, "manager", "reports")
+
+			response, err := env.oauth.Token(context.Background(), serviceTokenRequest(tt.scope))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if response.RefreshToken != "" {
+				t.Fatal("refresh token was issued to a service")
+			}
+			clientId, scopes, _, err := env.jwt.ParseServiceToken(response.AccessToken)
+			if err != nil {
+				t.Fatalf("parse service token: %v", err)
+			}
+			if clientId != "service" || !slices.Equal(scopes, tt.wantScopes) {
+				t.Fatalf("token for %q with scopes %v, want service with %v", clientId, scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsRejectsClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(env *oauthTestEnv, req *oauth.TokenRequest)
+		wantErr error
+	}{
+		{"wrong secret", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.ClientSecret = "wrong-secret"
+		}, oauthErrors.ErrInvalidClient},
+		{"missing secret", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.ClientSecret = ""
+		}, oauthErrors.ErrInvalidClient},
+		// у публичного клиента нет секрета, которым он подтвердил бы, что он - это он
+		{"public client", func(env *oauthTestEnv, _ *oauth.TokenRequest) {
+			client := env.repo.clients["service"]
+			client.IsPublic, client.SecretHash = true, nil
+			env.repo.clients["service"] = client
+		}, oauthErrors.ErrUnauthorizedClient},
+		{"grant not allowed", func(_ *oauthTestEnv, req *oauth.TokenRequest) {
+			req.ClientId = "web"
+		}, oauthErrors.ErrUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			env.addServiceClient(t, "manager")
+			req := serviceTokenRequest("")
+			tt.change(env, &req)
+
+			if _, err := env.oauth.Token(context.Background(), req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// serve пропускает запрос с токеном через JwtValidation и middlewares и возвращает статус ответа
+func serve(env *oauthTestEnv, token string, middlewares ...echo.MiddlewareFunc) int {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	middlewares = append([]echo.MiddlewareFunc{echomiddleware.JwtValidation(env.jwt, env.denylist)}, middlewares...)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/manager/report", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec.Code
+}
+
+func TestServiceTokenMiddleware(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.addServiceClient(t, "manager", "reports")
+	response, err := env.oauth.Token(context.Background(), serviceTokenRequest("manager"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		middleware echo.MiddlewareFunc
+		want       int
+	}{
+		{"role granted by scope", echomiddleware.RoleMiddleware(echomiddleware.RoleManager), http.StatusOK},
+		{"role without scope", echomiddleware.RoleMiddleware(echomiddleware.RoleAdmin), http.StatusForbidden},
+		{"granted scope", echomiddleware.ScopeMiddleware("manager"), http.StatusOK},
+		// reports разрешен клиенту, но в токен не запрошен
+		{"scope narrowed out", echomiddleware.ScopeMiddleware("reports"), http.StatusForbidden},
+		{"user only route", echomiddleware.UserOnlyMiddleware(), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(env, response.AccessToken, tt.middleware); got != tt.want {
+				t.Fatalf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
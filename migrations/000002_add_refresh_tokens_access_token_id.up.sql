@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_token_id UUID NOT NULL DEFAULT gen_random_uuid();
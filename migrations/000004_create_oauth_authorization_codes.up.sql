@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash             TEXT PRIMARY KEY,
+    client_id             TEXT        NOT NULL,
+    user_id               BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri          TEXT        NOT NULL,
+    scope                 TEXT        NOT NULL DEFAULT '',
+    nonce                 TEXT        NOT NULL DEFAULT '',
+    code_challenge        TEXT        NOT NULL,
+    code_challenge_method TEXT        NOT NULL,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at            TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS client_id;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id                TEXT PRIMARY KEY,
+    name              TEXT        NOT NULL,
+    secret_hash       BYTEA,
+    redirect_uris     TEXT[]      NOT NULL DEFAULT '{}',
+    scopes            TEXT[]      NOT NULL DEFAULT '{}',
+    grant_types       TEXT[]      NOT NULL DEFAULT '{}',
+    is_public         BOOLEAN     NOT NULL DEFAULT FALSE,
+    creation_datetime TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    update_datetime   TIMESTAMPTZ
+);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id TEXT NOT NULL DEFAULT '';
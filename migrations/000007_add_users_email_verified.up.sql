@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- аккаунты, созданные до появления подтверждения, считаются подтвержденными
+UPDATE users SET email_verified = TRUE;
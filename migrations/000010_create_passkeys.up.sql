@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+    id           TEXT PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    public_key   BYTEA       NOT NULL,
+    sign_count   BIGINT      NOT NULL DEFAULT 0,
+    transports   TEXT[]      NOT NULL DEFAULT '{}',
+    name         TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    challenge_hash TEXT PRIMARY KEY,
+    user_id        BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    ceremony       TEXT        NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMPTZ NOT NULL
+);
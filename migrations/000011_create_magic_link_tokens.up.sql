@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS magic_link_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_link_tokens_user_id_created_at ON magic_link_tokens (user_id, created_at);
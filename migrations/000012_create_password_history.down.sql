@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_users_password_changed_at ON users;
+DROP FUNCTION IF EXISTS set_password_changed_at();
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
+DROP TABLE IF EXISTS password_history;
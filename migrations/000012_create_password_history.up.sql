@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS password_history (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    password_hash TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id, id);
+
+-- для существующих пользователей отсчет срока действия пароля начинается с миграции
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- пароль меняют и другие сервисы (назначение пароля администратором идет через gRPC),
+-- поэтому дата смены выставляется триггером при любом изменении пароля
+CREATE OR REPLACE FUNCTION set_password_changed_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.password IS DISTINCT FROM OLD.password THEN
+        NEW.password_changed_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_users_password_changed_at ON users;
+CREATE TRIGGER trg_users_password_changed_at
+    BEFORE UPDATE OF password ON users
+    FOR EACH ROW EXECUTE FUNCTION set_password_changed_at();
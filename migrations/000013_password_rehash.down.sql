@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION set_password_changed_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.password IS DISTINCT FROM OLD.password THEN
+        NEW.password_changed_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
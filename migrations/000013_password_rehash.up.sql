@@ -0,0 +1,11 @@
+-- пересчет хеша того же пароля более стойким алгоритмом - не смена пароля,
+-- такое обновление выставляет sso.password_rehash и срок действия пароля не сбрасывает
+CREATE OR REPLACE FUNCTION set_password_changed_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.password IS DISTINCT FROM OLD.password
+        AND current_setting('sso.password_rehash', true) IS DISTINCT FROM 'on' THEN
+        NEW.password_changed_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
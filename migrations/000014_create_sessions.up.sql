@@ -0,0 +1,22 @@
+-- сессия - одно семейство refresh токенов: вход и все последующие ротации
+CREATE TABLE IF NOT EXISTS sessions (
+    id           UUID PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id    TEXT        NOT NULL DEFAULT '',
+    user_agent   TEXT        NOT NULL DEFAULT '',
+    ip           TEXT        NOT NULL DEFAULT '',
+    device       TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked      BOOLEAN     NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- сессии, начатые до миграции, видны в списке без устройства и IP
+INSERT INTO sessions (id, user_id, client_id, created_at, last_seen_at, expires_at, revoked)
+SELECT family, user_id, MAX(client_id), MIN(issued_at), MAX(issued_at), MAX(expires_at), BOOL_AND(revoked)
+FROM refresh_tokens
+GROUP BY family, user_id
+ON CONFLICT (id) DO NOTHING;
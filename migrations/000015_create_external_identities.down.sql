@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS external_auth_states;
+DROP TABLE IF EXISTS external_identities;
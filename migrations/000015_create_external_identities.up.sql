@@ -0,0 +1,22 @@
+-- учетная запись пользователя у внешнего провайдера (Google, Yandex, ...), через которую он входит
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider      TEXT        NOT NULL,
+    subject       TEXT        NOT NULL,
+    user_id       BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email         TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_identities_user_id ON external_identities (user_id);
+
+-- незавершенные входы через внешних провайдеров: state из адреса возврата и секреты PKCE и nonce
+CREATE TABLE IF NOT EXISTS external_auth_states (
+    state_hash    TEXT PRIMARY KEY,
+    provider      TEXT        NOT NULL,
+    nonce         TEXT        NOT NULL,
+    code_verifier TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMPTZ NOT NULL
+);
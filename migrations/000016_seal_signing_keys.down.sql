@@ -0,0 +1,3 @@
+-- без признака шифрования зашифрованные ключи не прочитать, сервис вернется к ключу из конфигурации
+DELETE FROM signing_keys WHERE sealed;
+ALTER TABLE signing_keys DROP COLUMN IF EXISTS sealed;
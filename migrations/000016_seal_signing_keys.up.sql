@@ -0,0 +1,3 @@
+-- приватные ключи шифруются тем же ключом, что и секреты TOTP. Уже сохраненные ключи
+-- шифруются сервисом при первой загрузке
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS sealed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,12 @@
+-- незавершенные входы, ожидающие второго шага (код второго фактора, смена истекшего пароля).
+-- id - jti выданного токена: токен действует, пока запись существует, и не больше max attempts попыток
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id         TEXT PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    TEXT        NOT NULL,
+    attempts   INT         NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_id ON login_challenges (user_id);
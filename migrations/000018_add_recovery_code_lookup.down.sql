@@ -0,0 +1 @@
+ALTER TABLE recovery_codes DROP COLUMN IF EXISTS lookup;
@@ -0,0 +1,3 @@
+-- короткий префикс sha256 кода: по нему выбирается кандидат, и bcrypt считается для одного кода,
+-- а не для всех неиспользованных. У кодов, выданных раньше, префикса нет - они проверяются по-старому
+ALTER TABLE recovery_codes ADD COLUMN IF NOT EXISTS lookup TEXT NOT NULL DEFAULT '';
@@ -6,3 +6,8 @@ const RequestIDCtxKey key = "request_id"
 const TraceIDCtxKey key = "trace_id"
 const UserIDCtxKey key = "user_id"
 const RoleIDCtxKey key = "role_id"
+const ClientIDCtxKey key = "client_id"
+const ScopesCtxKey key = "scopes"
+const ClientIPCtxKey key = "client_ip"
+const TokenIDCtxKey key = "token_id"
+const UserAgentCtxKey key = "user_agent"
@@ -3,6 +3,7 @@ package echomiddleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -10,7 +11,14 @@ import (
 )
 
 type Jwt interface {
-	ParseToken(tokenString string) (userId int64, roleId int64, err error)
+	ParseAccessToken(tokenString string) (userId int64, roleId int64, tokenId string, err error)
+	// ParseServiceToken разбирает токен сервиса, выданный по client_credentials
+	ParseServiceToken(tokenString string) (clientId string, scopes []string, tokenId string, err error)
+}
+
+// Revocation проверяет, не был ли access токен отозван (logout) до истечения срока жизни
+type Revocation interface {
+	IsRevoked(ctx context.Context, tokenId string) bool
 }
 
 const (
@@ -19,17 +27,40 @@ const (
 	RoleAdmin   int64 = 3
 )
 
-func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
+// roleScopes - scope, которые дают сервису доступ к группам маршрутов, закрытым ролью
+var roleScopes = map[int64]string{
+	RoleClient:  "client",
+	RoleManager: "manager",
+	RoleAdmin:   "admin",
+}
+
+func JwtValidation(jwt Jwt, revocation Revocation) echo.MiddlewareFunc {
 	skip := map[string]struct{}{
-		"/auth/logIn":   {},
-		"/auth/signUp":  {},
-		"/auth/refresh": {},
-		"/auth/forgotPassword": {},
-		"/auth/resetPassword": {},
-		"/health":       {},
-		"/swagger/*":    {},
-		"/v":            {},
-		"/metrics":      {},
+		"/auth/logIn":                       {},
+		"/auth/signUp":                      {},
+		"/auth/refresh":                     {},
+		"/auth/forgotPassword":              {},
+		"/auth/resetPassword":               {},
+		"/auth/changeExpiredPassword":       {},
+		"/auth/verifyEmail":                 {},
+		"/auth/resendVerificationEmail":     {},
+		"/auth/magicLink":                   {},
+		"/auth/magicLink/consume":           {},
+		"/auth/2fa/verify":                  {},
+		"/auth/passkey/login/options":       {},
+		"/auth/passkey/login":               {},
+		"/auth/external/:provider/start":    {},
+		"/auth/external/:provider/callback": {},
+		"/health":                           {},
+		"/swagger/*":                        {},
+		"/v":                                {},
+		"/metrics":                          {},
+		"/.well-known/jwks.json":            {},
+		"/.well-known/openid-configuration": {},
+		"/oauth2/authorize":                 {},
+		"/oauth2/token":                     {},
+		"/oauth2/introspect":                {},
+		"/oauth2/revoke":                    {},
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -55,16 +86,31 @@ func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
 			}
 			tokenString := parts[1]
 
-			userId, roleId, err := jwt.ParseToken(tokenString)
-			if err != nil {
+			ctx := c.Request().Context()
+			userId, roleId, tokenId, err := jwt.ParseAccessToken(tokenString)
+			if err == nil {
+				ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
+				ctx = context.WithValue(ctx, contextkeys.RoleIDCtxKey, roleId)
+			} else {
+				// Токен может принадлежать сервису (client_credentials)
+				clientId, scopes, serviceTokenId, serviceErr := jwt.ParseServiceToken(tokenString)
+				if serviceErr != nil {
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": err.Error(),
+					})
+				}
+				tokenId = serviceTokenId
+				ctx = context.WithValue(ctx, contextkeys.ClientIDCtxKey, clientId)
+				ctx = context.WithValue(ctx, contextkeys.ScopesCtxKey, scopes)
+			}
+
+			if tokenId != "" && revocation.IsRevoked(ctx, tokenId) {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": err.Error(),
+					"error": "token revoked",
 				})
 			}
 
-			ctx := c.Request().Context()
-			ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
-			ctx = context.WithValue(ctx, contextkeys.RoleIDCtxKey, roleId)
+			ctx = context.WithValue(ctx, contextkeys.TokenIDCtxKey, tokenId)
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
@@ -72,10 +118,22 @@ func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
 	}
 }
 
-// RoleMiddleware проверяет, что роль пользователя соответствует разрешенным ролям
+// RoleMiddleware проверяет, что роль пользователя соответствует разрешенным ролям.
+// Сервис с токеном client_credentials допускается, если у него есть scope одной из ролей
 func RoleMiddleware(allowedRoles ...int64) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if scopes, ok := c.Request().Context().Value(contextkeys.ScopesCtxKey).([]string); ok {
+				for _, allowedRole := range allowedRoles {
+					if slices.Contains(scopes, roleScopes[allowedRole]) {
+						return next(c)
+					}
+				}
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "access denied: insufficient scope",
+				})
+			}
+
 			roleId, ok := c.Request().Context().Value(contextkeys.RoleIDCtxKey).(int64)
 			if !ok {
 				return c.JSON(http.StatusForbidden, map[string]string{
@@ -96,3 +154,46 @@ func RoleMiddleware(allowedRoles ...int64) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// UserOnlyMiddleware пропускает только access токены пользователей. Ставится на маршруты, через
+// которые можно закрепить доступ (ключи подписи, OAuth клиенты, учетные записи сотрудников):
+// скомпрометированный сервис со scope admin не должен выдать себе новые учетные данные
+func UserOnlyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			_, isService := ctx.Value(contextkeys.ScopesCtxKey).([]string)
+			_, isUser := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+			if isService || !isUser {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "access denied: user token required",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// ScopeMiddleware пропускает только сервисы, у токена которых есть все перечисленные scope.
+// Запросы пользователей отклоняются
+func ScopeMiddleware(requiredScopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, ok := c.Request().Context().Value(contextkeys.ScopesCtxKey).([]string)
+			if !ok {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "scope not found in context",
+				})
+			}
+
+			for _, scope := range requiredScopes {
+				if !slices.Contains(scopes, scope) {
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "access denied: insufficient scope",
+					})
+				}
+			}
+			return next(c)
+		}
+	}
+}
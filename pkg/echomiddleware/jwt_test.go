@@ -0,0 +1,139 @@
+package echomiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/lib/jwt"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+func serveWithContext(ctx context.Context, middlewares ...echo.MiddlewareFunc) int {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/key/rotate", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec.Code
+}
+
+func userContext(roleId int64) context.Context {
+	ctx := context.WithValue(context.Background(), contextkeys.UserIDCtxKey, int64(1))
+	return context.WithValue(ctx, contextkeys.RoleIDCtxKey, roleId)
+}
+
+func serviceContext(scopes ...string) context.Context {
+	ctx := context.WithValue(context.Background(), contextkeys.ClientIDCtxKey, "service")
+	return context.WithValue(ctx, contextkeys.ScopesCtxKey, scopes)
+}
+
+func TestAdminOnlyRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"admin user", userContext(RoleAdmin), http.StatusOK},
+		{"manager user", userContext(RoleManager), http.StatusForbidden},
+		{"service with admin scope", serviceContext("admin"), http.StatusForbidden},
+		{"service without scopes", serviceContext(), http.StatusForbidden},
+		{"no token", context.Background(), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serveWithContext(tt.ctx, RoleMiddleware(RoleAdmin), UserOnlyMiddleware())
+			if got != tt.want {
+				t.Fatalf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleMiddlewareAllowsServiceScope(t *testing.T) {
+	if got := serveWithContext(serviceContext("admin"), RoleMiddleware(RoleAdmin)); got != http.StatusOK {
+		t.Fatalf("service with admin scope: status %d, want %d", got, http.StatusOK)
+	}
+	if got := serveWithContext(serviceContext("manager"), RoleMiddleware(RoleAdmin)); got != http.StatusForbidden {
+		t.Fatalf("service with manager scope: status %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+// noRevocation - ни один токен не отозван
+type noRevocation struct{}
+
+func (noRevocation) IsRevoked(context.Context, string) bool { return false }
+
+func serveBearer(validator Jwt, revocation Revocation, token string) int {
+	e := echo.New()
+	handler := JwtValidation(validator, revocation)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/client/orders", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec.Code
+}
+
+// к API допускаются только access и сервисные токены: промежуточные токены входа
+// и refresh токен как bearer не принимаются
+func TestJwtValidationChecksTokenType(t *testing.T) {
+	lib := jwt.NewJwtLib(jwt.Options{
+		Issuer:               "sso-test",
+		Audience:             "sso-test",
+		AccessDuration:       time.Minute,
+		RefreshDuration:      time.Hour,
+		ServiceDuration:      time.Minute,
+		VerificationDuration: time.Hour,
+		MFADuration:          time.Minute,
+	}, jwt.NewKeyring(jwt.NewHMACKey("test", []byte("test-secret-test-secret-test-secret"))))
+
+	accessToken, refreshToken, err := lib.NewToken(1, RoleClient, "refresh-id", "access-id", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(token string, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	// токен администратора, выданный стороннему клиенту через /oauth2/token
+	clientAccessToken, _, err := lib.NewToken(1, RoleAdmin, "client-refresh-id", "client-access-id", "partner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"access token", accessToken, http.StatusOK},
+		{"service token", issue(lib.NewServiceToken("service", []string{"manager"}, "service-id")), http.StatusOK},
+		{"refresh token", refreshToken, http.StatusUnauthorized},
+		{"access token issued to a client", clientAccessToken, http.StatusUnauthorized},
+		{"mfa token", issue(lib.NewMFAToken(1, RoleClient, "mfa-id")), http.StatusUnauthorized},
+		{"email verification token", issue(lib.NewEmailVerificationToken(1, "user@example.com")), http.StatusUnauthorized},
+		{"password change token", issue(lib.NewPasswordChangeToken(1, RoleClient, "password-change-id")), http.StatusUnauthorized},
+		{"malformed token", "not-a-token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveBearer(lib, noRevocation{}, tt.token); got != tt.want {
+				t.Fatalf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
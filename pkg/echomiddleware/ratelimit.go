@@ -0,0 +1,156 @@
+package echomiddleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/pkg/contextkeys"
+	"github.com/phenirain/sso/pkg/metrics"
+)
+
+// Чем различаются клиенты в ограничителе частоты
+const (
+	RateLimitByIP = "ip"
+	// RateLimitByUser - по пользователю из токена, для анонимных запросов по IP
+	RateLimitByUser = "user"
+	// RateLimitByRoute - общий лимит на маршрут для всех клиентов
+	RateLimitByRoute = "route"
+)
+
+// RateLimitConfig - лимит для группы маршрутов: Requests запросов за Period
+// с допустимым всплеском до Burst запросов подряд
+type RateLimitConfig struct {
+	Name     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+	KeyBy    string
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// rate - пополнение в токенах за секунду
+	rate        float64
+	burst       float64
+	lastCleanup time.Time
+}
+
+// RateLimitMiddleware ограничивает частоту запросов алгоритмом token bucket.
+// Все маршруты, подключенные с одним экземпляром middleware, делят общие лимиты.
+// Отвечает заголовками RateLimit-Limit/Remaining/Reset, при превышении - 429 и Retry-After
+func RateLimitMiddleware(cfg RateLimitConfig, m *metrics.Metrics) echo.MiddlewareFunc {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = cfg.Requests
+	}
+	limiter := &rateLimiter{
+		buckets:     make(map[string]*tokenBucket),
+		rate:        float64(cfg.Requests) / cfg.Period.Seconds(),
+		burst:       float64(burst),
+		lastCleanup: time.Now(),
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Method == http.MethodOptions {
+				return next(c)
+			}
+
+			allowed, remaining, retryAfter, reset := limiter.take(rateLimitKey(c, cfg.KeyBy), time.Now())
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(burst))
+			header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+			if !allowed {
+				m.RecordRateLimitRejected(cfg.Name, c.Path())
+				seconds := ceilSeconds(retryAfter)
+				header.Set("Retry-After", strconv.Itoa(seconds))
+				return c.JSON(http.StatusTooManyRequests, response.NewBadResponse[any](
+					"Слишком много запросов",
+					fmt.Sprintf("Повторите запрос через %d сек.", seconds),
+				))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// take списывает токен из корзины ключа. Возвращает, сколько токенов осталось,
+// через сколько появится следующий токен и через сколько корзина заполнится полностью
+func (l *rateLimiter) take(key string, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cleanup(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	var retryAfter time.Duration
+	if bucket.tokens < 1 {
+		retryAfter = l.refillTime(1 - bucket.tokens)
+	}
+	return allowed, int(bucket.tokens), retryAfter, l.refillTime(l.burst - bucket.tokens)
+}
+
+// cleanup удаляет корзины, которые уже успели заполниться - они ничем не отличаются от новых
+func (l *rateLimiter) cleanup(now time.Time) {
+	fullAfter := l.refillTime(l.burst)
+	if now.Sub(l.lastCleanup) < fullAfter {
+		return
+	}
+	l.lastCleanup = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= fullAfter {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// rateLimitKey - корзина клиента. IP берется через echo.Echo.IPExtractor (см. NewIPExtractor),
+// иначе клиент получал бы новую корзину, подставляя X-Forwarded-For
+func rateLimitKey(c echo.Context, keyBy string) string {
+	switch keyBy {
+	case RateLimitByRoute:
+		return c.Request().Method + " " + c.Path()
+	case RateLimitByUser:
+		if userId, ok := c.Request().Context().Value(contextkeys.UserIDCtxKey).(int64); ok {
+			return "user:" + strconv.FormatInt(userId, 10)
+		}
+	}
+	return "ip:" + c.RealIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
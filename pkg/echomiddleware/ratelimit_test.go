@@ -0,0 +1,113 @@
+package echomiddleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/internal/dto/response"
+	"github.com/phenirain/sso/pkg/metrics"
+)
+
+// метрики регистрируются в глобальном реестре Prometheus - создаем их один раз на пакет
+var testMetrics = sync.OnceValue(metrics.New)
+
+// newRateLimitedServer - маршрут с лимитом в один запрос на клиента за минуту
+func newRateLimitedServer(t *testing.T, trustedProxies []string) *echo.Echo {
+	t.Helper()
+	extractor, err := NewIPExtractor(trustedProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.IPExtractor = extractor
+	limit := RateLimitMiddleware(RateLimitConfig{
+		Name:     "test",
+		Requests: 1,
+		Period:   time.Minute,
+		KeyBy:    RateLimitByIP,
+	}, testMetrics())
+	e.POST("/auth/logIn", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, limit)
+	return e
+}
+
+func serveFrom(e *echo.Echo, remoteAddr string, headers map[string]string) int {
+	req := httptest.NewRequest(http.MethodPost, "/auth/logIn", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRateLimitIgnoresSpoofedHeaders(t *testing.T) {
+	e := newRateLimitedServer(t, nil)
+
+	if code := serveFrom(e, "203.0.113.7:4000", nil); code != http.StatusOK {
+		t.Fatalf("first request: status %d", code)
+	}
+	// новый адрес в заголовке не дает клиенту новую корзину
+	spoofed := []map[string]string{
+		{echo.HeaderXForwardedFor: "198.51.100.1"},
+		{echo.HeaderXRealIP: "198.51.100.2"},
+		{echo.HeaderXForwardedFor: "198.51.100.3, 198.51.100.4", echo.HeaderXRealIP: "198.51.100.5"},
+	}
+	for _, headers := range spoofed {
+		if code := serveFrom(e, "203.0.113.7:4000", headers); code != http.StatusTooManyRequests {
+			t.Fatalf("spoofed %v: status %d, want %d", headers, code, http.StatusTooManyRequests)
+		}
+	}
+
+	if code := serveFrom(e, "203.0.113.8:4000", nil); code != http.StatusOK {
+		t.Fatalf("other client: status %d", code)
+	}
+}
+
+func TestRateLimitBehindTrustedProxy(t *testing.T) {
+	e := newRateLimitedServer(t, []string{"10.0.0.0/8"})
+
+	// клиенты за прокси получают разные корзины
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		if code := serveFrom(e, "10.0.0.5:4000", map[string]string{echo.HeaderXForwardedFor: client}); code != http.StatusOK {
+			t.Fatalf("client %s: status %d", client, code)
+		}
+	}
+	if code := serveFrom(e, "10.0.0.5:4000", map[string]string{echo.HeaderXForwardedFor: "198.51.100.1"}); code != http.StatusTooManyRequests {
+		t.Fatalf("repeated client: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	// подставленный клиентом адрес слева не меняет корзину
+	spoofed := map[string]string{echo.HeaderXForwardedFor: "1.2.3.4, 198.51.100.2"}
+	if code := serveFrom(e, "10.0.0.5:4000", spoofed); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed leftmost address: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+// отказ приходит в общем формате ответов API вместе с Retry-After
+func TestRateLimitRejectionBody(t *testing.T) {
+	e := newRateLimitedServer(t, nil)
+	serveFrom(e, "203.0.113.7:4000", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logIn", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body response.ApiResponse[any]
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Success || body.Message == "" || body.Details == "" {
+		t.Fatalf("unexpected body %s", rec.Body)
+	}
+}
@@ -0,0 +1,57 @@
+package echomiddleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor возвращает способ определения IP клиента для echo.Echo.IPExtractor.
+// Без доверенных прокси берется адрес соединения, а X-Forwarded-For и X-Real-IP игнорируются:
+// их может прислать сам клиент. С доверенными прокси (IP или CIDR) X-Forwarded-For читается
+// справа налево до первого адреса не из списка - его и считаем клиентом.
+// От IP клиента зависят ограничение частоты, блокировки входа и аудит сессий
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		// по умолчанию echo доверяет loopback и частным сетям - доверяем только явно перечисленным
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		ipRange, err := parseIPRange(proxy)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// parseIPRange разбирает CIDR или одиночный IP
+func parseIPRange(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, ipRange, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная подсеть доверенного прокси %q: %w", value, err)
+		}
+		return ipRange, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("некорректный адрес доверенного прокси %q", value)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -0,0 +1,78 @@
+package echomiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phenirain/sso/pkg/contextkeys"
+)
+
+func TestNewIPExtractor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		xff            string
+		want           string
+	}{
+		{"direct ignores forwarded header", nil, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"direct ignores header from loopback", nil, "127.0.0.1:4000", "198.51.100.1", "127.0.0.1"},
+		{"trusted proxy forwards client", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "198.51.100.1", "198.51.100.1"},
+		{"single trusted proxy address", []string{"10.0.0.5"}, "10.0.0.5:4000", "198.51.100.1", "198.51.100.1"},
+		{"untrusted peer cannot forward", []string{"10.0.0.0/8"}, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		// клиент дописал свой адрес слева - прокси добавил настоящий справа
+		{"spoofed leftmost address", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"private network is not trusted implicitly", []string{"10.0.0.0/8"}, "192.168.1.1:4000", "198.51.100.1", "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := NewIPExtractor(tt.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.xff)
+			req.Header.Set(echo.HeaderXRealIP, "192.0.2.1")
+
+			if got := extractor(req); got != tt.want {
+				t.Fatalf("client ip %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIPExtractorRejectsInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if _, err := NewIPExtractor([]string{proxy}); err == nil {
+			t.Errorf("trusted proxy %q accepted", proxy)
+		}
+	}
+}
+
+func TestPutClientContextUsesIPExtractor(t *testing.T) {
+	e := echo.New()
+	extractor, err := NewIPExtractor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.IPExtractor = extractor
+
+	var clientIP string
+	e.GET("/", func(c echo.Context) error {
+		clientIP, _ = c.Request().Context().Value(contextkeys.ClientIPCtxKey).(string)
+		return c.NoContent(http.StatusOK)
+	}, PutClientContext)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+	req.Header.Set(echo.HeaderXRealIP, "198.51.100.1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	if clientIP != "203.0.113.7" {
+		t.Fatalf("client ip in context %q, want 203.0.113.7", clientIP)
+	}
+}
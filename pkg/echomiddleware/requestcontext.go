@@ -19,4 +19,16 @@ func PutRequestIDContext(next echo.HandlerFunc) echo.HandlerFunc {
 
 		return next(c)
 	}
-}
\ No newline at end of file
+}
+
+// PutClientContext кладет в контекст запроса IP и User-Agent клиента - сервисам они нужны без доступа к echo.Context.
+// IP определяет echo.Echo.IPExtractor (см. NewIPExtractor), заголовкам клиента без доверенного прокси не верим
+func PutClientContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := context.WithValue(c.Request().Context(), contextkeys.ClientIPCtxKey, c.RealIP())
+		ctx = context.WithValue(ctx, contextkeys.UserAgentCtxKey, c.Request().UserAgent())
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
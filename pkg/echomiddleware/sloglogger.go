@@ -55,6 +55,9 @@ func SlogLoggerMiddleware(logger logger) echo.MiddlewareFunc {
 					attrs = append(attrs, slog.Int64(string(contextkeys.UserIDCtxKey), uid))
 				}
 			}
+			if clientID, ok := c.Request().Context().Value(contextkeys.ClientIDCtxKey).(string); ok {
+				attrs = append(attrs, slog.String(string(contextkeys.ClientIDCtxKey), clientID))
+			}
 
 			respErrStr := "?"
 			if v.Error != nil {
@@ -9,9 +9,16 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
-// UserIDInterceptor создает интерцептор для добавления user_id в метаданные gRPC запросов
+// UserIDInterceptor создает интерцептор для добавления user_id в метаданные gRPC запросов.
+// Для сервисов, вызывающих API по client_credentials, вместо user_id передается client_id
 func UserIDInterceptor() grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if clientID, ok := ctx.Value(contextkeys.ClientIDCtxKey).(string); ok {
+			md := metadata.Pairs(string(contextkeys.ClientIDCtxKey), clientID)
+			ctx = metadata.NewOutgoingContext(ctx, md)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
 		// Добавляем user_id в метаданные
 		userID, ok := ctx.Value(contextkeys.UserIDCtxKey).(int64)
 		if !ok {
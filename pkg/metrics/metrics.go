@@ -14,6 +14,8 @@ type Metrics struct {
 
 	AuthOperationsTotal *prometheus.CounterVec
 
+	RateLimitRejectedTotal *prometheus.CounterVec
+
 	InfluxDB *InfluxDBWriter
 }
 
@@ -49,6 +51,13 @@ func New() *Metrics {
 			},
 			[]string{"operation", "status"},
 		),
+		RateLimitRejectedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sso_rate_limit_rejected_total",
+				Help: "Total number of requests rejected by rate limiting",
+			},
+			[]string{"group", "path"},
+		),
 	}
 
 	return m
@@ -78,6 +87,11 @@ func (m *Metrics) RecordAuthOperation(operation, status, userRole string) {
 	}
 }
 
+// RecordRateLimitRejected records a request rejected by rate limiting
+func (m *Metrics) RecordRateLimitRejected(group, path string) {
+	m.RateLimitRejectedTotal.WithLabelValues(group, path).Inc()
+}
+
 // SetTotalUsers sets the total users gauge
 func (m *Metrics) SetTotalUsers(count float64) {
 	m.TotalUsersGauge.Set(count)